@@ -80,14 +80,24 @@ func Connect() {
 		&models.AdditionalInformation{},
 		&models.User{},
 		&models.DegreeProgram{},
+		&models.PlanVersion{},
+		&models.UserPlanVersion{},
 		&models.Subject{},
 		&models.UserSubject{},
+		&models.ExamAttempt{},
 		&models.SubjectRequirement{},
+		&models.SubjectCorequisite{},
+		&models.RequirementGroup{},
+		&models.RequirementGroupSubject{},
 		&models.ElectivePool{},
 		&models.ElectivePoolSubject{},
 		&models.ElectiveRule{},
+		&models.SubjectEquivalence{},
+		&models.SubjectEquivalenceSource{},
+		&models.SubjectRecognition{},
 		&models.Session{},
 		&models.PasswordResetToken{},
+		&models.ProgressShareToken{},
 	); err != nil {
 		slog.Error("automigrate failed", slog.Any("error", err))
 		panic(err)
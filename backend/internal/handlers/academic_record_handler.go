@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"acadifyapp/internal/services"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMyProgramRecord descarga la historia académica del usuario en json, csv o pdf.
+func GetMyProgramRecord(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", "json")))
+	switch format {
+	case "json", "csv", "pdf":
+	default:
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "format must be one of json, csv, pdf"})
+		return
+	}
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+	if !ensureEnrolled(c, user.ID, programID) {
+		return
+	}
+
+	record, err := services.LoadAcademicRecord(user, programID, time.Now().UTC())
+	if err != nil {
+		slog.Error("Error loading the academic record", "programID", programID, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error loading the academic record"})
+		return
+	}
+
+	filename := "record-" + strings.ToLower(services.CodeFromName(record.Program)) + "." + format
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	switch format {
+	case "csv":
+		out, err := services.RenderRecordCSV(*record)
+		if err != nil {
+			slog.Error("Error rendering the academic record", "programID", programID, slog.Any("error", err))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error rendering the academic record"})
+			return
+		}
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", out)
+	case "pdf":
+		c.Data(http.StatusOK, "application/pdf", services.RenderRecordPDF(*record))
+	default:
+		c.IndentedJSON(http.StatusOK, record)
+	}
+}
@@ -3,8 +3,8 @@ package handlers
 import (
 	"acadifyapp/internal/db"
 	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
 	"errors"
-	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -17,7 +17,7 @@ import (
 func GetAllPrograms(c *gin.Context) {
 	var programs *[]models.DegreeProgram
 
-	err := db.Db.Model(&models.DegreeProgram{}).Preload("Subjects").Preload("University").Find(&programs).Error
+	err := db.Db.Model(&models.DegreeProgram{}).Preload("Subjects", services.ScopePlanVersion("plan_version_id", nil)).Preload("University").Find(&programs).Error
 	if err != nil {
 		slog.Error("Error getting all the programs from db", slog.Any("error: ", err))
 		c.IndentedJSON(http.StatusInternalServerError, "An error ocurred while getting the programs")
@@ -82,7 +82,11 @@ func GetProgramById(c *gin.Context) {
 	id := c.Param("id")
 	var program *models.DegreeProgram
 
-	if err := db.Db.Preload("Subjects").Preload("University").Where("id = ?", id).First(&program).Error; err != nil {
+	versionID, ok := requestedPlanVersion(c, id)
+	if !ok {
+		return
+	}
+	if err := db.Db.Preload("Subjects", services.ScopePlanVersion("plan_version_id", versionID)).Preload("University").Where("id = ?", id).First(&program).Error; err != nil {
 		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Program not found"})
 		return
 	}
@@ -129,6 +133,29 @@ func UpdateProgram(c *gin.Context) {
 			delete(updates, "universityID")
 		}
 	}
+	if raw, ok := updates["requirementValidation"]; ok {
+		delete(updates, "requirementValidation")
+		value, _ := raw.(string)
+		mode, valid := parseValidationMode(value)
+		if !valid {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "invalid requirementValidation"})
+			return
+		}
+		updates["requirement_validation"] = mode
+	}
+	if raw, ok := updates["regularizationMonths"]; ok {
+		delete(updates, "regularizationMonths")
+		if raw == nil {
+			updates["regularization_months"] = nil
+		} else {
+			months, valid := raw.(float64)
+			if !valid || months <= 0 || months != float64(int(months)) {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "regularizationMonths must be a positive integer"})
+				return
+			}
+			updates["regularization_months"] = int(months)
+		}
+	}
 
 	if err := db.Db.Model(&updatedProgram).Updates(updates).Error; err != nil {
 		slog.Error("Error updating the program from db", "programID", id, slog.Any("Error: ", err))
@@ -136,7 +163,7 @@ func UpdateProgram(c *gin.Context) {
 		return
 	}
 
-	if err := db.Db.Preload("Subjects").Preload("University").Where("id = ?", id).First(&updatedProgram).Error; err != nil {
+	if err := db.Db.Preload("Subjects", services.ScopePlanVersion("plan_version_id", nil)).Preload("University").Where("id = ?", id).First(&updatedProgram).Error; err != nil {
 		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Error getting the program after the update"})
 		return
 	}
@@ -177,9 +204,24 @@ func DeleteProgram(c *gin.Context) {
 		if err := tx.Exec("DELETE FROM elective_pools WHERE degree_program_id = ?", id).Error; err != nil {
 			return err
 		}
+		if err := tx.Exec(
+			"DELETE FROM subject_equivalence_sources WHERE equivalence_id IN (SELECT id FROM subject_equivalences WHERE degree_program_id = ? OR source_program_id = ?)",
+			id, id,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM subject_equivalences WHERE degree_program_id = ? OR source_program_id = ?", id, id).Error; err != nil {
+			return err
+		}
 		if err := tx.Exec("DELETE FROM subjects WHERE degree_program_id = ?", id).Error; err != nil {
 			return err
 		}
+		if err := tx.Exec("DELETE FROM user_plan_versions WHERE degree_program_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM plan_versions WHERE degree_program_id = ?", id).Error; err != nil {
+			return err
+		}
 		if err := tx.Delete(&models.DegreeProgram{}, "id = ?", id).Error; err != nil {
 			return err
 		}
@@ -214,7 +256,7 @@ func GetAllDegreeProgramsWithSubjects(c *gin.Context) {
 		return
 	}
 
-	result := query.Preload("Subjects").Preload("University").Limit(limit).Offset(offset).Find(&degreePrograms)
+	result := query.Preload("Subjects", services.ScopePlanVersion("plan_version_id", nil)).Preload("University").Limit(limit).Offset(offset).Find(&degreePrograms)
 
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -244,9 +286,41 @@ func GetAllDegreeProgramsWithSubjects(c *gin.Context) {
 	})
 }
 
+// ApproveProgram aprueba el programa. Con ?strict=true primero valida el plan base y cada versión
+// publicada; si alguno tiene errores responde 422 con esos reportes sin aprobar.
 func ApproveProgram(c *gin.Context) {
 	id := c.Param("id")
 
+	var existing models.DegreeProgram
+	if err := db.Db.Select("id").First(&existing, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+			return
+		}
+		slog.Error("Error loading the program", "programID", id, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error approving the program"})
+		return
+	}
+
+	if c.Query("strict") == "true" {
+		reports, err := services.ValidatePublishedPlans(id)
+		if err != nil {
+			slog.Error("Error validating the program", "programID", id, slog.Any("error", err))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error validating the program"})
+			return
+		}
+		invalid := make([]services.ProgramValidationReport, 0)
+		for _, report := range reports {
+			if !report.Valid {
+				invalid = append(invalid, report)
+			}
+		}
+		if len(invalid) > 0 {
+			c.IndentedJSON(http.StatusUnprocessableEntity, gin.H{"error": "The program has validation errors", "reports": invalid})
+			return
+		}
+	}
+
 	tx := db.Db.Model(&models.DegreeProgram{}).
 		Where("id = ?", id).
 		Update("approval_status", models.DegreeProgramApproved)
@@ -261,7 +335,7 @@ func ApproveProgram(c *gin.Context) {
 	}
 
 	var program models.DegreeProgram
-	if err := db.Db.Preload("Subjects").Preload("University").First(&program, "id = ?", id).Error; err != nil {
+	if err := db.Db.Preload("Subjects", services.ScopePlanVersion("plan_version_id", nil)).Preload("University").First(&program, "id = ?", id).Error; err != nil {
 		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error loading approved program"})
 		return
 	}
@@ -289,7 +363,7 @@ func PublishProgram(c *gin.Context) {
 	}
 
 	var program models.DegreeProgram
-	if err := db.Db.Preload("Subjects").Preload("University").First(&program, "id = ?", id).Error; err != nil {
+	if err := db.Db.Preload("Subjects", services.ScopePlanVersion("plan_version_id", nil)).Preload("University").First(&program, "id = ?", id).Error; err != nil {
 		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error loading published program"})
 		return
 	}
@@ -314,7 +388,7 @@ func UnapproveProgram(c *gin.Context) {
 	}
 
 	var program models.DegreeProgram
-	if err := db.Db.Preload("Subjects").Preload("University").First(&program, "id = ?", id).Error; err != nil {
+	if err := db.Db.Preload("Subjects", services.ScopePlanVersion("plan_version_id", nil)).Preload("University").First(&program, "id = ?", id).Error; err != nil {
 		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error loading unapproved program"})
 		return
 	}
@@ -339,7 +413,7 @@ func UnpublishProgram(c *gin.Context) {
 	}
 
 	var program models.DegreeProgram
-	if err := db.Db.Preload("Subjects").Preload("University").First(&program, "id = ?", id).Error; err != nil {
+	if err := db.Db.Preload("Subjects", services.ScopePlanVersion("plan_version_id", nil)).Preload("University").First(&program, "id = ?", id).Error; err != nil {
 		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error loading unpublished program"})
 		return
 	}
@@ -381,6 +455,73 @@ func canViewProgram(c *gin.Context, program *models.DegreeProgram) bool {
 	return count > 0
 }
 
+// canWriteProgram es la versión silenciosa de ensureProgramWriteAccessForProgram: sólo dice si
+// el usuario puede editar el programa, sin responder.
+func canWriteProgram(c *gin.Context, program *models.DegreeProgram) bool {
+	if isAdminOrStaff(c) {
+		return true
+	}
+	if program.ApprovalStatus == models.DegreeProgramApproved && program.PublicRequested {
+		return false
+	}
+	u, ok := c.Get("user")
+	if !ok {
+		return false
+	}
+	user, ok := u.(models.User)
+	if !ok {
+		return false
+	}
+	var count int64
+	_ = db.Db.Table("user_degree_programs").
+		Where("user_id = ? AND degree_program_id = ?", user.ID, program.ID).
+		Count(&count).Error
+	return count > 0
+}
+
+// viewablePrograms aplica canViewProgram a varios programas resolviendo las inscripciones en una
+// sola consulta.
+func viewablePrograms(c *gin.Context, programs []models.DegreeProgram) []models.DegreeProgram {
+	visible := make([]models.DegreeProgram, 0, len(programs))
+	pending := make([]string, 0)
+	staff := isAdminOrStaff(c)
+	for _, p := range programs {
+		if staff || (p.ApprovalStatus == models.DegreeProgramApproved && p.PublicRequested) {
+			visible = append(visible, p)
+			continue
+		}
+		pending = append(pending, p.ID)
+	}
+	if len(pending) == 0 {
+		return visible
+	}
+	u, ok := c.Get("user")
+	if !ok {
+		return visible
+	}
+	user, ok := u.(models.User)
+	if !ok {
+		return visible
+	}
+	var enrolledIDs []string
+	if err := db.Db.Table("user_degree_programs").
+		Where("user_id = ? AND degree_program_id IN ?", user.ID, pending).
+		Pluck("degree_program_id", &enrolledIDs).Error; err != nil {
+		slog.Error("Error loading enrollments", slog.Any("error", err))
+		return visible
+	}
+	enrolled := make(map[string]bool, len(enrolledIDs))
+	for _, id := range enrolledIDs {
+		enrolled[id] = true
+	}
+	for _, p := range programs {
+		if enrolled[p.ID] {
+			visible = append(visible, p)
+		}
+	}
+	return visible
+}
+
 func ensureProgramWriteAccess(c *gin.Context, programID string) bool {
 	var program models.DegreeProgram
 	if err := db.Db.Select("id", "approval_status", "public_requested").Where("id = ?", programID).First(&program).Error; err != nil {
@@ -432,6 +573,52 @@ func ensureProgramWriteAccessForProgram(c *gin.Context, program *models.DegreePr
 	return true
 }
 
+// UpsertSeed reimporta un seed sobre un programa existente (?version= para una versión del
+// plan). Con ?dry_run=true sólo devuelve el diff; ?force=true permite borrar materias con avance.
+func UpsertSeed(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var program models.DegreeProgram
+	if err := db.Db.Where("id = ?", programID).First(&program).Error; err != nil {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+		return
+	}
+	versionID, ok := requestedPlanVersion(c, programID)
+	if !ok {
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+	if !dryRun && !ensurePlanVersionWritable(c, versionID) {
+		return
+	}
+
+	var payload models.JsonToDegreeProgram
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "invalid JSON"})
+		return
+	}
+	if err := services.ValidateSeed(&payload); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	diff, err := services.UpsertSeed(program, versionID, payload, dryRun, c.Query("force") == "true")
+	if err != nil {
+		if errors.Is(err, services.ErrSeedRemovesProgress) {
+			c.IndentedJSON(http.StatusConflict, gin.H{"error": "The seed removes subjects with user progress; use force=true to remove them", "diff": diff})
+			return
+		}
+		slog.Error("Error re-importing the seed", "programID", programID, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "failed to re-import the seed"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, diff)
+}
+
 func UploadSeed(c *gin.Context) {
 	var payload models.JsonToDegreeProgram
 
@@ -455,51 +642,7 @@ func UploadSeed(c *gin.Context) {
 		return
 	}
 
-	validTerms := map[models.SubjectTerm]struct{}{
-		models.TermAnnual:    {},
-		models.TermSemester:  {},
-		models.TermQuarterly: {},
-		models.TermBimonthly: {},
-	}
-	codeMap := make(map[string]struct{}, len(payload.Subjects))
-	for i := range payload.Subjects {
-		payload.Subjects[i].Code = strings.TrimSpace(payload.Subjects[i].Code)
-		payload.Subjects[i].Name = strings.TrimSpace(payload.Subjects[i].Name)
-		s := payload.Subjects[i]
-
-		if s.Code == "" {
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("subject at index %d is missing a code", i)})
-			return
-		}
-		if s.Name == "" {
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("subject %q is missing a name", s.Code)})
-			return
-		}
-		if _, valid := validTerms[s.Term]; !valid {
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("subject %q has invalid term %q", s.Code, s.Term)})
-			return
-		}
-		if _, exists := codeMap[s.Code]; exists {
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("duplicate subject code %q", s.Code)})
-			return
-		}
-		codeMap[s.Code] = struct{}{}
-	}
-
-	for _, s := range payload.Subjects {
-		for _, req := range s.Requirements {
-			if _, exists := codeMap[req.SubjectCode]; !exists {
-				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("subject %q references unknown requirement code %q", s.Code, req.SubjectCode)})
-				return
-			}
-			if req.Type != models.SeedRequirementApproved && req.Type != models.SeedRequirementRegularize {
-				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("subject %q has invalid requirement type %q", s.Code, req.Type)})
-				return
-			}
-		}
-	}
-
-	if err := detectCircularDeps(payload.Subjects); err != nil {
+	if err := services.ValidateSeed(&payload); err != nil {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -536,8 +679,11 @@ func UploadSeed(c *gin.Context) {
 			newSubject := models.Subject{
 				ID:              uuid.NewString(),
 				Name:            sub.Name,
+				Code:            sub.Code,
 				Term:            string(sub.Term),
 				Year:            &year,
+				Credits:         sub.Credits,
+				Hours:           sub.Hours,
 				IsElective:      sub.IsElective,
 				DegreeProgramID: newDegreeProgram.ID,
 			}
@@ -551,72 +697,79 @@ func UploadSeed(c *gin.Context) {
 
 		for _, sub := range payload.Subjects {
 			for _, req := range sub.Requirements {
-				var minStatus models.RequirementMinStatus
-				if req.Type == models.SeedRequirementApproved {
-					minStatus = models.ReqPassed
-				} else {
-					minStatus = models.ReqFinalPending
+				if req.Type == models.SeedRequirementCorequisite {
+					co := models.SubjectCorequisite{SubjectID: subjectCodeID[sub.Code], CorequisiteID: subjectCodeID[req.SubjectCode]}
+					if err := tx.Omit("Subject", "Corequisite").Create(&co).Error; err != nil {
+						slog.Error("Error creating subject corequisite", slog.String("subject", sub.Code), slog.String("corequisite", req.SubjectCode), slog.Any("error", err))
+						return err
+					}
+					continue
 				}
 
 				subjReq := models.SubjectRequirement{
 					SubjectID:     subjectCodeID[sub.Code],
 					RequirementID: subjectCodeID[req.SubjectCode],
-					MinStatus:     minStatus,
+					MinStatus:     services.SeedMinStatus(req.Type),
 				}
 				if err := tx.Create(&subjReq).Error; err != nil {
 					slog.Error("Error creating subject requirement", slog.String("subject", sub.Code), slog.String("requirement", req.SubjectCode), slog.Any("error", err))
 					return err
 				}
 			}
+			for _, seedGroup := range sub.RequirementGroups {
+				group := services.SeedGroupToModel(seedGroup, subjectCodeID)
+				if err := services.CreateRequirementGroupTx(tx, subjectCodeID[sub.Code], &group); err != nil {
+					slog.Error("Error creating requirement group", slog.String("subject", sub.Code), slog.Any("error", err))
+					return err
+				}
+			}
 		}
 
-		return nil
-	}); err != nil {
-		slog.Error("Seed transaction failed, rolled back", slog.Any("error", err))
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "failed to seed degree program"})
-		return
-	}
-
-	var result models.DegreeProgram
-	db.Db.Preload("Subjects").Preload("University").First(&result, "id = ?", degreeProgramID)
-	c.IndentedJSON(http.StatusCreated, result)
-}
-
-func detectCircularDeps(subjects []models.SeedSubject) error {
-	graph := make(map[string][]string, len(subjects))
-	for _, s := range subjects {
-		deps := make([]string, 0, len(s.Requirements))
-		for _, r := range s.Requirements {
-			deps = append(deps, r.SubjectCode)
-		}
-		graph[s.Code] = deps
-	}
-
-	// 0 = unvisited, 1 = in stack, 2 = done
-	state := make(map[string]int, len(subjects))
-	var dfs func(code string) error
-	dfs = func(code string) error {
-		state[code] = 1
-		for _, dep := range graph[code] {
-			if state[dep] == 1 {
-				return fmt.Errorf("circular dependency detected involving subject %q", dep)
+		poolCodeID := make(map[string]string, len(payload.ElectivePools))
+		for _, seedPool := range payload.ElectivePools {
+			pool := models.ElectivePool{
+				ID:              uuid.NewString(),
+				DegreeProgramID: newDegreeProgram.ID,
+				Name:            seedPool.Name,
+				Description:     seedPool.Description,
 			}
-			if state[dep] == 0 {
-				if err := dfs(dep); err != nil {
+			if err := tx.Omit("DegreeProgram", "Subjects").Create(&pool).Error; err != nil {
+				slog.Error("Error creating elective pool", slog.String("pool", seedPool.Code), slog.Any("error", err))
+				return err
+			}
+			poolCodeID[seedPool.Code] = pool.ID
+			for _, code := range seedPool.SubjectCodes {
+				member := models.ElectivePoolSubject{ElectivePoolID: pool.ID, SubjectID: subjectCodeID[code]}
+				if err := tx.Omit("Pool", "Subject").Create(&member).Error; err != nil {
+					slog.Error("Error adding subject to elective pool", slog.String("pool", seedPool.Code), slog.String("subject", code), slog.Any("error", err))
 					return err
 				}
 			}
 		}
-		state[code] = 2
-		return nil
-	}
-
-	for _, s := range subjects {
-		if state[s.Code] == 0 {
-			if err := dfs(s.Code); err != nil {
+		for _, seedRule := range payload.ElectiveRules {
+			rule := models.ElectiveRule{
+				ID:              uuid.NewString(),
+				DegreeProgramID: newDegreeProgram.ID,
+				PoolID:          poolCodeID[seedRule.PoolCode],
+				AppliesFromYear: seedRule.AppliesFromYear,
+				AppliesToYear:   seedRule.AppliesToYear,
+				RequirementType: seedRule.RequirementType,
+				MinimumValue:    seedRule.MinimumValue,
+			}
+			if err := tx.Omit("DegreeProgram", "Pool").Create(&rule).Error; err != nil {
+				slog.Error("Error creating elective rule", slog.String("pool", seedRule.PoolCode), slog.Any("error", err))
 				return err
 			}
 		}
+
+		return nil
+	}); err != nil {
+		slog.Error("Seed transaction failed, rolled back", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "failed to seed degree program"})
+		return
 	}
-	return nil
+
+	var result models.DegreeProgram
+	db.Db.Preload("Subjects", services.ScopePlanVersion("plan_version_id", nil)).Preload("University").First(&result, "id = ?", degreeProgramID)
+	c.IndentedJSON(http.StatusCreated, result)
 }
@@ -3,6 +3,7 @@ package handlers
 import (
 	"acadifyapp/internal/db"
 	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
 	"errors"
 	"log/slog"
 	"net/http"
@@ -13,8 +14,9 @@ import (
 )
 
 type CreateElectivePoolDTO struct {
-	Name        string  `json:"name"`
-	Description *string `json:"description,omitempty"`
+	Name          string  `json:"name"`
+	Description   *string `json:"description,omitempty"`
+	PlanVersionID *string `json:"plan_version_id,omitempty"`
 }
 
 type UpdateElectivePoolDTO struct {
@@ -58,11 +60,21 @@ func CreateElectivePool(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.PlanVersionID != nil {
+		if _, err := services.GetPlanVersion(degreeProgram.ID, *req.PlanVersionID); err != nil {
+			respondPlanVersionError(c, err)
+			return
+		}
+		if !ensurePlanVersionWritable(c, req.PlanVersionID) {
+			return
+		}
+	}
 
 	pool := models.ElectivePool{
 		ID:              uuid.NewString(),
 		DegreeProgramID: degreeProgram.ID,
 		Name:            name,
+		PlanVersionID:   req.PlanVersionID,
 	}
 	if description != nil {
 		pool.Description = *description
@@ -91,8 +103,13 @@ func GetElectivePoolsByProgram(c *gin.Context) {
 		return
 	}
 
+	versionID, ok := requestedPlanVersion(c, degreeProgram.ID)
+	if !ok {
+		return
+	}
+
 	var pools []models.ElectivePool
-	if err := db.Db.Where("degree_program_id = ?", degreeProgram.ID).Preload("Subjects").Find(&pools).Error; err != nil {
+	if err := db.Db.Where("degree_program_id = ?", degreeProgram.ID).Scopes(services.ScopePlanVersion("plan_version_id", versionID)).Preload("Subjects").Find(&pools).Error; err != nil {
 		slog.Error("Error fetching elective pools", slog.Any("error: ", err))
 		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error fetching elective pools"})
 		return
@@ -153,6 +170,10 @@ func UpdateElectivePool(c *gin.Context) {
 		return
 	}
 
+	if !ensurePlanVersionWritable(c, pool.PlanVersionID) {
+		return
+	}
+
 	var req UpdateElectivePoolDTO
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "parámetros inválidos"})
@@ -211,6 +232,10 @@ func DeleteElectivePool(c *gin.Context) {
 		return
 	}
 
+	if !ensurePlanVersionWritable(c, pool.PlanVersionID) {
+		return
+	}
+
 	if err := db.Db.Delete(&pool).Error; err != nil {
 		slog.Error("Error deleting elective pool", slog.Any("error: ", err))
 		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error deleting elective pool"})
@@ -246,6 +271,10 @@ func AddSubjectToElectivePool(c *gin.Context) {
 		return
 	}
 
+	if !ensurePlanVersionWritable(c, pool.PlanVersionID) {
+		return
+	}
+
 	var req PoolSubjectDTO
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "parámetros inválidos"})
@@ -271,6 +300,10 @@ func AddSubjectToElectivePool(c *gin.Context) {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "Subject no pertenece al degreeProgram"})
 		return
 	}
+	if !inSamePlanVersion(models.Subject{PlanVersionID: pool.PlanVersionID}, []models.Subject{subject}) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "Subject no pertenece a la versión del plan del pool"})
+		return
+	}
 
 	var link models.ElectivePoolSubject
 	if err := db.Db.Where("elective_pool_id = ? AND subject_id = ?", pool.ID, subject.ID).First(&link).Error; err == nil {
@@ -326,6 +359,10 @@ func RemoveSubjectFromElectivePool(c *gin.Context) {
 		return
 	}
 
+	if !ensurePlanVersionWritable(c, pool.PlanVersionID) {
+		return
+	}
+
 	var link models.ElectivePoolSubject
 	if err := db.Db.Where("elective_pool_id = ? AND subject_id = ?", pool.ID, subjectID).First(&link).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -3,6 +3,7 @@ package handlers
 import (
 	"acadifyapp/internal/db"
 	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
 	"errors"
 	"log/slog"
 	"net/http"
@@ -87,6 +88,9 @@ func CreateElectiveRule(c *gin.Context) {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "Pool no pertenece al degreeProgram"})
 		return
 	}
+	if !ensurePlanVersionWritable(c, pool.PlanVersionID) {
+		return
+	}
 
 	rule := models.ElectiveRule{
 		ID:              uuid.NewString(),
@@ -96,6 +100,7 @@ func CreateElectiveRule(c *gin.Context) {
 		AppliesToYear:   req.AppliesToYear,
 		RequirementType: req.RequirementType,
 		MinimumValue:    req.MinimumValue,
+		PlanVersionID:   pool.PlanVersionID,
 	}
 
 	if err := db.Db.Create(&rule).Error; err != nil {
@@ -121,8 +126,13 @@ func GetElectiveRulesByProgram(c *gin.Context) {
 		return
 	}
 
+	versionID, ok := requestedPlanVersion(c, degreeProgram.ID)
+	if !ok {
+		return
+	}
+
 	var rules []models.ElectiveRule
-	if err := db.Db.Where("degree_program_id = ?", degreeProgram.ID).Preload("Pool").Find(&rules).Error; err != nil {
+	if err := db.Db.Where("degree_program_id = ?", degreeProgram.ID).Scopes(services.ScopePlanVersion("plan_version_id", versionID)).Preload("Pool").Find(&rules).Error; err != nil {
 		slog.Error("Error fetching elective rules", slog.Any("error: ", err))
 		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error fetching elective rules"})
 		return
@@ -183,6 +193,10 @@ func UpdateElectiveRule(c *gin.Context) {
 		return
 	}
 
+	if !ensurePlanVersionWritable(c, rule.PlanVersionID) {
+		return
+	}
+
 	var req UpdateElectiveRuleDTO
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "parámetros inválidos"})
@@ -238,6 +252,10 @@ func UpdateElectiveRule(c *gin.Context) {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "Pool no pertenece al degreeProgram"})
 			return
 		}
+		if !inSamePlanVersion(models.Subject{PlanVersionID: rule.PlanVersionID}, []models.Subject{{PlanVersionID: pool.PlanVersionID}}) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "Pool no pertenece a la versión del plan de la regla"})
+			return
+		}
 		rule.PoolID = *req.PoolID
 	}
 	if req.AppliesFromYear != nil {
@@ -288,6 +306,10 @@ func DeleteElectiveRule(c *gin.Context) {
 		return
 	}
 
+	if !ensurePlanVersionWritable(c, rule.PlanVersionID) {
+		return
+	}
+
 	if err := db.Db.Delete(&rule).Error; err != nil {
 		slog.Error("Error deleting elective rule", slog.Any("error: ", err))
 		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error deleting elective rule"})
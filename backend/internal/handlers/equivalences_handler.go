@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxEquivalenceSources = 20
+
+type SaveEquivalenceDTO struct {
+	// SourceProgramID es el programa del plan viejo; vacío significa el mismo programa (otra versión).
+	SourceProgramID  string   `json:"source_program_id"`
+	TargetSubjectID  string   `json:"target_subject_id"`
+	SourceSubjectIDs []string `json:"source_subject_ids"`
+}
+
+type MigrationDTO struct {
+	ToProgramID string  `json:"to_program_id"`
+	ToVersionID *string `json:"to_version_id"`
+}
+
+func respondEquivalenceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrEquivalenceNotFound):
+		c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Equivalence not found"})
+	case errors.Is(err, services.ErrInvalidEquivalence):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "Las materias de la equivalencia no son válidas"})
+	case errors.Is(err, services.ErrSameMigrationTarget):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "El plan destino es el plan actual"})
+	case errors.Is(err, services.ErrPlanVersionNotFound):
+		respondPlanVersionError(c, err)
+	default:
+		slog.Error("Error handling equivalences", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error handling equivalences"})
+	}
+}
+
+// bindEquivalence valida el payload y arma la equivalencia hacia programID.
+func bindEquivalence(c *gin.Context, programID string) (*models.SubjectEquivalence, []string, bool) {
+	var req SaveEquivalenceDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "parámetros inválidos"})
+		return nil, nil, false
+	}
+
+	sourceProgramID := programID
+	if strings.TrimSpace(req.SourceProgramID) != "" {
+		id, err := validateID(req.SourceProgramID, "source_program_id")
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return nil, nil, false
+		}
+		sourceProgramID = id
+	}
+	targetSubjectID, err := validateID(req.TargetSubjectID, "target_subject_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return nil, nil, false
+	}
+	if len(req.SourceSubjectIDs) == 0 || len(req.SourceSubjectIDs) > maxEquivalenceSources {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "source_subject_ids debe tener entre 1 y 20 materias"})
+		return nil, nil, false
+	}
+	sourceIDs := make([]string, 0, len(req.SourceSubjectIDs))
+	for _, raw := range req.SourceSubjectIDs {
+		id, err := validateID(raw, "source_subject_ids")
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return nil, nil, false
+		}
+		sourceIDs = append(sourceIDs, id)
+	}
+
+	return &models.SubjectEquivalence{
+		DegreeProgramID: programID,
+		SourceProgramID: sourceProgramID,
+		TargetSubjectID: targetSubjectID,
+	}, sourceIDs, true
+}
+
+func GetEquivalencesByProgram(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	if _, ok := loadViewableProgram(c, programID); !ok {
+		return
+	}
+	sourceProgramID := strings.TrimSpace(c.Query("source_program_id"))
+	if sourceProgramID != "" {
+		if sourceProgramID, err = validateID(sourceProgramID, "source_program_id"); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+	}
+
+	equivalences, err := services.ListEquivalences(programID, sourceProgramID)
+	if err != nil {
+		respondEquivalenceError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, equivalences)
+}
+
+func CreateEquivalence(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	equivalence, sourceIDs, ok := bindEquivalence(c, programID)
+	if !ok {
+		return
+	}
+
+	if err := services.SaveEquivalence(equivalence, sourceIDs); err != nil {
+		respondEquivalenceError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, equivalence)
+}
+
+func UpdateEquivalence(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	equivalenceID, err := validateID(c.Param("equivalenceId"), "equivalence_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	existing, err := services.GetEquivalence(programID, equivalenceID)
+	if err != nil {
+		respondEquivalenceError(c, err)
+		return
+	}
+	equivalence, sourceIDs, ok := bindEquivalence(c, programID)
+	if !ok {
+		return
+	}
+	equivalence.ID = existing.ID
+	equivalence.CreatedAt = existing.CreatedAt
+
+	if err := services.SaveEquivalence(equivalence, sourceIDs); err != nil {
+		respondEquivalenceError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, equivalence)
+}
+
+func DeleteEquivalence(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	equivalenceID, err := validateID(c.Param("equivalenceId"), "equivalence_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	if err := services.DeleteEquivalence(programID, equivalenceID); err != nil {
+		respondEquivalenceError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// migrationTarget resuelve el plan destino; sin programa se usa el mismo (cambio de versión).
+func migrationTarget(c *gin.Context, programID string, rawProgramID string, rawVersionID *string) (string, *string, bool) {
+	targetProgramID := programID
+	if strings.TrimSpace(rawProgramID) != "" {
+		id, err := validateID(rawProgramID, "to_program_id")
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return "", nil, false
+		}
+		targetProgramID = id
+	}
+	var targetVersionID *string
+	if rawVersionID != nil && strings.TrimSpace(*rawVersionID) != "" {
+		id, err := validateID(*rawVersionID, "to_version_id")
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return "", nil, false
+		}
+		targetVersionID = &id
+	}
+	return targetProgramID, targetVersionID, true
+}
+
+// PreviewMyMigration muestra cómo quedaría el avance del usuario en el plan destino sin guardar nada.
+// Si el destino es otro programa, tiene que ser visible para el usuario (público o inscripto).
+func PreviewMyMigration(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+	toVersion := c.Query("to_version_id")
+	targetProgramID, targetVersionID, ok := migrationTarget(c, programID, c.Query("to_program_id"), &toVersion)
+	if !ok {
+		return
+	}
+	if !ensureEnrolled(c, user.ID, programID) {
+		return
+	}
+	// El destino puede ser otro programa: sólo se previsualiza si el usuario puede verlo.
+	if targetProgramID != programID {
+		if _, ok := loadViewableProgram(c, targetProgramID); !ok {
+			return
+		}
+	}
+
+	preview, err := services.PreviewMigration(user.ID, programID, targetProgramID, targetVersionID)
+	if err != nil {
+		respondEquivalenceError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, preview)
+}
+
+// MigrateMyProgram aplica el diff de PreviewMyMigration. El usuario tiene que estar inscripto en
+// ambos programas; el avance en el plan de origen se conserva.
+func MigrateMyProgram(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+	var req MigrationDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "parámetros inválidos"})
+		return
+	}
+	targetProgramID, targetVersionID, ok := migrationTarget(c, programID, req.ToProgramID, req.ToVersionID)
+	if !ok {
+		return
+	}
+	if !ensureEnrolled(c, user.ID, programID) {
+		return
+	}
+	if targetProgramID != programID && !ensureEnrolled(c, user.ID, targetProgramID) {
+		return
+	}
+
+	preview, err := services.PreviewMigration(user.ID, programID, targetProgramID, targetVersionID)
+	if err != nil {
+		respondEquivalenceError(c, err)
+		return
+	}
+	if err := services.ApplyMigration(user.ID, preview); err != nil {
+		respondEquivalenceError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, preview)
+}
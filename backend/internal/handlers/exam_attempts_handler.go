@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CreateExamAttemptDTO struct {
+	Date   string          `json:"date"`
+	Grade  float64         `json:"grade"`
+	Passed *bool           `json:"passed"`
+	Type   models.ExamType `json:"type"`
+}
+
+type UpdateExamAttemptDTO struct {
+	Date   *string          `json:"date,omitempty"`
+	Grade  *float64         `json:"grade,omitempty"`
+	Passed *bool            `json:"passed,omitempty"`
+	Type   *models.ExamType `json:"type,omitempty"`
+}
+
+// parseAttemptDate acepta fechas YYYY-MM-DD o RFC3339.
+func parseAttemptDate(raw string) (time.Time, bool) {
+	raw = strings.TrimSpace(raw)
+	if date, err := time.Parse(time.DateOnly, raw); err == nil {
+		return date, true
+	}
+	if date, err := time.Parse(time.RFC3339, raw); err == nil {
+		return date.UTC(), true
+	}
+	return time.Time{}, false
+}
+
+func validExamType(examType models.ExamType) bool {
+	switch examType {
+	case models.ExamFinal, models.ExamPromotion, models.ExamEquivalence:
+		return true
+	default:
+		return false
+	}
+}
+
+// attemptSubject valida el usuario y la materia del path y que el usuario esté inscripto en su programa.
+func attemptSubject(c *gin.Context) (models.User, string, bool) {
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return models.User{}, "", false
+	}
+	subjectID, err := validateID(c.Param("subjectId"), "subject_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return models.User{}, "", false
+	}
+
+	var subject models.Subject
+	if err := db.Db.Select("id", "degree_program_id").Where("id = ?", subjectID).First(&subject).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Subject not found"})
+			return models.User{}, "", false
+		}
+		slog.Error("Error loading subject", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error loading subject"})
+		return models.User{}, "", false
+	}
+	if !ensureEnrolled(c, user.ID, subject.DegreeProgramID) {
+		return models.User{}, "", false
+	}
+	return user, subjectID, true
+}
+
+func GetMyExamAttempts(c *gin.Context) {
+	user, subjectID, ok := attemptSubject(c)
+	if !ok {
+		return
+	}
+
+	attempts, err := services.ListExamAttempts(user.ID, subjectID)
+	if err != nil {
+		slog.Error("Error loading exam attempts", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error loading exam attempts"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, attempts)
+}
+
+func CreateMyExamAttempt(c *gin.Context) {
+	user, subjectID, ok := attemptSubject(c)
+	if !ok {
+		return
+	}
+
+	var req CreateExamAttemptDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "parámetros inválidos"})
+		return
+	}
+	date, ok := parseAttemptDate(req.Date)
+	if !ok {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "date debe tener formato YYYY-MM-DD"})
+		return
+	}
+	if req.Grade < 0 || req.Grade > 10 {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "grade must be between 0 and 10"})
+		return
+	}
+	if req.Passed == nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "passed es requerido"})
+		return
+	}
+	if req.Type == "" {
+		req.Type = models.ExamFinal
+	}
+	if !validExamType(req.Type) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "type inválido"})
+		return
+	}
+
+	attempt := models.ExamAttempt{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		SubjectID: subjectID,
+		Date:      date,
+		Grade:     req.Grade,
+		Passed:    *req.Passed,
+		Type:      req.Type,
+	}
+	userSubject, err := services.CreateExamAttempt(&attempt)
+	if err != nil {
+		slog.Error("Error creating exam attempt", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error creating exam attempt"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, gin.H{"attempt": attempt, "subject": userSubjectResult(userSubject)})
+}
+
+func UpdateMyExamAttempt(c *gin.Context) {
+	user, subjectID, ok := attemptSubject(c)
+	if !ok {
+		return
+	}
+	attemptID, err := validateID(c.Param("attemptId"), "attempt_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	var req UpdateExamAttemptDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "parámetros inválidos"})
+		return
+	}
+	var date time.Time
+	if req.Date != nil {
+		date, ok = parseAttemptDate(*req.Date)
+		if !ok {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "date debe tener formato YYYY-MM-DD"})
+			return
+		}
+	}
+	if req.Grade != nil && (*req.Grade < 0 || *req.Grade > 10) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "grade must be between 0 and 10"})
+		return
+	}
+	if req.Type != nil && !validExamType(*req.Type) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "type inválido"})
+		return
+	}
+
+	attempt, userSubject, err := services.UpdateExamAttempt(user.ID, subjectID, attemptID, func(a *models.ExamAttempt) {
+		if req.Date != nil {
+			a.Date = date
+		}
+		if req.Grade != nil {
+			a.Grade = *req.Grade
+		}
+		if req.Passed != nil {
+			a.Passed = *req.Passed
+		}
+		if req.Type != nil {
+			a.Type = *req.Type
+		}
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrExamAttemptNotFound) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Exam attempt not found"})
+			return
+		}
+		slog.Error("Error updating exam attempt", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error updating exam attempt"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"attempt": attempt, "subject": userSubjectResult(userSubject)})
+}
+
+func DeleteMyExamAttempt(c *gin.Context) {
+	user, subjectID, ok := attemptSubject(c)
+	if !ok {
+		return
+	}
+	attemptID, err := validateID(c.Param("attemptId"), "attempt_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	userSubject, err := services.DeleteExamAttempt(user.ID, subjectID, attemptID)
+	if err != nil {
+		if errors.Is(err, services.ErrExamAttemptNotFound) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Exam attempt not found"})
+			return
+		}
+		slog.Error("Error deleting exam attempt", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error deleting exam attempt"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"ok": true, "subject": userSubjectResult(userSubject)})
+}
+
+func userSubjectResult(us *models.UserSubject) gin.H {
+	return gin.H{
+		"id":                 us.SubjectID,
+		"status":             us.Status,
+		"final_calification": us.FinalCalification,
+	}
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"acadifyapp/internal/db"
 	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
 	"bytes"
 	"encoding/json"
 	"net/http"
@@ -9,7 +11,11 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 type stubPasswordResetMailer struct{}
@@ -35,6 +41,26 @@ func performRequest(t *testing.T, method, route, path string, body []byte, handl
 	return w
 }
 
+// performRequestAs es performRequest con el usuario autenticado ya cargado en el contexto,
+// como lo deja el middleware de auth.
+func performRequestAs(t *testing.T, user models.User, method, route, path string, body []byte, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Handle(method, route, func(c *gin.Context) {
+		c.Set("user", user)
+		c.Next()
+	}, handler)
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	return w
+}
+
 func TestCreateUser_InvalidJSON_Returns400(t *testing.T) {
 	t.Parallel()
 
@@ -259,7 +285,7 @@ func TestGetAllSubjectsFromProgram_BuildsMinStatus(t *testing.T) {
 
 	// TODO (requiere DB):
 	// - Crear program + subjects + requirements con min_status distinto.
-	// - Llamar GET /subjects/:programId.
+	// - Llamar GET /subjects/:id.
 	// - Verificar que cada requirement incluya minStatus correcto.
 }
 
@@ -467,3 +493,549 @@ func TestRevokeSession_UserNotFound_Returns404(t *testing.T) {
 	// - Llamar revoke con userId inexistente.
 	// - Esperar 404 "User not found".
 }
+
+func TestGetMyProgramProgress_NoUser_Returns401(t *testing.T) {
+	t.Parallel()
+
+	w := performRequest(t, http.MethodGet, "/me/programs/:id/progress", "/me/programs/123/progress", nil, GetMyProgramProgress)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestCreateMyExamAttempt_NoUser_Returns401(t *testing.T) {
+	t.Parallel()
+
+	w := performRequest(t, http.MethodPost, "/me/attempts/:subjectId", "/me/attempts/123", []byte(`{"date":"2024-03-01","grade":8,"passed":true}`), CreateMyExamAttempt)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestSetMyPlanVersion_NoUser_Returns401(t *testing.T) {
+	t.Parallel()
+
+	w := performRequest(t, http.MethodPut, "/me/programs/:id/version", "/me/programs/123/version", []byte(`{"plan_version_id":null}`), SetMyPlanVersion)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestInSamePlanVersion(t *testing.T) {
+	t.Parallel()
+
+	v1, v2 := "v1", "v2"
+	tests := []struct {
+		name    string
+		subject *string
+		reqs    []*string
+		want    bool
+	}{
+		{name: "base plan", subject: nil, reqs: []*string{nil, nil}, want: true},
+		{name: "same version", subject: &v1, reqs: []*string{&v1}, want: true},
+		{name: "base requirement in version", subject: &v1, reqs: []*string{nil}, want: false},
+		{name: "versioned requirement in base", subject: nil, reqs: []*string{&v1}, want: false},
+		{name: "different versions", subject: &v1, reqs: []*string{&v1, &v2}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqs := make([]models.Subject, 0, len(tt.reqs))
+			for _, id := range tt.reqs {
+				reqs = append(reqs, models.Subject{PlanVersionID: id})
+			}
+			if got := inSamePlanVersion(models.Subject{PlanVersionID: tt.subject}, reqs); got != tt.want {
+				t.Fatalf("inSamePlanVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSubjectRequirements_InvalidID_Returns400(t *testing.T) {
+	t.Parallel()
+
+	w := performRequest(t, http.MethodGet, "/subjects/:id/requirements", "/subjects/"+strings.Repeat("x", maxIDLen+1)+"/requirements", nil, GetSubjectRequirements)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGetMyProgramRecord_InvalidFormat_Returns400(t *testing.T) {
+	t.Parallel()
+
+	w := performRequest(t, http.MethodGet, "/me/programs/:id/record", "/me/programs/p1/record?format=xlsx", nil, GetMyProgramRecord)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGetSharedProgress_MalformedToken_Returns404(t *testing.T) {
+	t.Parallel()
+
+	for _, token := range []string{"abc", strings.Repeat("z", 64), strings.Repeat("a", 65)} {
+		w := performRequest(t, http.MethodGet, "/shared/:token", "/shared/"+token, nil, GetSharedProgress)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("token %q: status = %d, want %d", token, w.Code, http.StatusNotFound)
+		}
+		if w.Header().Get("Cache-Control") != "no-store" {
+			t.Fatalf("token %q: missing Cache-Control: no-store", token)
+		}
+	}
+}
+
+func TestSearch_InvalidQuery_Returns400(t *testing.T) {
+	t.Parallel()
+
+	for _, query := range []string{"", "?q=%20a%20", "?q=" + strings.Repeat("x", 101), "?q=fisica&type=course"} {
+		w := performRequest(t, http.MethodGet, "/search", "/search"+query, nil, Search)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("query %q: status = %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+// useMockDB reemplaza db.Db por una conexión de sqlmock mientras dura el test. Los tests que la
+// usan no pueden ser paralelos porque db.Db es global.
+func useMockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	gdb, err := gorm.Open(mysql.New(mysql.Config{Conn: conn, SkipInitializeWithVersion: true}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("gorm: %v", err)
+	}
+	previous := db.Db
+	db.Db = gdb
+	t.Cleanup(func() {
+		db.Db = previous
+		conn.Close()
+	})
+	return mock
+}
+
+func TestGetProgramById_ReturnsOnlyRequestedPlanVersionSubjects(t *testing.T) {
+	programColumns := []string{"id", "name", "university_id", "approval_status", "public_requested"}
+	subjectColumns := []string{"id", "name", "degree_program_id", "plan_version_id"}
+	tests := []struct {
+		name    string
+		path    string
+		version string
+		want    string
+	}{
+		{"plan base por defecto", "/degreeProgram/p1", "", "base-1"},
+		{"versión pedida", "/degreeProgram/p1?version=v2", "v2", "v2-1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := useMockDB(t)
+			if tc.version != "" {
+				mock.ExpectQuery("SELECT \\* FROM `plan_versions`").WithArgs(tc.version, "p1", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "degree_program_id", "status"}).AddRow(tc.version, "p1", models.PlanVersionPublished))
+			}
+			mock.ExpectQuery("SELECT \\* FROM `degree_programs`").
+				WillReturnRows(sqlmock.NewRows(programColumns).AddRow("p1", "Sistemas", "u1", models.DegreeProgramApproved, true))
+			var subjects *sqlmock.ExpectedQuery
+			var versionID any
+			if tc.version == "" {
+				subjects = mock.ExpectQuery("SELECT \\* FROM `subjects` WHERE `subjects`.`degree_program_id` = \\? AND plan_version_id IS NULL").WithArgs("p1")
+			} else {
+				subjects = mock.ExpectQuery("SELECT \\* FROM `subjects` WHERE `subjects`.`degree_program_id` = \\? AND plan_version_id = \\?").WithArgs("p1", tc.version)
+				versionID = tc.version
+			}
+			subjects.WillReturnRows(sqlmock.NewRows(subjectColumns).AddRow(tc.want, "Álgebra", "p1", versionID))
+			mock.ExpectQuery("SELECT \\* FROM `universities`").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("u1", "UTN"))
+
+			w := performRequest(t, http.MethodGet, "/degreeProgram/:id", tc.path, nil, GetProgramById)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+			var program models.DegreeProgram
+			if err := json.Unmarshal(w.Body.Bytes(), &program); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if len(program.Subjects) != 1 || program.Subjects[0].ID != tc.want {
+				t.Fatalf("subjects = %+v, want only %s", program.Subjects, tc.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestGetProgramById_DraftVersionAnonymous_Returns404(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `plan_versions`").WithArgs("v2", "p1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "degree_program_id", "status"}).AddRow("v2", "p1", models.PlanVersionDraft))
+	mock.ExpectQuery("SELECT `id`,`approval_status`,`public_requested` FROM `degree_programs`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_status", "public_requested"}).AddRow("p1", models.DegreeProgramApproved, true))
+
+	w := performRequest(t, http.MethodGet, "/degreeProgram/:id", "/degreeProgram/p1?version=v2", nil, GetProgramById)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetSubjectRecognitions_PrivateProgram_Returns404(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT `id`,`degree_program_id` FROM `subjects`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "degree_program_id"}).AddRow("s1", "p1"))
+	mock.ExpectQuery("SELECT \\* FROM `degree_programs`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_status", "public_requested"}).AddRow("p1", models.DegreeProgramPending, false))
+
+	w := performRequest(t, http.MethodGet, "/recognitions/:subjectId", "/recognitions/s1", nil, GetSubjectRecognitions)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectWritableSubject prepara las consultas de loadWritableSubject para la materia s2 del plan
+// base de p1 con un usuario staff.
+func expectWritableSubject(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT \\* FROM `subjects` WHERE id = \\?").WithArgs("s2", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "degree_program_id"}).AddRow("s2", "Análisis II", "p1"))
+	mock.ExpectQuery("SELECT `id`,`approval_status`,`public_requested` FROM `degree_programs`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_status", "public_requested"}).AddRow("p1", models.DegreeProgramPending, false))
+}
+
+func requirementRows(pairs ...string) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"subject_id", "requirement_id", "min_status"})
+	for i := 0; i+1 < len(pairs); i += 2 {
+		rows.AddRow(pairs[i], pairs[i+1], models.ReqPassed)
+	}
+	return rows
+}
+
+var staffUser = models.User{ID: "staff-1", Role: "staff"}
+
+func TestAddSubjectRequirement(t *testing.T) {
+	tests := []struct {
+		name         string
+		requirements *sqlmock.Rows
+		corequisites *sqlmock.Rows
+		wantStatus   int
+	}{
+		{
+			name:         "crea la correlativa",
+			requirements: requirementRows("s2", "s1"),
+			corequisites: sqlmock.NewRows([]string{"subject_id", "corequisite_id"}),
+			wantStatus:   http.StatusCreated,
+		},
+		{
+			name:         "rechaza un ciclo",
+			requirements: requirementRows("s2", "s1", "s1", "s2"),
+			wantStatus:   http.StatusBadRequest,
+		},
+		{
+			name:         "rechaza una correlativa que es co-requisito",
+			requirements: requirementRows("s2", "s1"),
+			corequisites: sqlmock.NewRows([]string{"subject_id", "corequisite_id"}).AddRow("s2", "s1"),
+			wantStatus:   http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := useMockDB(t)
+			expectWritableSubject(mock)
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT \\* FROM `subjects` WHERE \\(id = \\? AND degree_program_id = \\?\\) AND plan_version_id IS NULL").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "degree_program_id"}).AddRow("s1", "p1"))
+			mock.ExpectQuery("SELECT \\* FROM `subject_requirements`").WillReturnRows(requirementRows())
+			mock.ExpectExec("INSERT INTO `subject_requirements`").WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery("SELECT `id` FROM `subjects`").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("s1").AddRow("s2"))
+			mock.ExpectQuery("SELECT \\* FROM `subject_requirements`").WillReturnRows(tc.requirements)
+			mock.ExpectQuery("SELECT \\* FROM `requirement_groups`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			if tc.corequisites != nil {
+				mock.ExpectQuery("SELECT `id` FROM `subjects`").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("s1").AddRow("s2"))
+				mock.ExpectQuery("SELECT \\* FROM `subject_corequisites`").WillReturnRows(tc.corequisites)
+			}
+			if tc.wantStatus == http.StatusCreated {
+				mock.ExpectCommit()
+			} else {
+				if tc.corequisites != nil {
+					mock.ExpectQuery("SELECT \\* FROM `subject_requirements`").WillReturnRows(requirementRows("s2", "s1"))
+					mock.ExpectQuery("SELECT \\* FROM `requirement_groups`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				}
+				mock.ExpectRollback()
+			}
+
+			w := performRequestAs(t, staffUser, http.MethodPost, "/subjects/:id/requirements", "/subjects/s2/requirements", []byte(`{"requirement_id":"s1"}`), AddSubjectRequirement)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tc.wantStatus, w.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestUpdateSubjectRequirement(t *testing.T) {
+	t.Run("cambia el estado mínimo", func(t *testing.T) {
+		mock := useMockDB(t)
+		expectWritableSubject(mock)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT \\* FROM `subject_requirements`").WillReturnRows(requirementRows("s2", "s1"))
+		mock.ExpectExec("UPDATE `subject_requirements` SET `min_status`=\\?").
+			WithArgs(models.ReqFinalPending, sqlmock.AnyArg(), "s2", "s1").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT `id` FROM `subjects`").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("s1").AddRow("s2"))
+		mock.ExpectQuery("SELECT \\* FROM `subject_requirements`").WillReturnRows(requirementRows("s2", "s1"))
+		mock.ExpectQuery("SELECT \\* FROM `requirement_groups`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		w := performRequestAs(t, staffUser, http.MethodPut, "/subjects/:id/requirements/:requirementId", "/subjects/s2/requirements/s1", []byte(`{"min_status":"final_pending"}`), UpdateSubjectRequirement)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var row models.SubjectRequirement
+		if err := json.Unmarshal(w.Body.Bytes(), &row); err != nil || row.MinStatus != models.ReqFinalPending {
+			t.Fatalf("body = %s, want min status final_pending", w.Body.String())
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("rechaza in_progress", func(t *testing.T) {
+		mock := useMockDB(t)
+		expectWritableSubject(mock)
+
+		w := performRequestAs(t, staffUser, http.MethodPut, "/subjects/:id/requirements/:requirementId", "/subjects/s2/requirements/s1", []byte(`{"min_status":"in_progress"}`), UpdateSubjectRequirement)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestDeleteSubjectRequirement(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   *sqlmock.Rows
+		wantStatus int
+	}{
+		{name: "borra la correlativa", existing: requirementRows("s2", "s1"), wantStatus: http.StatusOK},
+		{name: "correlativa inexistente", existing: requirementRows(), wantStatus: http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := useMockDB(t)
+			expectWritableSubject(mock)
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT \\* FROM `subject_requirements`").WillReturnRows(tc.existing)
+			if tc.wantStatus == http.StatusOK {
+				mock.ExpectExec("DELETE FROM `subject_requirements`").WithArgs("s2", "s1").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			w := performRequestAs(t, staffUser, http.MethodDelete, "/subjects/:id/requirements/:requirementId", "/subjects/s2/requirements/s1", nil, DeleteSubjectRequirement)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tc.wantStatus, w.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestApproveProgram_MissingProgramReturns404BeforeValidating(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT `id` FROM `degree_programs`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	w := performRequest(t, http.MethodPost, "/degree-programs/:id/approve", "/degree-programs/missing/approve?strict=true", nil, ApproveProgram)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApproveProgram_StrictValidatesPublishedVersions(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT `id` FROM `degree_programs`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("p1"))
+	mock.ExpectQuery("SELECT `id` FROM `plan_versions` WHERE degree_program_id = \\? AND status = \\?").
+		WithArgs("p1", models.PlanVersionPublished).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("v1"))
+
+	// Plan base vacío: sin problemas.
+	mock.ExpectQuery("SELECT \\* FROM `subjects` WHERE degree_program_id = \\? AND plan_version_id IS NULL").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `elective_pools`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `elective_rules`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT `elective_pool_subjects`").WillReturnRows(sqlmock.NewRows([]string{"elective_pool_id", "subject_id"}))
+
+	// La versión publicada tiene un ciclo de correlativas.
+	mock.ExpectQuery("SELECT \\* FROM `subjects` WHERE degree_program_id = \\? AND plan_version_id = \\?").
+		WithArgs("p1", "v1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "degree_program_id"}).AddRow("a", "A", "p1").AddRow("b", "B", "p1"))
+	mock.ExpectQuery("SELECT \\* FROM `subject_requirements`").
+		WillReturnRows(sqlmock.NewRows([]string{"subject_id", "requirement_id", "min_status"}).
+			AddRow("a", "b", models.ReqPassed).AddRow("b", "a", models.ReqPassed))
+	mock.ExpectQuery("SELECT \\* FROM `requirement_groups`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `subject_corequisites`").WillReturnRows(sqlmock.NewRows([]string{"subject_id", "corequisite_id"}))
+	mock.ExpectQuery("SELECT \\* FROM `elective_pools`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `elective_rules`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT `elective_pool_subjects`").WillReturnRows(sqlmock.NewRows([]string{"elective_pool_id", "subject_id"}))
+
+	w := performRequest(t, http.MethodPost, "/degree-programs/:id/approve", "/degree-programs/p1/approve?strict=true", nil, ApproveProgram)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusUnprocessableEntity, w.Body.String())
+	}
+	var body struct {
+		Reports []services.ProgramValidationReport `json:"reports"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(body.Reports) != 1 || body.Reports[0].PlanVersionID == nil || *body.Reports[0].PlanVersionID != "v1" {
+		t.Fatalf("reports = %+v, want only the v1 report", body.Reports)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearch_HidesProgramsTheUserCannotSee(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT `id`,`name`,`university_id`,`approval_status`,`public_requested` FROM `degree_programs`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "university_id", "approval_status", "public_requested"}).
+			AddRow("public", "Ingeniería en Sistemas", "u1", models.DegreeProgramApproved, true).
+			AddRow("draft", "Ingeniería Química", "u1", models.DegreeProgramPending, false).
+			AddRow("mine", "Ingeniería Civil", "u1", models.DegreeProgramPending, true))
+	mock.ExpectQuery("SELECT `degree_program_id` FROM `user_degree_programs`").
+		WithArgs("student-1", "draft", "mine").
+		WillReturnRows(sqlmock.NewRows([]string{"degree_program_id"}).AddRow("mine"))
+	mock.ExpectQuery("SELECT `id`,`name`,`location` FROM `universities`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "location"}))
+
+	student := models.User{ID: "student-1", Role: "user"}
+	w := performRequestAs(t, student, http.MethodGet, "/search", "/search?q=ingenieria&type=program", nil, Search)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var body struct {
+		Data []services.SearchHit `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	ids := make(map[string]bool, len(body.Data))
+	for _, hit := range body.Data {
+		ids[hit.ID] = true
+	}
+	if len(ids) != 2 || !ids["public"] || !ids["mine"] {
+		t.Fatalf("hits = %+v, want the public program and the one the user is enrolled in", body.Data)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectStrictProgramViolation prepara las consultas de SaveUserSubjects para el programa p1 en
+// modo strict, donde "b" pide "a" aprobada y el usuario no tiene avance cargado.
+func expectStrictProgramViolation(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT \\* FROM `user_plan_versions`").WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery("SELECT \\* FROM `subjects`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "degree_program_id"}).AddRow("a", "A", "p1").AddRow("b", "B", "p1"))
+	mock.ExpectQuery("SELECT \\* FROM `subject_requirements`").
+		WillReturnRows(sqlmock.NewRows([]string{"subject_id", "requirement_id", "min_status"}).AddRow("b", "a", models.ReqPassed))
+	mock.ExpectQuery("SELECT \\* FROM `subject_recognitions`").WillReturnRows(sqlmock.NewRows([]string{"subject_id"}))
+	mock.ExpectQuery("SELECT \\* FROM `user_subjects`").WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery("SELECT `id`,`requirement_validation` FROM `degree_programs`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "requirement_validation"}).AddRow("p1", models.ValidationStrict))
+	mock.ExpectQuery("SELECT \\* FROM `requirement_groups`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `subject_corequisites`").WillReturnRows(sqlmock.NewRows([]string{"subject_id"}))
+}
+
+func TestSaveMySubjectsFromProgram_StrictProgramIgnoresValidationOff(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow("student-1", "user"))
+	mock.ExpectQuery("SELECT \\* FROM `user_degree_programs`").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "degree_program_id"}).AddRow("student-1", "p1"))
+	mock.ExpectQuery("SELECT \\* FROM `degree_programs`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("p1"))
+	expectStrictProgramViolation(mock)
+
+	student := models.User{ID: "student-1", Role: "user"}
+	body := []byte(`{"subjects":[{"id":"b","status":"passed"}]}`)
+	w := performRequestAs(t, student, http.MethodPost, "/me/programs/:programId/subjects", "/me/programs/p1/subjects?validation=off", body, SaveMySubjectsFromProgram)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusUnprocessableEntity, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPreviewMyMigration_PrivateTargetProgram_Returns404(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `user_degree_programs`").WithArgs("student-1", "p1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT \\* FROM `degree_programs`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_status", "public_requested"}).AddRow("p2", models.DegreeProgramPending, false))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `user_degree_programs`").WithArgs("student-1", "p2").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	student := models.User{ID: "student-1", Role: "user"}
+	w := performRequestAs(t, student, http.MethodGet, "/me/programs/:id/migration", "/me/programs/p1/migration?to_program_id=p2", nil, PreviewMyMigration)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetSubjectRequirements_DraftVersionAnonymous_Returns404(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `subjects` WHERE id = \\?").WithArgs("s1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "degree_program_id", "plan_version_id"}).AddRow("s1", "p1", "v2"))
+	mock.ExpectQuery("SELECT \\* FROM `degree_programs`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_status", "public_requested"}).AddRow("p1", models.DegreeProgramApproved, true))
+	mock.ExpectQuery("SELECT \\* FROM `plan_versions`").WithArgs("v2", "p1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "degree_program_id", "status"}).AddRow("v2", "p1", models.PlanVersionDraft))
+	mock.ExpectQuery("SELECT `id`,`approval_status`,`public_requested` FROM `degree_programs`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_status", "public_requested"}).AddRow("p1", models.DegreeProgramApproved, true))
+
+	w := performRequest(t, http.MethodGet, "/subjects/:id/requirements", "/subjects/s1/requirements", nil, GetSubjectRequirements)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplyMyTranscript_StrictProgramIgnoresValidationOff(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `user_degree_programs`").WithArgs("student-1", "p1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	expectStrictProgramViolation(mock)
+
+	student := models.User{ID: "student-1", Role: "user"}
+	body := []byte(`{"subjects":[{"id":"b","status":"passed"}],"validationMode":"off"}`)
+	w := performRequestAs(t, student, http.MethodPost, "/me/subjects/:programId/import/apply", "/me/subjects/p1/import/apply", body, ApplyMyTranscript)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusUnprocessableEntity, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"acadifyapp/internal/db"
 	"acadifyapp/internal/models"
 	"acadifyapp/internal/services"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -257,3 +261,276 @@ func UnfavoriteProgram(c *gin.Context) {
 
 	c.IndentedJSON(http.StatusOK, gin.H{"ok": true})
 }
+
+func GetMyProgramProgress(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+	if !ensureEnrolled(c, user.ID, programID) {
+		return
+	}
+
+	state, err := services.LoadUserProgramState(user.ID, programID)
+	if err != nil {
+		slog.Error("Error loading user program state", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error loading progress"})
+		return
+	}
+
+	attempts, err := services.LoadProgramExamAttempts(user.ID, programID)
+	if err != nil {
+		slog.Error("Error loading exam attempts", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error loading progress"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"program_id": programID,
+		"progress":   services.ComputeProgress(state.Subjects, state.UserSubjects, attempts),
+	})
+}
+
+func authenticatedUser(c *gin.Context) (models.User, bool) {
+	u, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no autenticado"})
+		return models.User{}, false
+	}
+	user, ok := u.(models.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no autenticado"})
+		return models.User{}, false
+	}
+	return user, true
+}
+
+func ensureEnrolled(c *gin.Context, userID string, programID string) bool {
+	var count int64
+	if err := db.Db.Table("user_degree_programs").
+		Where("user_id = ? AND degree_program_id = ?", userID, programID).
+		Count(&count).Error; err != nil {
+		slog.Error("Error validating enrollment", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error validating enrollment"})
+		return false
+	}
+	if count == 0 {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"ok": false, "error": "You are not registered in this program"})
+		return false
+	}
+	return true
+}
+
+func GetMyProgramElectives(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	year := 0
+	if raw := strings.TrimSpace(c.Query("year")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "year debe ser mayor que 0"})
+			return
+		}
+		year = parsed
+	}
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+	if !ensureEnrolled(c, user.ID, programID) {
+		return
+	}
+
+	state, err := services.LoadUserProgramState(user.ID, programID)
+	if err != nil {
+		slog.Error("Error loading user program state", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error loading electives"})
+		return
+	}
+	electives, err := services.LoadProgramElectives(programID, state.PlanVersionID)
+	if err != nil {
+		slog.Error("Error loading elective rules", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error loading electives"})
+		return
+	}
+
+	statuses := state.StatusBySubject()
+	if year == 0 {
+		year = services.CurrentPlanYear(state.Subjects, statuses)
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"program_id": programID,
+		"plan_year":  year,
+		"rules":      services.EvaluateElectiveRules(electives, state.Subjects, statuses, year),
+	})
+}
+
+func GetMyProgramAudit(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+	if !ensureEnrolled(c, user.ID, programID) {
+		return
+	}
+
+	audit, err := services.AuditUserProgram(user.ID, programID)
+	if err != nil {
+		slog.Error("Error building graduation audit", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error building audit"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, audit)
+}
+
+const defaultPlanMaxSubjects = 5
+
+func GetMyProgramPlan(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	opts, err := parsePlanOptions(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+	if !ensureEnrolled(c, user.ID, programID) {
+		return
+	}
+
+	state, err := services.LoadUserProgramState(user.ID, programID)
+	if err != nil {
+		slog.Error("Error loading user program state", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error building plan"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"program_id": programID,
+		"plan":       services.BuildSemesterPlan(state.Subjects, state.Requirements, state.Groups, state.StatusBySubject(), opts),
+	})
+}
+
+func parsePlanOptions(c *gin.Context) (services.PlanOptions, error) {
+	opts := services.PlanOptions{
+		Unit:          services.PlanLoadSubjects,
+		StartSemester: 1,
+		FinalLag:      1,
+	}
+
+	if raw := strings.TrimSpace(c.Query("unit")); raw != "" {
+		switch unit := services.PlanLoadUnit(strings.ToLower(raw)); unit {
+		case services.PlanLoadSubjects, services.PlanLoadHours, services.PlanLoadCredits:
+			opts.Unit = unit
+		default:
+			return opts, errors.New("unit inválido")
+		}
+	}
+	if raw := strings.TrimSpace(c.Query("max_load")); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || parsed <= 0 {
+			return opts, errors.New("max_load debe ser mayor que 0")
+		}
+		opts.MaxLoad = parsed
+	} else if opts.Unit == services.PlanLoadSubjects {
+		opts.MaxLoad = defaultPlanMaxSubjects
+	} else {
+		return opts, errors.New("max_load es requerido")
+	}
+	if raw := strings.TrimSpace(c.Query("start_semester")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || (parsed != 1 && parsed != 2) {
+			return opts, errors.New("start_semester debe ser 1 o 2")
+		}
+		opts.StartSemester = parsed
+	}
+	if raw := strings.TrimSpace(c.Query("start_year")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return opts, errors.New("start_year inválido")
+		}
+		opts.StartYear = parsed
+	}
+	if raw := strings.TrimSpace(c.Query("final_lag")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			return opts, errors.New("final_lag no puede ser negativo")
+		}
+		opts.FinalLag = parsed
+	}
+	if raw := strings.TrimSpace(c.Query("include_electives")); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return opts, errors.New("include_electives inválido")
+		}
+		opts.IncludeElectives = parsed
+	}
+
+	return opts, nil
+}
+
+func GetMyProgramRegularizations(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	warnDays := services.DefaultRegularizationWarningDays
+	if raw := strings.TrimSpace(c.Query("within_days")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "within_days debe ser un entero no negativo"})
+			return
+		}
+		warnDays = parsed
+	}
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+	if !ensureEnrolled(c, user.ID, programID) {
+		return
+	}
+
+	var program models.DegreeProgram
+	if err := db.Db.Select("id", "regularization_months").Where("id = ?", programID).First(&program).Error; err != nil {
+		slog.Error("Error loading program", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error loading regularizations"})
+		return
+	}
+	state, err := services.LoadUserProgramState(user.ID, programID)
+	if err != nil {
+		slog.Error("Error loading user program state", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error loading regularizations"})
+		return
+	}
+
+	report := services.EvaluateRegularizations(state.Subjects, state.UserSubjects, program.RegularizationMonths, time.Now().UTC(), warnDays)
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"program_id":      programID,
+		"validity_months": report.ValidityMonths,
+		"expiring":        report.Expiring,
+		"expired":         report.Expired,
+	})
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreatePlanVersionDTO struct {
+	Name string `json:"name"`
+	// CopyFromBase copia el plan base; CopyFromVersionID copia otra versión del programa.
+	CopyFromBase      bool    `json:"copy_from_base,omitempty"`
+	CopyFromVersionID *string `json:"copy_from_version_id,omitempty"`
+}
+
+type UpdatePlanVersionDTO struct {
+	Name *string `json:"name,omitempty"`
+}
+
+type SetMyPlanVersionDTO struct {
+	PlanVersionID *string `json:"plan_version_id"`
+}
+
+// requestedPlanVersion lee ?version= y valida que pertenezca al programa. Sin parámetro
+// devuelve nil, que corresponde al plan base. Los borradores sólo los ve quien puede editar el
+// programa; para el resto responde 404 como si no existieran.
+func requestedPlanVersion(c *gin.Context, programID string) (*string, bool) {
+	raw := strings.TrimSpace(c.Query("version"))
+	if raw == "" {
+		return nil, true
+	}
+	versionID, err := validateID(raw, "version")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return nil, false
+	}
+	version, err := services.GetPlanVersion(programID, versionID)
+	if err != nil {
+		respondPlanVersionError(c, err)
+		return nil, false
+	}
+	if version.Status != models.PlanVersionPublished && !canSeeDraftVersions(c, programID) {
+		respondPlanVersionError(c, services.ErrPlanVersionNotFound)
+		return nil, false
+	}
+	return &versionID, true
+}
+
+// canSeeDraftVersions dice si el usuario puede ver los borradores del plan del programa.
+func canSeeDraftVersions(c *gin.Context, programID string) bool {
+	if isAdminOrStaff(c) {
+		return true
+	}
+	var program models.DegreeProgram
+	if err := db.Db.Select("id", "approval_status", "public_requested").Where("id = ?", programID).First(&program).Error; err != nil {
+		return false
+	}
+	return canWriteProgram(c, &program)
+}
+
+// ensurePlanVersionWritable responde 409 si la versión ya fue publicada.
+func ensurePlanVersionWritable(c *gin.Context, versionID *string) bool {
+	if err := services.EnsurePlanVersionWritable(versionID); err != nil {
+		respondPlanVersionError(c, err)
+		return false
+	}
+	return true
+}
+
+func respondPlanVersionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPlanVersionNotFound):
+		c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Plan version not found"})
+	case errors.Is(err, services.ErrPlanVersionPublished):
+		c.IndentedJSON(http.StatusConflict, gin.H{"ok": false, "error": "Plan version is published and read-only"})
+	default:
+		slog.Error("Error handling plan version", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error handling plan version"})
+	}
+}
+
+func GetPlanVersionsByProgram(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	program, ok := loadViewableProgram(c, programID)
+	if !ok {
+		return
+	}
+
+	query := db.Db.Where("degree_program_id = ?", programID)
+	if !canWriteProgram(c, program) {
+		query = query.Where("status = ?", models.PlanVersionPublished)
+	}
+	var versions []models.PlanVersion
+	if err := query.Order("created_at ASC").Find(&versions).Error; err != nil {
+		slog.Error("Error fetching plan versions", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error fetching plan versions"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, versions)
+}
+
+func CreatePlanVersion(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	if !ensureProgramWriteAccess(c, programID) {
+		return
+	}
+
+	var req CreatePlanVersionDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "parámetros inválidos"})
+		return
+	}
+	name, err := validateRequiredString(req.Name, "name", maxNameLen)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	if req.CopyFromBase && req.CopyFromVersionID != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "copy_from_base y copy_from_version_id son excluyentes"})
+		return
+	}
+	var sourceID *string
+	if req.CopyFromVersionID != nil {
+		id, err := validateID(*req.CopyFromVersionID, "copy_from_version_id")
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		sourceID = &id
+	}
+
+	version, err := services.CreatePlanVersion(programID, name, req.CopyFromBase || sourceID != nil, sourceID)
+	if err != nil {
+		respondPlanVersionError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, version)
+}
+
+func UpdatePlanVersion(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	if !ensureProgramWriteAccess(c, programID) {
+		return
+	}
+	versionID, err := validateID(c.Param("versionId"), "version_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	var req UpdatePlanVersionDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "parámetros inválidos"})
+		return
+	}
+	name, err := validateOptionalString(req.Name, "name", maxNameLen)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	version, err := services.GetPlanVersion(programID, versionID)
+	if err != nil {
+		respondPlanVersionError(c, err)
+		return
+	}
+	if version.Status == models.PlanVersionPublished {
+		respondPlanVersionError(c, services.ErrPlanVersionPublished)
+		return
+	}
+	if name != nil {
+		version.Name = *name
+	}
+	if err := db.Db.Save(version).Error; err != nil {
+		slog.Error("Error updating plan version", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error updating plan version"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, version)
+}
+
+func PublishPlanVersion(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	if !ensureProgramWriteAccess(c, programID) {
+		return
+	}
+	versionID, err := validateID(c.Param("versionId"), "version_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	version, err := services.PublishPlanVersion(programID, versionID)
+	if err != nil {
+		respondPlanVersionError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, version)
+}
+
+func DeletePlanVersion(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	if !ensureProgramWriteAccess(c, programID) {
+		return
+	}
+	versionID, err := validateID(c.Param("versionId"), "version_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	if err := services.DeletePlanVersion(programID, versionID); err != nil {
+		respondPlanVersionError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func SetMyPlanVersion(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+	if !ensureEnrolled(c, user.ID, programID) {
+		return
+	}
+
+	var req SetMyPlanVersionDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "parámetros inválidos"})
+		return
+	}
+	if req.PlanVersionID != nil {
+		versionID, err := validateID(*req.PlanVersionID, "plan_version_id")
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		req.PlanVersionID = &versionID
+	}
+
+	if err := services.SetUserPlanVersion(user.ID, programID, req.PlanVersionID); err != nil {
+		respondPlanVersionError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"ok": true, "program_id": programID, "plan_version_id": req.PlanVersionID})
+}
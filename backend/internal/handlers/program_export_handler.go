@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"acadifyapp/internal/services"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportProgram descarga el plan en el mismo formato que acepta POST /degreeProgram/seed.
+func ExportProgram(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	program, ok := loadViewableProgram(c, programID)
+	if !ok {
+		return
+	}
+	versionID, ok := requestedPlanVersion(c, programID)
+	if !ok {
+		return
+	}
+
+	export, err := services.ExportProgram(*program, versionID)
+	if err != nil {
+		slog.Error("Error exporting the program", "programID", programID, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error exporting the program"})
+		return
+	}
+
+	filename := strings.ToLower(services.CodeFromName(program.Name)) + ".json"
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.IndentedJSON(http.StatusOK, export)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// loadViewableProgram busca el programa y responde 404 si no existe o el usuario no puede verlo.
+func loadViewableProgram(c *gin.Context, programID string) (*models.DegreeProgram, bool) {
+	var program models.DegreeProgram
+	if err := db.Db.Where("id = ?", programID).First(&program).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+			return nil, false
+		}
+		slog.Error("Error loading the program", "programID", programID, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error loading the program"})
+		return nil, false
+	}
+	if !canViewProgram(c, &program) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+		return nil, false
+	}
+	return &program, true
+}
+
+func GetProgramGraphAnalysis(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := loadViewableProgram(c, programID); !ok {
+		return
+	}
+	versionID, ok := requestedPlanVersion(c, programID)
+	if !ok {
+		return
+	}
+
+	analysis, err := services.AnalyzeProgramGraph(programID, versionID)
+	if err != nil {
+		if errors.Is(err, services.ErrRequirementCycle) {
+			c.IndentedJSON(http.StatusUnprocessableEntity, gin.H{"error": "Program requirements contain a circular dependency"})
+			return
+		}
+		slog.Error("Error analyzing the program graph", "programID", programID, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error analyzing the program"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, analysis)
+}
+
+// GetProgramGraph exporta las correlativas del programa en json, dot, mermaid o graphml.
+// Con ?overlay=true y sesión iniciada cada materia lleva el estado del usuario.
+func GetProgramGraph(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", "json")))
+	switch format {
+	case "json", "dot", "mermaid", "graphml":
+	default:
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "format must be one of json, dot, mermaid, graphml"})
+		return
+	}
+	overlay := false
+	if raw := strings.TrimSpace(c.Query("overlay")); raw != "" {
+		overlay, err = strconv.ParseBool(raw)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "overlay must be a boolean"})
+			return
+		}
+	}
+
+	program, ok := loadViewableProgram(c, programID)
+	if !ok {
+		return
+	}
+	versionID, ok := requestedPlanVersion(c, programID)
+	if !ok {
+		return
+	}
+
+	subjects, requirements, err := services.LoadProgramSubjects(programID, versionID)
+	if err != nil {
+		slog.Error("Error loading the program subjects", "programID", programID, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error loading the program graph"})
+		return
+	}
+
+	var statuses map[string]models.SubjectStatus
+	if overlay {
+		user, ok := authenticatedUser(c)
+		if !ok {
+			return
+		}
+		userSubjects, err := services.GetAllUserSubjects(user.ID, programID)
+		if err != nil {
+			slog.Error("Error loading user subjects", "programID", programID, slog.Any("error", err))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error loading the program graph"})
+			return
+		}
+		statuses = make(map[string]models.SubjectStatus, len(userSubjects))
+		for _, us := range userSubjects {
+			statuses[us.SubjectID] = us.Status
+		}
+	}
+
+	graph := services.BuildProgramGraph(*program, subjects, requirements, statuses)
+	switch format {
+	case "dot":
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", []byte(services.RenderDOT(graph)))
+	case "mermaid":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(services.RenderMermaid(graph)))
+	case "graphml":
+		out, err := services.RenderGraphML(graph)
+		if err != nil {
+			slog.Error("Error rendering GraphML", "programID", programID, slog.Any("error", err))
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error rendering the program graph"})
+			return
+		}
+		c.Data(http.StatusOK, "application/graphml+xml; charset=utf-8", out)
+	default:
+		c.IndentedJSON(http.StatusOK, graph)
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"acadifyapp/internal/services"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateProgram devuelve el reporte de problemas del plan (?version= para una versión).
+func ValidateProgram(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := loadViewableProgram(c, programID); !ok {
+		return
+	}
+	versionID, ok := requestedPlanVersion(c, programID)
+	if !ok {
+		return
+	}
+
+	report, err := services.ValidateProgram(programID, versionID)
+	if err != nil {
+		slog.Error("Error validating the program", "programID", programID, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error validating the program"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, report)
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxShareExpiryDays = 365
+
+type CreateProgressShareRequest struct {
+	Label         *string `json:"label"`
+	ExpiresInDays *int    `json:"expires_in_days"`
+}
+
+// CreateMyProgressShare crea un link de sólo lectura para el programa. El token se devuelve una
+// única vez: después sólo queda su hash.
+func CreateMyProgressShare(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+	if !ensureEnrolled(c, user.ID, programID) {
+		return
+	}
+
+	var req CreateProgressShareRequest
+	// El body es opcional: sin body se crea un link sin nombre ni vencimiento.
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "Invalid payload"})
+		return
+	}
+	label, err := validateOptionalString(req.Label, "label", maxNameLen)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	if label == nil {
+		label = new(string)
+	}
+	now := time.Now().UTC()
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		if *req.ExpiresInDays <= 0 || *req.ExpiresInDays > maxShareExpiryDays {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "expires_in_days must be between 1 and 365"})
+			return
+		}
+		at := now.AddDate(0, 0, *req.ExpiresInDays)
+		expiresAt = &at
+	}
+
+	rawToken, err := generateSecureToken(32)
+	if err != nil {
+		slog.Error("Error generating share token", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error creating the share link"})
+		return
+	}
+	share, err := services.CreateProgressShare(user.ID, programID, hashResetToken(rawToken), *label, expiresAt, now)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyShares) {
+			c.IndentedJSON(http.StatusConflict, gin.H{"ok": false, "error": "Too many share links for this program; revoke one first"})
+			return
+		}
+		slog.Error("Error creating share link", "programID", programID, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error creating the share link"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, gin.H{"ok": true, "share": share, "token": rawToken, "path": "/shared/" + rawToken})
+}
+
+// GetMyProgressShares lista los links del usuario: los de un programa con /me/programs/:id/shares
+// o todos con /me/shares. Los tokens no se pueden recuperar.
+func GetMyProgressShares(c *gin.Context) {
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+	programID := ""
+	if raw := c.Param("id"); raw != "" {
+		var err error
+		if programID, err = validateID(raw, "program_id"); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+	}
+
+	shares, err := services.ListProgressShares(user.ID, programID)
+	if err != nil {
+		slog.Error("Error loading share links", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error loading share links"})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"ok": true, "shares": shares})
+}
+
+func RevokeMyProgressShare(c *gin.Context) {
+	shareID, err := validateID(c.Param("shareId"), "share_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := services.RevokeProgressShare(user.ID, shareID); err != nil {
+		if errors.Is(err, services.ErrShareNotFound) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Share link not found"})
+			return
+		}
+		slog.Error("Error revoking share link", "shareID", shareID, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error revoking the share link"})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// GetSharedProgress muestra, sin sesión, el avance compartido con el mismo formato que
+// GET /me/subjects/:programId. Tokens mal formados, vencidos o revocados dan el mismo 404.
+func GetSharedProgress(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex")
+
+	rawToken := strings.TrimSpace(c.Param("token"))
+	if _, err := hex.DecodeString(rawToken); err != nil || len(rawToken) != 64 {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+	share, err := services.FindProgressShare(hashResetToken(rawToken), time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, services.ErrShareNotFound) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+			return
+		}
+		slog.Error("Error loading share link", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error loading subjects"})
+		return
+	}
+
+	var program models.DegreeProgram
+	if err := db.Db.Preload("University").Where("id = ?", share.DegreeProgramID).First(&program).Error; err != nil {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+	state, err := services.LoadUserProgramState(share.UserID, share.DegreeProgramID)
+	if err != nil {
+		slog.Error("Error loading shared subjects", "shareID", share.ID, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error loading subjects"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, subjectsWithUserStatus(program, state))
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CreateRecognitionDTO struct {
+	SubjectID           string `json:"subject_id"`
+	RecognizedSubjectID string `json:"recognized_subject_id"`
+}
+
+func respondRecognitionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRecognitionNotFound):
+		c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Recognition not found"})
+	case errors.Is(err, services.ErrRecognitionExists):
+		c.IndentedJSON(http.StatusConflict, gin.H{"ok": false, "error": "Alguna de las materias ya está reconocida en ese programa"})
+	case errors.Is(err, services.ErrInvalidRecognition):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "Las materias tienen que existir y ser de programas distintos"})
+	default:
+		slog.Error("Error handling recognitions", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error handling recognitions"})
+	}
+}
+
+func GetSubjectRecognitions(c *gin.Context) {
+	subjectID, err := validateID(c.Param("subjectId"), "subject_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	var subject models.Subject
+	if err := db.Db.Select("id", "degree_program_id").Where("id = ?", subjectID).First(&subject).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Subject not found"})
+			return
+		}
+		respondRecognitionError(c, err)
+		return
+	}
+	if _, ok := loadViewableProgram(c, subject.DegreeProgramID); !ok {
+		return
+	}
+
+	recognitions, err := services.ListRecognitions(subjectID)
+	if err != nil {
+		respondRecognitionError(c, err)
+		return
+	}
+	recognitions, err = viewableRecognitions(c, recognitions)
+	if err != nil {
+		respondRecognitionError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, recognitions)
+}
+
+// viewableRecognitions descarta las materias reconocidas de programas que el usuario no puede ver.
+func viewableRecognitions(c *gin.Context, recognitions []services.RecognizedSubject) ([]services.RecognizedSubject, error) {
+	if len(recognitions) == 0 {
+		return recognitions, nil
+	}
+	programIDs := make([]string, 0, len(recognitions))
+	for _, r := range recognitions {
+		programIDs = append(programIDs, r.DegreeProgramID)
+	}
+	var programs []models.DegreeProgram
+	if err := db.Db.Select("id", "approval_status", "public_requested").Where("id IN ?", programIDs).Find(&programs).Error; err != nil {
+		return nil, err
+	}
+	visible := make(map[string]bool, len(programs))
+	for _, p := range viewablePrograms(c, programs) {
+		visible[p.ID] = true
+	}
+	out := make([]services.RecognizedSubject, 0, len(recognitions))
+	for _, r := range recognitions {
+		if visible[r.DegreeProgramID] {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func CreateRecognition(c *gin.Context) {
+	var req CreateRecognitionDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "parámetros inválidos"})
+		return
+	}
+	subjectID, err := validateID(req.SubjectID, "subject_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	recognizedSubjectID, err := validateID(req.RecognizedSubjectID, "recognized_subject_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	link, err := services.CreateRecognition(subjectID, recognizedSubjectID)
+	if err != nil {
+		respondRecognitionError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, link)
+}
+
+func DeleteRecognition(c *gin.Context) {
+	subjectID, err := validateID(c.Param("subjectId"), "subject_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	recognizedSubjectID, err := validateID(c.Param("recognizedSubjectId"), "recognized_subject_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	if err := services.DeleteRecognition(subjectID, recognizedSubjectID); err != nil {
+		respondRecognitionError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxRequirementGroupNodes = 50
+
+type RequirementGroupDTO struct {
+	Kind         models.RequirementGroupKind `json:"kind"`
+	MinStatus    models.RequirementMinStatus `json:"min_status,omitempty"`
+	MinCount     *int                        `json:"min_count,omitempty"`
+	MinimumValue *float64                    `json:"minimum_value,omitempty"`
+	Year         *int                        `json:"year,omitempty"`
+	SubjectIDs   []string                    `json:"subject_ids,omitempty"`
+	Children     []RequirementGroupDTO       `json:"children,omitempty"`
+}
+
+// toModel convierte el payload en un árbol de grupos; nodes cuenta los nodos para acotar el tamaño.
+func (dto RequirementGroupDTO) toModel(nodes *int) (models.RequirementGroup, error) {
+	*nodes++
+	if *nodes > maxRequirementGroupNodes {
+		return models.RequirementGroup{}, fmt.Errorf("a requirement group can have at most %d nodes", maxRequirementGroupNodes)
+	}
+	group := models.RequirementGroup{
+		Kind:         dto.Kind,
+		MinStatus:    dto.MinStatus,
+		MinCount:     dto.MinCount,
+		MinimumValue: dto.MinimumValue,
+		Year:         dto.Year,
+	}
+	for _, raw := range dto.SubjectIDs {
+		id, err := validateID(raw, "subject_ids")
+		if err != nil {
+			return models.RequirementGroup{}, err
+		}
+		group.Subjects = append(group.Subjects, models.RequirementGroupSubject{SubjectID: id})
+	}
+	for _, child := range dto.Children {
+		childGroup, err := child.toModel(nodes)
+		if err != nil {
+			return models.RequirementGroup{}, err
+		}
+		group.Children = append(group.Children, childGroup)
+	}
+	return group, nil
+}
+
+func respondRequirementGroupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRequirementGroupNotFound):
+		c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Requirement group not found"})
+	case errors.Is(err, services.ErrInvalidRequirementGroup):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+	case errors.Is(err, services.ErrRequirementCycle):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "El grupo genera una dependencia circular"})
+	case errors.Is(err, services.ErrCorequisiteConflict):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "A corequisite cannot also be a prerequisite of the subject"})
+	default:
+		slog.Error("Error handling requirement groups", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error handling requirement groups"})
+	}
+}
+
+// loadWritableSubject busca la materia y verifica que el usuario pueda editar su plan.
+func loadWritableSubject(c *gin.Context) (*models.Subject, bool) {
+	subjectID, err := validateID(c.Param("id"), "subject_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return nil, false
+	}
+	var subject models.Subject
+	if err := db.Db.Where("id = ?", subjectID).First(&subject).Error; err != nil {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Subject not found"})
+		return nil, false
+	}
+	if !ensureProgramWriteAccess(c, subject.DegreeProgramID) {
+		return nil, false
+	}
+	if !ensurePlanVersionWritable(c, subject.PlanVersionID) {
+		return nil, false
+	}
+	return &subject, true
+}
+
+func bindRequirementGroup(c *gin.Context) (*models.RequirementGroup, bool) {
+	var req RequirementGroupDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "parámetros inválidos"})
+		return nil, false
+	}
+	nodes := 0
+	group, err := req.toModel(&nodes)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return nil, false
+	}
+	return &group, true
+}
+
+func GetRequirementGroupsByProgram(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	if _, ok := loadViewableProgram(c, programID); !ok {
+		return
+	}
+	versionID, ok := requestedPlanVersion(c, programID)
+	if !ok {
+		return
+	}
+
+	subjects, _, err := services.LoadProgramSubjects(programID, versionID)
+	if err != nil {
+		respondRequirementGroupError(c, err)
+		return
+	}
+	subjectIDs := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		subjectIDs = append(subjectIDs, s.ID)
+	}
+	groups, err := services.LoadRequirementGroups(subjectIDs)
+	if err != nil {
+		respondRequirementGroupError(c, err)
+		return
+	}
+	if groups == nil {
+		groups = []models.RequirementGroup{}
+	}
+
+	c.IndentedJSON(http.StatusOK, groups)
+}
+
+func CreateRequirementGroup(c *gin.Context) {
+	subject, ok := loadWritableSubject(c)
+	if !ok {
+		return
+	}
+	group, ok := bindRequirementGroup(c)
+	if !ok {
+		return
+	}
+
+	if err := services.SaveRequirementGroup(*subject, group, ""); err != nil {
+		respondRequirementGroupError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, group)
+}
+
+// UpdateRequirementGroup reemplaza el árbol completo del grupo.
+func UpdateRequirementGroup(c *gin.Context) {
+	subject, ok := loadWritableSubject(c)
+	if !ok {
+		return
+	}
+	groupID, err := validateID(c.Param("groupId"), "group_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	if _, err := services.GetRequirementGroup(subject.ID, groupID); err != nil {
+		respondRequirementGroupError(c, err)
+		return
+	}
+	group, ok := bindRequirementGroup(c)
+	if !ok {
+		return
+	}
+
+	if err := services.SaveRequirementGroup(*subject, group, groupID); err != nil {
+		respondRequirementGroupError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, group)
+}
+
+func DeleteRequirementGroup(c *gin.Context) {
+	subject, ok := loadWritableSubject(c)
+	if !ok {
+		return
+	}
+	groupID, err := validateID(c.Param("groupId"), "group_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	if err := services.DeleteRequirementGroup(subject.ID, groupID); err != nil {
+		respondRequirementGroupError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"ok": true})
+}
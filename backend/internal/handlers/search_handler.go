@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"acadifyapp/internal/services"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	minSearchQueryLen = 2
+	maxSearchQueryLen = 100
+)
+
+// Search busca universidades (nombre, ubicación y tags), programas y materias sin distinguir
+// acentos ni mayúsculas. Sólo aparecen programas, y materias de programas, que el usuario puede
+// ver. Con ?type=university,program,subject se limita a esos tipos.
+func Search(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if n := utf8.RuneCountInString(q); n < minSearchQueryLen || n > maxSearchQueryLen {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "q must be between 2 and 100 characters"})
+		return
+	}
+	types := make(map[services.SearchHitType]bool)
+	if raw := strings.TrimSpace(c.Query("type")); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			switch hitType := services.SearchHitType(strings.TrimSpace(t)); hitType {
+			case services.SearchUniversity, services.SearchProgram, services.SearchSubject:
+				types[hitType] = true
+			default:
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "type must be university, program or subject"})
+				return
+			}
+		}
+	}
+	page, limit, offset := parsePagination(c)
+
+	programs, err := services.LoadSearchPrograms()
+	if err != nil {
+		slog.Error("Error loading programs for search", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error searching"})
+		return
+	}
+	withSubjects := len(types) == 0 || types[services.SearchSubject]
+	index, err := services.LoadSearchIndex(viewablePrograms(c, programs), q, withSubjects)
+	if err != nil {
+		slog.Error("Error loading search index", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error searching"})
+		return
+	}
+
+	hits := services.Search(index, q, types)
+	total := len(hits)
+	start, end := min(offset, total), min(offset+limit, total)
+	c.JSON(http.StatusOK, gin.H{
+		"count": total,
+		"page":  page,
+		"limit": limit,
+		"data":  hits[start:end],
+	})
+}
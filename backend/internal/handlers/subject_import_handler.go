@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxImportFileSize = 5 << 20
+
+// ImportSubjects carga materias desde una planilla CSV o XLSX (campo "file") en el programa.
+// Las filas se emparejan por código con las materias existentes y nunca se borra ninguna.
+// Con ?dry_run=true sólo valida y devuelve el diff; con errores no se guarda nada.
+func ImportSubjects(c *gin.Context) {
+	programID, err := validateID(c.Param("id"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	if !ensureProgramWriteAccess(c, programID) {
+		return
+	}
+	var program models.DegreeProgram
+	if err := db.Db.Where("id = ?", programID).First(&program).Error; err != nil {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Program not found"})
+		return
+	}
+	versionID, ok := requestedPlanVersion(c, programID)
+	if !ok {
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+	if !dryRun && !ensurePlanVersionWritable(c, versionID) {
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "file is required"})
+		return
+	}
+	if header.Size > maxImportFileSize {
+		c.IndentedJSON(http.StatusRequestEntityTooLarge, gin.H{"ok": false, "error": "file is too large"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "could not read the file"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize+1))
+	if err != nil || len(data) > maxImportFileSize {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "could not read the file"})
+		return
+	}
+
+	var rows []services.ImportRow
+	var rowErrors []services.ImportRowError
+	switch strings.ToLower(filepath.Ext(header.Filename)) {
+	case ".csv", ".txt":
+		rows, rowErrors, err = services.ParseSubjectsCSV(bytes.NewReader(data))
+	case ".xlsx":
+		rows, rowErrors, err = services.ParseSubjectsXLSX(bytes.NewReader(data), int64(len(data)))
+	default:
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "file must be .csv or .xlsx"})
+		return
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidImportFile) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		slog.Error("Error reading the import file", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error reading the file"})
+		return
+	}
+	if len(rows)+len(rowErrors) > maxSubjectsPayload {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "too many rows"})
+		return
+	}
+	if len(rowErrors) > 0 {
+		c.IndentedJSON(http.StatusUnprocessableEntity, gin.H{"ok": false, "rows": len(rows) + len(rowErrors), "errors": rowErrors})
+		return
+	}
+
+	result, err := services.ImportSubjects(program, versionID, rows, dryRun)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidImportFile) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		slog.Error("Error importing subjects", "programID", programID, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error importing subjects"})
+		return
+	}
+	if len(result.Errors) > 0 {
+		c.IndentedJSON(http.StatusUnprocessableEntity, gin.H{"ok": false, "rows": result.Rows, "errors": result.Errors})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"ok": true, "dry_run": dryRun, "rows": result.Rows, "diff": result.Diff})
+}
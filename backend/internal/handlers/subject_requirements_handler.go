@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type subjectRequirementDTO struct {
+	RequirementID string                      `json:"requirement_id"`
+	MinStatus     models.RequirementMinStatus `json:"min_status"`
+}
+
+type subjectRequirementResponse struct {
+	ID        string                      `json:"id"`
+	Name      string                      `json:"name"`
+	MinStatus models.RequirementMinStatus `json:"minStatus"`
+}
+
+func respondSubjectRequirementError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRequirementNotFound):
+		c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Requirement not found"})
+	case errors.Is(err, services.ErrDuplicateRequirement):
+		c.IndentedJSON(http.StatusConflict, gin.H{"ok": false, "error": "The subject already has this requirement"})
+	case errors.Is(err, services.ErrInvalidRequirement), errors.Is(err, services.ErrRequirementCycle):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+	case errors.Is(err, services.ErrCorequisiteConflict):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "A corequisite cannot also be a prerequisite of the subject"})
+	default:
+		slog.Error("Error handling subject requirements", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error handling subject requirements"})
+	}
+}
+
+// GetSubjectRequirements lista las correlativas de la materia. Las materias de versiones del plan
+// sin publicar sólo las ven quienes pueden ver los borradores, igual que en requestedPlanVersion.
+func GetSubjectRequirements(c *gin.Context) {
+	subjectID, err := validateID(c.Param("id"), "subject_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	var subject models.Subject
+	if err := db.Db.Where("id = ?", subjectID).First(&subject).Error; err != nil {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Subject not found"})
+		return
+	}
+	if _, ok := loadViewableProgram(c, subject.DegreeProgramID); !ok {
+		return
+	}
+	if subject.PlanVersionID != nil {
+		version, err := services.GetPlanVersion(subject.DegreeProgramID, *subject.PlanVersionID)
+		if err != nil {
+			respondPlanVersionError(c, err)
+			return
+		}
+		if version.Status != models.PlanVersionPublished && !canSeeDraftVersions(c, subject.DegreeProgramID) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"ok": false, "error": "Subject not found"})
+			return
+		}
+	}
+
+	rows, err := services.ListSubjectRequirements(subject.ID)
+	if err != nil {
+		respondSubjectRequirementError(c, err)
+		return
+	}
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.RequirementID)
+	}
+	names := make(map[string]string, len(ids))
+	if len(ids) > 0 {
+		var required []models.Subject
+		if err := db.Db.Select("id", "name").Where("id IN ?", ids).Find(&required).Error; err != nil {
+			respondSubjectRequirementError(c, err)
+			return
+		}
+		for _, s := range required {
+			names[s.ID] = s.Name
+		}
+	}
+
+	response := make([]subjectRequirementResponse, 0, len(rows))
+	for _, row := range rows {
+		response = append(response, subjectRequirementResponse{ID: row.RequirementID, Name: names[row.RequirementID], MinStatus: row.MinStatus})
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func AddSubjectRequirement(c *gin.Context) {
+	subject, ok := loadWritableSubject(c)
+	if !ok {
+		return
+	}
+	var req subjectRequirementDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "parámetros inválidos"})
+		return
+	}
+	requirementID, err := validateID(req.RequirementID, "requirement_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	if req.MinStatus == "" {
+		req.MinStatus = models.ReqPassed
+	}
+
+	row, err := services.AddSubjectRequirement(*subject, requirementID, req.MinStatus)
+	if err != nil {
+		respondSubjectRequirementError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, row)
+}
+
+// UpdateSubjectRequirement cambia el estado mínimo exigido para la correlativa.
+func UpdateSubjectRequirement(c *gin.Context) {
+	subject, ok := loadWritableSubject(c)
+	if !ok {
+		return
+	}
+	requirementID, err := validateID(c.Param("requirementId"), "requirement_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	var req struct {
+		MinStatus models.RequirementMinStatus `json:"min_status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "parámetros inválidos"})
+		return
+	}
+
+	row, err := services.UpdateSubjectRequirement(*subject, requirementID, req.MinStatus)
+	if err != nil {
+		respondSubjectRequirementError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, row)
+}
+
+func DeleteSubjectRequirement(c *gin.Context) {
+	subject, ok := loadWritableSubject(c)
+	if !ok {
+		return
+	}
+	requirementID, err := validateID(c.Param("requirementId"), "requirement_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	if err := services.DeleteSubjectRequirement(subject.ID, requirementID); err != nil {
+		respondSubjectRequirementError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"acadifyapp/internal/db"
 	"acadifyapp/internal/models"
+	"acadifyapp/internal/services"
 	"errors"
 	"log/slog"
 	"net/http"
@@ -26,7 +27,9 @@ type createSubjectDTO struct {
 	Term            *string            `json:"term,omitempty"`
 	DegreeProgramID string             `json:"degreeProgramID" binding:"required"`
 	IsElective      *bool              `json:"is_elective,omitempty"`
+	PlanVersionID   *string            `json:"planVersionID,omitempty"`
 	Requirements    []requirementInput `json:"requirements"`
+	Corequisites    []string           `json:"corequisites,omitempty"`
 }
 
 type updateSubjectDTO struct {
@@ -36,6 +39,7 @@ type updateSubjectDTO struct {
 	Term            *string             `json:"term,omitempty"`
 	DegreeProgramID *string             `json:"degreeProgramID,omitempty"`
 	Requirements    *[]requirementInput `json:"requirements,omitempty"`
+	Corequisites    *[]string           `json:"corequisites,omitempty"`
 }
 
 func normalizeSubjectTerm(term string) (string, bool) {
@@ -48,16 +52,84 @@ func normalizeSubjectTerm(term string) (string, bool) {
 	}
 }
 
+// inSamePlanVersion indica si todas las correlativas son de la misma versión del plan que la materia.
+func inSamePlanVersion(subject models.Subject, requirements []models.Subject) bool {
+	for _, r := range requirements {
+		if (r.PlanVersionID == nil) != (subject.PlanVersionID == nil) {
+			return false
+		}
+		if r.PlanVersionID != nil && *r.PlanVersionID != *subject.PlanVersionID {
+			return false
+		}
+	}
+	return true
+}
+
 var (
 	errInvalidSubjectTerm    = errors.New("invalid subject term")
 	errEmptySubjectProgramID = errors.New("degree program id cannot be empty")
+	errInvalidCorequisite    = errors.New("invalid corequisite")
 )
 
+// replaceCorequisites reemplaza los co-requisitos de la materia. Tienen que ser otras materias
+// del mismo plan y no pueden ser a la vez correlativas (directas o indirectas).
+func replaceCorequisites(tx *gorm.DB, subject models.Subject, ids []string) error {
+	if err := tx.Where("subject_id = ?", subject.ID).Delete(&models.SubjectCorequisite{}).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, dup := seen[id]; dup || id == subject.ID {
+			return errInvalidCorequisite
+		}
+		seen[id] = struct{}{}
+	}
+	var coSubjects []models.Subject
+	if err := tx.Where("id IN ? AND degree_program_id = ?", ids, subject.DegreeProgramID).Find(&coSubjects).Error; err != nil {
+		return err
+	}
+	if len(coSubjects) != len(ids) || !inSamePlanVersion(subject, coSubjects) {
+		return errInvalidCorequisite
+	}
+
+	rows := make([]models.SubjectCorequisite, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, models.SubjectCorequisite{SubjectID: subject.ID, CorequisiteID: id})
+	}
+	if err := tx.Omit("Subject", "Corequisite").Create(&rows).Error; err != nil {
+		return err
+	}
+	return services.CheckCorequisiteConflicts(tx, subject.DegreeProgramID, subject.PlanVersionID)
+}
+
+// respondCorequisiteError responde los errores de co-requisitos; devuelve false si err es de otro tipo.
+func respondCorequisiteError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, errInvalidCorequisite):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Some corequisite IDs are invalid"})
+	case errors.Is(err, services.ErrCorequisiteConflict):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "A corequisite cannot also be a prerequisite of the subject"})
+	default:
+		return false
+	}
+	return true
+}
+
+// GetAllSubjectsFromProgram lista las materias del programa. La ruta comparte el árbol GET con
+// /subjects/:id/requirements, por eso el parámetro se llama id aunque sea el del programa.
 func GetAllSubjectsFromProgram(c *gin.Context) {
-	programID := c.Param("programId")
+	programID := c.Param("id")
+	versionID, ok := requestedPlanVersion(c, programID)
+	if !ok {
+		return
+	}
 
 	var subjects []models.Subject
-	result := db.Db.Preload("Requirements").Where("degree_program_id = ?", programID).Find(&subjects) // :contentReference[oaicite:2]{index=2}
+	result := db.Db.Preload("Requirements").Where("degree_program_id = ?", programID).Scopes(services.ScopePlanVersion("plan_version_id", versionID)).Find(&subjects) // :contentReference[oaicite:2]{index=2}
 	if result.Error != nil {
 		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Program not found"})
 		return
@@ -76,7 +148,9 @@ func GetAllSubjectsFromProgram(c *gin.Context) {
 		SubjectYear     *int                    `json:"subjectYear,omitempty"`
 		Term            string                  `json:"term"`
 		DegreeProgramID string                  `json:"degreeProgramID"`
+		PlanVersionID   *string                 `json:"planVersionID,omitempty"`
 		Requirements    []requirementWithStatus `json:"requirements"`
+		Corequisites    []string                `json:"corequisites"`
 		CreatedAt       time.Time               `json:"created_at"`
 		UpdatedAt       time.Time               `json:"updated_at"`
 	}
@@ -100,6 +174,15 @@ func GetAllSubjectsFromProgram(c *gin.Context) {
 			reqStatusBySubject[row.SubjectID][row.RequirementID] = row.MinStatus
 		}
 	}
+	corequisitesBySubject := make(map[string][]string)
+	corequisites, err := services.LoadCorequisites(db.Db, subjectIDs)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error loading subject corequisites"})
+		return
+	}
+	for _, co := range corequisites {
+		corequisitesBySubject[co.SubjectID] = append(corequisitesBySubject[co.SubjectID], co.CorequisiteID)
+	}
 
 	response := make([]subjectWithRequirements, 0, len(subjects))
 	for _, subject := range subjects {
@@ -115,6 +198,10 @@ func GetAllSubjectsFromProgram(c *gin.Context) {
 				MinStatus: string(status),
 			})
 		}
+		subjectCorequisites := corequisitesBySubject[subject.ID]
+		if subjectCorequisites == nil {
+			subjectCorequisites = []string{}
+		}
 		response = append(response, subjectWithRequirements{
 			ID:              subject.ID,
 			Name:            subject.Name,
@@ -122,7 +209,9 @@ func GetAllSubjectsFromProgram(c *gin.Context) {
 			SubjectYear:     subject.Year,
 			Term:            subject.Term,
 			DegreeProgramID: subject.DegreeProgramID,
+			PlanVersionID:   subject.PlanVersionID,
 			Requirements:    requirements,
+			Corequisites:    subjectCorequisites,
 			CreatedAt:       subject.CreatedAt,
 			UpdatedAt:       subject.UpdatedAt,
 		})
@@ -147,6 +236,15 @@ func CreateSubject(c *gin.Context) {
 	if !ensureProgramWriteAccess(c, dto.DegreeProgramID) {
 		return
 	}
+	if dto.PlanVersionID != nil {
+		if _, err := services.GetPlanVersion(dto.DegreeProgramID, *dto.PlanVersionID); err != nil {
+			respondPlanVersionError(c, err)
+			return
+		}
+		if !ensurePlanVersionWritable(c, dto.PlanVersionID) {
+			return
+		}
+	}
 
 	year := dto.Year
 	if year == nil {
@@ -169,6 +267,7 @@ func CreateSubject(c *gin.Context) {
 		Term:            term,
 		DegreeProgramID: dto.DegreeProgramID,
 		IsElective:      dto.IsElective != nil && *dto.IsElective,
+		PlanVersionID:   dto.PlanVersionID,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
@@ -193,7 +292,7 @@ func CreateSubject(c *gin.Context) {
 			if err := tx.Where("id IN ?", ids).Find(&reqSubjects).Error; err != nil {
 				return err
 			}
-			if len(reqSubjects) != len(ids) {
+			if len(reqSubjects) != len(ids) || !inSamePlanVersion(subject, reqSubjects) {
 				return gorm.ErrRecordNotFound
 			}
 
@@ -213,13 +312,24 @@ func CreateSubject(c *gin.Context) {
 			if err := tx.Create(&rows).Error; err != nil {
 				return err
 			}
+			if err := services.CheckRequirementCycles(tx, subject.DegreeProgramID, subject.PlanVersionID); err != nil {
+				return err
+			}
 		}
 
-		return nil
+		// 3) Co-requisitos (tabla aparte, admiten relación mutua)
+		return replaceCorequisites(tx, subject, dto.Corequisites)
 	})
 
 	if err != nil {
 		slog.Error("Error creating the subject", slog.Any("error", err))
+		if respondCorequisiteError(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrRequirementCycle) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if err == gorm.ErrRecordNotFound {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Some requirement IDs are invalid"})
 			return
@@ -253,6 +363,9 @@ func UpdateSubject(c *gin.Context) {
 	if !ensureProgramWriteAccess(c, subject.DegreeProgramID) {
 		return
 	}
+	if !ensurePlanVersionWritable(c, subject.PlanVersionID) {
+		return
+	}
 
 	err := db.Db.Transaction(func(tx *gorm.DB) error {
 		updates := map[string]interface{}{}
@@ -284,6 +397,12 @@ func UpdateSubject(c *gin.Context) {
 			}
 		}
 
+		if dto.Corequisites != nil {
+			if err := replaceCorequisites(tx, subject, *dto.Corequisites); err != nil {
+				return err
+			}
+		}
+
 		// requirements update (nuevo modelo)
 		if dto.Requirements != nil {
 			// si mandan [] => limpiar correlativas
@@ -305,7 +424,7 @@ func UpdateSubject(c *gin.Context) {
 			if err := tx.Where("id IN ?", ids).Find(&reqSubjects).Error; err != nil {
 				return err
 			}
-			if len(reqSubjects) != len(ids) {
+			if len(reqSubjects) != len(ids) || !inSamePlanVersion(subject, reqSubjects) {
 				return gorm.ErrRecordNotFound
 			}
 
@@ -329,12 +448,25 @@ func UpdateSubject(c *gin.Context) {
 			if err := tx.Create(&rows).Error; err != nil {
 				return err
 			}
+			if err := services.CheckRequirementCycles(tx, subject.DegreeProgramID, subject.PlanVersionID); err != nil {
+				return err
+			}
+			if err := services.CheckCorequisiteConflicts(tx, subject.DegreeProgramID, subject.PlanVersionID); err != nil {
+				return err
+			}
 		}
 
 		return nil
 	})
 
 	if err != nil {
+		if respondCorequisiteError(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrRequirementCycle) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if err == gorm.ErrRecordNotFound {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Some requirement IDs are invalid"})
 			return
@@ -370,21 +502,12 @@ func DeleteSubject(c *gin.Context) {
 	if !ensureProgramWriteAccess(c, subject.DegreeProgramID) {
 		return
 	}
+	if !ensurePlanVersionWritable(c, subject.PlanVersionID) {
+		return
+	}
 
 	err := db.Db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("subject_id = ? OR requirement_id = ?", id, id).Delete(&models.SubjectRequirement{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("subject_id = ?", id).Delete(&models.ElectivePoolSubject{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("subject_id = ?", id).Delete(&models.UserSubject{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Delete(&subject).Error; err != nil {
-			return err
-		}
-		return nil
+		return services.DeleteSubjectTx(tx, subject)
 	})
 	if err != nil {
 		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "An error has ocurred while deleting the subject"})
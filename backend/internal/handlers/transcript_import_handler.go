@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"acadifyapp/internal/services"
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxTranscriptEntries = 2000
+
+// ImportMyTranscript lee la historia académica del usuario (campo "file" con un .csv o .txt, o
+// el texto pegado en el campo "text") y devuelve la tabla de coincidencias para revisar. No
+// guarda nada: lo confirmado se aplica con ApplyMyTranscript.
+func ImportMyTranscript(c *gin.Context) {
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+	programID, err := validateID(c.Param("programId"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	if !ensureEnrolled(c, user.ID, programID) {
+		return
+	}
+
+	var entries []services.TranscriptEntry
+	if header, err := c.FormFile("file"); err == nil {
+		if header.Size > maxImportFileSize {
+			c.IndentedJSON(http.StatusRequestEntityTooLarge, gin.H{"ok": false, "error": "file is too large"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "could not read the file"})
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize+1))
+		if err != nil || len(data) > maxImportFileSize {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "could not read the file"})
+			return
+		}
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".csv":
+			entries, err = services.ParseTranscriptCSV(bytes.NewReader(data))
+		case ".txt":
+			entries, err = services.ParseTranscriptText(bytes.NewReader(data))
+		default:
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "file must be .csv or .txt"})
+			return
+		}
+		if err != nil {
+			respondTranscriptParseError(c, err)
+			return
+		}
+	} else {
+		text := c.PostForm("text")
+		if strings.TrimSpace(text) == "" {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "file or text is required"})
+			return
+		}
+		if len(text) > maxImportFileSize {
+			c.IndentedJSON(http.StatusRequestEntityTooLarge, gin.H{"ok": false, "error": "text is too large"})
+			return
+		}
+		if entries, err = services.ParseTranscriptText(strings.NewReader(text)); err != nil {
+			respondTranscriptParseError(c, err)
+			return
+		}
+	}
+	if len(entries) == 0 {
+		c.IndentedJSON(http.StatusUnprocessableEntity, gin.H{"ok": false, "error": "no subjects found in the transcript"})
+		return
+	}
+	if len(entries) > maxTranscriptEntries {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": "too many rows"})
+		return
+	}
+
+	table, err := services.MatchUserTranscript(user.ID, programID, entries)
+	if err != nil {
+		slog.Error("Error matching transcript", "programID", programID, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error reading the transcript"})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"ok": true, "matches": table.Matches, "proposals": table.Proposals})
+}
+
+func respondTranscriptParseError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidImportFile) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	slog.Error("Error reading the transcript", slog.Any("error", err))
+	c.IndentedJSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "Error reading the transcript"})
+}
+
+// ApplyMyTranscript guarda las propuestas confirmadas con el mismo payload y las mismas
+// validaciones que SaveMySubjectsFromProgram, pero sin borrar las materias que no vienen. Las
+// aprobadas que traen exam_date (la fecha de la propuesta) quedan además como intento de examen.
+// El validationMode del body no puede bajar el modo del programa.
+func ApplyMyTranscript(c *gin.Context) {
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+	programID, err := validateID(c.Param("programId"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !ensureEnrolled(c, user.ID, programID) {
+		return
+	}
+
+	var payload SaveUserSubjectsRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	items, ok := subjectProgressFromRequest(c, payload)
+	if !ok {
+		return
+	}
+	mode, ok := requestedValidationMode(c, payload.ValidationMode)
+	if !ok {
+		return
+	}
+	saveUserSubjects(c, user.ID, programID, items, mode, false)
+}
@@ -99,3 +99,47 @@ func GetAllUsers(c *gin.Context) {
 
 	c.IndentedJSON(http.StatusOK, users)
 }
+
+func GetUserProgramAudit(c *gin.Context) {
+	userID, err := validateID(c.Param("id"), "user_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	programID, err := validateID(c.Param("programId"), "program_id")
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := services.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		slog.Error("Error finding user by ID in db", slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error getting the user by ID"})
+		return
+	}
+	enrolled := false
+	for _, dp := range user.DegreePrograms {
+		if dp.ID == programID {
+			enrolled = true
+			break
+		}
+	}
+	if !enrolled {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "User is not enrolled in this program"})
+		return
+	}
+
+	audit, err := services.AuditUserProgram(user.ID, programID)
+	if err != nil {
+		slog.Error("Error building graduation audit", "userId", userID, slog.Any("error", err))
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error building audit"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, audit)
+}
@@ -4,8 +4,8 @@ import (
 	"acadifyapp/internal/db"
 	"acadifyapp/internal/models"
 	"acadifyapp/internal/services"
+	"errors"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm/clause"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -73,45 +73,34 @@ func GetMySubjectsFromProgram(c *gin.Context) {
 		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "Program not found"})
 		return
 	}
-	universityName := program.University.Name
 
-	var subjects []models.Subject
-	if err := db.Db.
-		Where("degree_program_id = ?", programId).
-		Find(&subjects).Error; err != nil {
+	state, err := services.LoadUserProgramState(u.ID, programId)
+	if err != nil {
 		slog.Error("Error loading subjects", slog.Any("error", err))
 		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error loading subjects"})
 		return
 	}
 
-	if len(subjects) == 0 {
-		c.IndentedJSON(http.StatusOK, gin.H{
+	c.IndentedJSON(http.StatusOK, subjectsWithUserStatus(program, state))
+}
+
+// subjectsWithUserStatus arma la respuesta de GET /me/subjects (y de GET /shared): el programa y
+// cada materia con el estado del usuario, sus correlativas y lo que le falta para cursarla o rendirla.
+func subjectsWithUserStatus(program models.DegreeProgram, state *services.UserProgramState) gin.H {
+	universityName := program.University.Name
+	if len(state.Subjects) == 0 {
+		return gin.H{
 			"id":           program.ID,
 			"name":         program.Name,
 			"university":   universityName,
 			"universityID": program.UniversityID,
 			"subjects":     []any{},
-		})
-		return
-	}
-
-	// estados del usuario
-	userSubjects, err := services.GetAllUserSubjects(u.ID, programId)
-	if err != nil {
-		slog.Info("Error getting the user subjects", "err", err)
-	}
-
-	statusBySubject := make(map[string]models.SubjectStatus, len(userSubjects))
-	finalCalificationBySubject := make(map[string]float64, len(userSubjects))
-	for _, us := range userSubjects {
-		statusBySubject[us.SubjectID] = us.Status
-		finalCalificationBySubject[us.SubjectID] = us.FinalCalification
+		}
 	}
 
-	// Traer reglas de requirements (join table)
-	subjectIDs := make([]string, 0, len(subjects))
-	for _, s := range subjects {
-		subjectIDs = append(subjectIDs, s.ID)
+	userSubjectBySubject := make(map[string]models.UserSubject, len(state.UserSubjects))
+	for _, us := range state.UserSubjects {
+		userSubjectBySubject[us.SubjectID] = us
 	}
 
 	type ReqRuleDTO struct {
@@ -119,53 +108,66 @@ func GetMySubjectsFromProgram(c *gin.Context) {
 		MinStatus string `json:"minStatus"`
 	}
 
-	var links []models.SubjectRequirement
-	if err := db.Db.Where("subject_id IN ?", subjectIDs).Find(&links).Error; err != nil {
-		slog.Error("Error loading subject requirements", slog.Any("error", err))
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error loading subject requirements"})
-		return
-	}
-
-	reqRulesBySubject := make(map[string][]ReqRuleDTO, len(subjectIDs))
-	for _, l := range links {
+	reqRulesBySubject := make(map[string][]ReqRuleDTO, len(state.Subjects))
+	for _, l := range state.Requirements {
 		reqRulesBySubject[l.SubjectID] = append(reqRulesBySubject[l.SubjectID], ReqRuleDTO{
 			ID:        l.RequirementID,
 			MinStatus: string(l.MinStatus),
 		})
 	}
 
-	out := make([]any, 0, len(subjects))
-	for _, s := range subjects {
-		st, found := statusBySubject[s.ID]
-		if !found {
-			st = models.StatusAvailable
-		}
-		finalCalification, hasFinalCalification := finalCalificationBySubject[s.ID]
+	eligibility := state.Eligibility()
+	groupsBySubject := services.GroupsBySubject(state.Groups)
+	corequisitesBySubject := make(map[string][]string, len(state.Corequisites))
+	for _, co := range state.Corequisites {
+		corequisitesBySubject[co.SubjectID] = append(corequisitesBySubject[co.SubjectID], co.CorequisiteID)
+	}
+
+	out := make([]any, 0, len(state.Subjects))
+	for _, s := range state.Subjects {
+		e := eligibility[s.ID]
+		us, hasUserSubject := userSubjectBySubject[s.ID]
 
 		subjectJSON := gin.H{
-			"id":              s.ID,
-			"name":            s.Name,
-			"year":            s.Year,
-			"subjectYear":     s.Year,
-			"term":            s.Term,
-			"degreeProgramID": s.DegreeProgramID,
-			"is_elective":     s.IsElective,
-			"status":          st,
-			"requirements":    reqRulesBySubject[s.ID], // [{id, minStatus}]
+			"id":                 s.ID,
+			"name":               s.Name,
+			"year":               s.Year,
+			"subjectYear":        s.Year,
+			"term":               s.Term,
+			"degreeProgramID":    s.DegreeProgramID,
+			"is_elective":        s.IsElective,
+			"status":             e.Status,
+			"requirements":       reqRulesBySubject[s.ID], // [{id, minStatus}]
+			"requirement_groups": groupsBySubject[s.ID],
+			"corequisites":       corequisitesBySubject[s.ID],
+			"can_enroll":         e.CanEnroll,
+			"can_take_final":     e.CanTakeFinal,
+			"unmet_requirements": e.UnmetRequirements,
+			"unmet_for_final":    e.UnmetForFinal,
+			"unmet_groups":       e.UnmetGroups,
+			"unmet_corequisites": e.UnmetCorequisites,
 		}
-		if hasFinalCalification {
-			subjectJSON["final_calification"] = finalCalification
+		if hasUserSubject {
+			subjectJSON["final_calification"] = us.FinalCalification
+			if us.RegularizedAt != nil {
+				subjectJSON["regularized_at"] = us.RegularizedAt
+				subjectJSON["regularization_expired"] = us.RegularizationExpired
+			}
+		}
+		if from, ok := state.Recognized[s.ID]; ok {
+			subjectJSON["by_equivalence"] = true
+			subjectJSON["recognized_from"] = from
 		}
 		out = append(out, subjectJSON)
 	}
 
-	c.IndentedJSON(http.StatusOK, gin.H{
+	return gin.H{
 		"id":           program.ID,
 		"name":         program.Name,
 		"university":   universityName,
 		"universityID": program.UniversityID,
 		"subjects":     out,
-	})
+	}
 }
 
 type SaveUserSubjectsRequest struct {
@@ -173,7 +175,20 @@ type SaveUserSubjectsRequest struct {
 		ID                string               `json:"id"`
 		Status            models.SubjectStatus `json:"status"`
 		FinalCalification *float64             `json:"final_calification,omitempty"`
+		RegularizedAt     *string              `json:"regularized_at,omitempty"`
+		ExamDate          *string              `json:"exam_date,omitempty"`
 	} `json:"subjects"`
+	ValidationMode *string `json:"validationMode,omitempty"`
+}
+
+func parseValidationMode(raw string) (models.RequirementValidationMode, bool) {
+	mode := models.RequirementValidationMode(strings.ToLower(strings.TrimSpace(raw)))
+	switch mode {
+	case models.ValidationOff, models.ValidationWarn, models.ValidationStrict:
+		return mode, true
+	default:
+		return "", false
+	}
 }
 
 func SaveMySubjectsFromProgram(c *gin.Context) {
@@ -213,142 +228,102 @@ func SaveMySubjectsFromProgram(c *gin.Context) {
 		return
 	}
 
-	var subjects []models.Subject
-	if err := db.Db.Where("degree_program_id = ?", programId).Find(&subjects).Error; err != nil {
-		slog.Error("Error loading subjects", slog.Any("error", err))
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error loading subjects"})
-		return
-	}
-
-	subjectIDs := make([]string, 0, len(subjects))
-	subjectIDSet := make(map[string]struct{}, len(subjects))
-	for _, s := range subjects {
-		subjectIDs = append(subjectIDs, s.ID)
-		subjectIDSet[s.ID] = struct{}{}
-	}
-
 	var payload SaveUserSubjectsRequest
 	if err := c.ShouldBindJSON(&payload); err != nil {
 		slog.Error("Invalid subjects payload", slog.Any("error", err))
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
 		return
 	}
-	if len(payload.Subjects) > maxSubjectsPayload {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Demasiadas materias en el payload"})
+	items, ok := subjectProgressFromRequest(c, payload)
+	if !ok {
 		return
 	}
 
-	allowedStatus := map[models.SubjectStatus]struct{}{
-		models.StatusAvailable:      {},
-		models.StatusInProgress:     {},
-		models.StatusFinalPending:   {},
-		models.StatusPassed:         {},
-		models.StatusPassedWithDist: {},
+	// Modo de validación: query > body, pero nunca menos estricto que el del programa
+	mode, ok := requestedValidationMode(c, payload.ValidationMode)
+	if !ok {
+		return
 	}
+	saveUserSubjects(c, u.ID, programId, items, mode, true)
+}
 
-	records := make([]models.UserSubject, 0, len(payload.Subjects))
-	payloadIDs := make([]string, 0, len(payload.Subjects))
-	payloadIDSet := make(map[string]struct{}, len(payload.Subjects))
-	missingCalificationSubjectIDs := make([]string, 0, len(payload.Subjects))
-	recordIndexBySubjectID := make(map[string]int, len(payload.Subjects))
-
+// subjectProgressFromRequest valida los ids y fechas del payload; el resto lo revisa
+// services.SaveUserSubjects.
+func subjectProgressFromRequest(c *gin.Context, payload SaveUserSubjectsRequest) ([]services.SubjectProgress, bool) {
+	if len(payload.Subjects) > maxSubjectsPayload {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Demasiadas materias en el payload"})
+		return nil, false
+	}
+	items := make([]services.SubjectProgress, 0, len(payload.Subjects))
 	for _, item := range payload.Subjects {
 		subjectID, err := validateID(item.ID, "subject_id")
 		if err != nil {
 			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		if _, ok := subjectIDSet[subjectID]; !ok {
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Subject not in program"})
-			return
-		}
-		if _, ok := allowedStatus[item.Status]; !ok {
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid subject status"})
-			return
+			return nil, false
 		}
-		if _, exists := payloadIDSet[subjectID]; exists {
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Duplicate subject in payload"})
-			return
-		}
-		if item.FinalCalification != nil && (*item.FinalCalification < 0 || *item.FinalCalification > 10) {
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "final_calification must be between 0 and 10"})
-			return
-		}
-		payloadIDs = append(payloadIDs, subjectID)
-		payloadIDSet[subjectID] = struct{}{}
-
-		record := models.UserSubject{
-			UserID:    u.ID,
-			SubjectID: subjectID,
-			Status:    item.Status,
-		}
-		if item.FinalCalification != nil {
-			record.FinalCalification = *item.FinalCalification
-		} else {
-			missingCalificationSubjectIDs = append(missingCalificationSubjectIDs, subjectID)
-		}
-		recordIndexBySubjectID[subjectID] = len(records)
-		records = append(records, record)
-	}
-
-	if len(missingCalificationSubjectIDs) > 0 {
-		var existingRows []models.UserSubject
-		if err := db.Db.
-			Where("user_id = ? AND subject_id IN ?", u.ID, missingCalificationSubjectIDs).
-			Find(&existingRows).Error; err != nil {
-			slog.Error("Error loading existing user subject grades", slog.Any("error", err))
-			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error saving subjects"})
-			return
+		progress := services.SubjectProgress{SubjectID: subjectID, Status: item.Status, FinalCalification: item.FinalCalification}
+		if item.RegularizedAt != nil && item.Status == models.StatusFinalPending {
+			date, ok := parseAttemptDate(*item.RegularizedAt)
+			if !ok {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "regularized_at debe tener formato YYYY-MM-DD"})
+				return nil, false
+			}
+			progress.RegularizedAt = &date
 		}
-		for _, row := range existingRows {
-			if idx, ok := recordIndexBySubjectID[row.SubjectID]; ok {
-				records[idx].FinalCalification = row.FinalCalification
+		if item.ExamDate != nil && (item.Status == models.StatusPassed || item.Status == models.StatusPassedWithDist) {
+			date, ok := parseAttemptDate(*item.ExamDate)
+			if !ok {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "exam_date debe tener formato YYYY-MM-DD"})
+				return nil, false
 			}
+			progress.ExamDate = &date
 		}
+		items = append(items, progress)
 	}
+	return items, true
+}
 
-	tx := db.Db.Begin()
-	if tx.Error != nil {
-		slog.Error("Error starting transaction", slog.Any("error", tx.Error))
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error saving subjects"})
-		return
+// requestedValidationMode lee ?validation= o, si no viene, el modo del body. Vacío significa
+// usar el modo configurado en el programa; SaveUserSubjects sólo usa el pedido si es más estricto.
+func requestedValidationMode(c *gin.Context, fromBody *string) (models.RequirementValidationMode, bool) {
+	rawMode := c.Query("validation")
+	if rawMode == "" && fromBody != nil {
+		rawMode = *fromBody
 	}
-
-	if len(subjectIDs) > 0 {
-		if len(payloadIDs) == 0 {
-			if err := tx.Where("user_id = ? AND subject_id IN ?", u.ID, subjectIDs).Delete(&models.UserSubject{}).Error; err != nil {
-				slog.Error("Error deleting user subjects", slog.Any("error", err))
-				tx.Rollback()
-				c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error saving subjects"})
-				return
-			}
-		} else {
-			if err := tx.Where("user_id = ? AND subject_id IN ? AND subject_id NOT IN ?", u.ID, subjectIDs, payloadIDs).Delete(&models.UserSubject{}).Error; err != nil {
-				slog.Error("Error pruning user subjects", slog.Any("error", err))
-				tx.Rollback()
-				c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error saving subjects"})
-				return
-			}
-		}
+	if rawMode == "" {
+		return "", true
 	}
-
-	if len(records) > 0 {
-		if err := tx.Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "user_id"}, {Name: "subject_id"}},
-			DoUpdates: clause.AssignmentColumns([]string{"status", "final_calification", "updated_at"}),
-		}).Create(&records).Error; err != nil {
-			slog.Error("Error upserting user subjects", slog.Any("error", err))
-			tx.Rollback()
-			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error saving subjects"})
-			return
-		}
+	mode, ok := parseValidationMode(rawMode)
+	if !ok {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid validation mode"})
+		return "", false
 	}
+	return mode, true
+}
 
-	if err := tx.Commit().Error; err != nil {
-		slog.Error("Error committing user subjects transaction", slog.Any("error", err))
+// saveUserSubjects guarda con services.SaveUserSubjects y traduce sus errores a la respuesta
+// de POST /me/subjects/:programId.
+func saveUserSubjects(c *gin.Context, userID string, programID string, items []services.SubjectProgress, mode models.RequirementValidationMode, replace bool) {
+	warnings, err := services.SaveUserSubjects(userID, programID, items, mode, replace)
+	var violationsErr *services.RequirementViolationsError
+	switch {
+	case err == nil:
+		c.IndentedJSON(http.StatusOK, gin.H{"ok": true, "warnings": warnings})
+	case errors.As(err, &violationsErr):
+		c.IndentedJSON(http.StatusUnprocessableEntity, gin.H{
+			"error":      "Requirement violations",
+			"violations": violationsErr.Violations,
+		})
+	case errors.Is(err, services.ErrSubjectNotInProgram):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Subject not in program"})
+	case errors.Is(err, services.ErrInvalidSubjectStatus):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid subject status"})
+	case errors.Is(err, services.ErrDuplicateUserSubject):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Duplicate subject in payload"})
+	case errors.Is(err, services.ErrInvalidCalification):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		slog.Error("Error saving user subjects", slog.Any("error", err))
 		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Error saving subjects"})
-		return
 	}
-
-	c.IndentedJSON(http.StatusOK, gin.H{"ok": true})
 }
@@ -70,11 +70,15 @@ type SeedRequirementType string
 const (
 	SeedRequirementApproved   SeedRequirementType = "approved"
 	SeedRequirementRegularize SeedRequirementType = "regularized"
+	// SeedRequirementCorequisite se guarda en subject_corequisites, no en subject_requirements.
+	SeedRequirementCorequisite SeedRequirementType = "corequisite"
 )
 
 type JsonToDegreeProgram struct {
-	DegreeProgram SeedDegreeProgram `json:"degreeProgram"`
-	Subjects      []SeedSubject     `json:"subjects"`
+	DegreeProgram SeedDegreeProgram  `json:"degreeProgram"`
+	Subjects      []SeedSubject      `json:"subjects"`
+	ElectivePools []SeedElectivePool `json:"electivePools,omitempty"`
+	ElectiveRules []SeedElectiveRule `json:"electiveRules,omitempty"`
 }
 
 type SeedDegreeProgram struct {
@@ -83,15 +87,45 @@ type SeedDegreeProgram struct {
 }
 
 type SeedSubject struct {
-	Code         string            `json:"code"`
-	Name         string            `json:"name"`
-	SubjectYear  int               `json:"subjectYear"`
-	Term         SubjectTerm       `json:"term"`
-	IsElective   bool              `json:"is_elective"`
-	Requirements []SeedRequirement `json:"requirements"`
+	Code              string                 `json:"code"`
+	Name              string                 `json:"name"`
+	SubjectYear       int                    `json:"subjectYear"`
+	Term              SubjectTerm            `json:"term"`
+	IsElective        bool                   `json:"is_elective"`
+	Credits           float64                `json:"credits,omitempty"`
+	Hours             float64                `json:"hours,omitempty"`
+	Requirements      []SeedRequirement      `json:"requirements"`
+	RequirementGroups []SeedRequirementGroup `json:"requirementGroups,omitempty"`
 }
 
 type SeedRequirement struct {
 	SubjectCode string              `json:"subjectCode"`
 	Type        SeedRequirementType `json:"type"`
 }
+
+// SeedRequirementGroup es un RequirementGroup del seed; las materias se referencian por código.
+type SeedRequirementGroup struct {
+	Kind         RequirementGroupKind   `json:"kind"`
+	Type         SeedRequirementType    `json:"type,omitempty"`
+	MinCount     *int                   `json:"minCount,omitempty"`
+	MinimumValue *float64               `json:"minimumValue,omitempty"`
+	Year         *int                   `json:"year,omitempty"`
+	SubjectCodes []string               `json:"subjectCodes,omitempty"`
+	Groups       []SeedRequirementGroup `json:"groups,omitempty"`
+}
+
+// SeedElectivePool es un ElectivePool del seed; las reglas lo referencian por código.
+type SeedElectivePool struct {
+	Code         string   `json:"code"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description,omitempty"`
+	SubjectCodes []string `json:"subjectCodes"`
+}
+
+type SeedElectiveRule struct {
+	PoolCode        string                  `json:"poolCode"`
+	AppliesFromYear int                     `json:"appliesFromYear"`
+	AppliesToYear   *int                    `json:"appliesToYear,omitempty"`
+	RequirementType ElectiveRequirementType `json:"requirementType"`
+	MinimumValue    float64                 `json:"minimumValue"`
+}
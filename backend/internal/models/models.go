@@ -32,11 +32,21 @@ const (
 	InstitutionMixed   InstitutionType = "mixed"
 )
 
+type RequirementValidationMode string
+
+const (
+	ValidationOff    RequirementValidationMode = "off"
+	ValidationWarn   RequirementValidationMode = "warn"
+	ValidationStrict RequirementValidationMode = "strict"
+)
+
 type RequirementMinStatus string
 
 const (
 	ReqPassed       RequirementMinStatus = "passed"
 	ReqFinalPending RequirementMinStatus = "final_pending"
+	// ReqInProgress solo se usa para co-requisitos: alcanza con estar cursando la materia.
+	ReqInProgress RequirementMinStatus = "in_progress"
 )
 
 type User struct {
@@ -69,24 +79,60 @@ type University struct {
 }
 
 type DegreeProgram struct {
-	ID              string                      `json:"id" gorm:"primaryKey;size:191"`
-	Name            string                      `json:"name" gorm:"not null;size:191"`
-	UniversityID    string                      `json:"universityID" gorm:"not null;size:191;index"`
-	University      University                  `json:"university" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	Subjects        []Subject                   `json:"subjects" gorm:"foreignKey:DegreeProgramID;constraint:OnDelete:CASCADE"`
-	ElectiveRules   []ElectiveRule              `json:"electiveRules,omitempty" gorm:"foreignKey:DegreeProgramID;constraint:OnDelete:CASCADE"`
-	ElectivePools   []ElectivePool              `json:"electivePools,omitempty" gorm:"foreignKey:DegreeProgramID;constraint:OnDelete:CASCADE"`
-	Users           []*User                     `json:"users" gorm:"many2many:user_degree_programs"`
-	FavoritedBy     []*User                     `json:"favoritedBy,omitempty" gorm:"many2many:user_favorite_programs"`
-	ApprovalStatus  DegreeProgramApprovalStatus `json:"approvalStatus" gorm:"type:enum('pending','approved','rejected');default:'pending'"`
-	PublicRequested bool                        `json:"publicRequested" gorm:"default:false"`
-	CreatedAt       time.Time                   `json:"created_at"`
-	UpdatedAt       time.Time                   `json:"updated_at"`
+	ID                    string                      `json:"id" gorm:"primaryKey;size:191"`
+	Name                  string                      `json:"name" gorm:"not null;size:191"`
+	UniversityID          string                      `json:"universityID" gorm:"not null;size:191;index"`
+	University            University                  `json:"university" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Subjects              []Subject                   `json:"subjects" gorm:"foreignKey:DegreeProgramID;constraint:OnDelete:CASCADE"`
+	ElectiveRules         []ElectiveRule              `json:"electiveRules,omitempty" gorm:"foreignKey:DegreeProgramID;constraint:OnDelete:CASCADE"`
+	ElectivePools         []ElectivePool              `json:"electivePools,omitempty" gorm:"foreignKey:DegreeProgramID;constraint:OnDelete:CASCADE"`
+	Users                 []*User                     `json:"users" gorm:"many2many:user_degree_programs"`
+	FavoritedBy           []*User                     `json:"favoritedBy,omitempty" gorm:"many2many:user_favorite_programs"`
+	ApprovalStatus        DegreeProgramApprovalStatus `json:"approvalStatus" gorm:"type:enum('pending','approved','rejected');default:'pending'"`
+	PublicRequested       bool                        `json:"publicRequested" gorm:"default:false"`
+	RequirementValidation RequirementValidationMode   `json:"requirementValidation" gorm:"type:enum('off','warn','strict');default:'warn'"`
+	RegularizationMonths  *int                        `json:"regularizationMonths,omitempty"`
+	CreatedAt             time.Time                   `json:"created_at"`
+	UpdatedAt             time.Time                   `json:"updated_at"`
+}
+
+type PlanVersionStatus string
+
+const (
+	PlanVersionDraft     PlanVersionStatus = "draft"
+	PlanVersionPublished PlanVersionStatus = "published"
+)
+
+// PlanVersion es una revisión del plan de estudios de un programa ("Plan 2008", "Plan 2023").
+// Las materias, pools y reglas sin versión forman el plan base del programa.
+type PlanVersion struct {
+	ID              string            `json:"id" gorm:"primaryKey;size:191"`
+	DegreeProgramID string            `json:"degree_program_id" gorm:"not null;size:191;index"`
+	DegreeProgram   DegreeProgram     `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Name            string            `json:"name" gorm:"not null;size:191"`
+	Status          PlanVersionStatus `json:"status" gorm:"type:enum('draft','published');not null;default:'draft'"`
+	PublishedAt     *time.Time        `json:"published_at,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// UserPlanVersion guarda en qué versión del plan está inscripto un usuario dentro de un programa.
+type UserPlanVersion struct {
+	UserID          string    `json:"user_id" gorm:"primaryKey;size:191"`
+	DegreeProgramID string    `json:"degree_program_id" gorm:"primaryKey;size:191"`
+	PlanVersionID   string    `json:"plan_version_id" gorm:"not null;size:191;index"`
+	User            User      `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
+func (UserPlanVersion) TableName() string { return "user_plan_versions" }
+
 type Subject struct {
-	ID              string         `json:"id" gorm:"primaryKey;size:191"`
-	Name            string         `json:"name" gorm:"not null;size:191"`
+	ID   string `json:"id" gorm:"primaryKey;size:191"`
+	Name string `json:"name" gorm:"not null;size:191"`
+	// Code identifica la materia dentro del plan en los seeds; vacío en materias creadas a mano.
+	Code            string         `json:"code,omitempty" gorm:"size:64;index"`
 	Year            *int           `json:"year,omitempty" gorm:"column:subject_year"`
 	Requirements    []*Subject     `json:"requirements" gorm:"many2many:subject_requirements;joinForeignKey:SubjectID;joinReferences:RequirementID"`
 	DegreeProgramID string         `json:"degreeProgramID" gorm:"not null;size:191;index"`
@@ -94,23 +140,55 @@ type Subject struct {
 	Term            string         `json:"term" gorm:"type:enum('annual', 'semester', 'quarterly', 'bimonthly')"`
 	Hours           float64        `json:"hours,omitempty"`
 	IsElective      bool           `json:"is_elective" gorm:"default:false"`
+	PlanVersionID   *string        `json:"planVersionID,omitempty" gorm:"size:191;index"`
 	ElectivePools   []ElectivePool `json:"electivePools,omitempty" gorm:"many2many:elective_pool_subjects"`
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 }
 
 type UserSubject struct {
-	UserID            string        `gorm:"primaryKey;size:191;index:user_subject_unique,unique"`
-	SubjectID         string        `gorm:"primaryKey;size:191;index:user_subject_unique,unique"`
-	Status            SubjectStatus `gorm:"type:enum('available','in_progress','passed_with_distinction','final_pending','passed');default:'available'"`
-	FinalCalification float64       `json:"final_calification" gorm:"column:final_calification"`
-	User              User          `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
-	Subject           Subject       `gorm:"foreignKey:SubjectID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
-	UpdatedAt         time.Time
+	UserID                string        `gorm:"primaryKey;size:191;index:user_subject_unique,unique"`
+	SubjectID             string        `gorm:"primaryKey;size:191;index:user_subject_unique,unique"`
+	Status                SubjectStatus `gorm:"type:enum('available','in_progress','passed_with_distinction','final_pending','passed');default:'available'"`
+	FinalCalification     float64       `json:"final_calification" gorm:"column:final_calification"`
+	RegularizedAt         *time.Time    `json:"regularized_at,omitempty"`
+	RegularizationExpired bool          `json:"regularization_expired" gorm:"not null;default:false"`
+	User                  User          `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	Subject               Subject       `gorm:"foreignKey:SubjectID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	UpdatedAt             time.Time
+
+	// ManualStatus y ManualCalification guardan lo que el usuario había cargado a mano mientras
+	// el estado sale de los intentos de examen; vacío significa que el estado es manual.
+	ManualStatus       SubjectStatus `json:"-" gorm:"size:32;not null;default:''"`
+	ManualCalification float64       `json:"-" gorm:"not null;default:0"`
 }
 
 func (UserSubject) TableName() string { return "user_subjects" }
 
+type ExamType string
+
+const (
+	ExamFinal       ExamType = "final"
+	ExamPromotion   ExamType = "promotion"
+	ExamEquivalence ExamType = "equivalence"
+)
+
+// ExamAttempt es una instancia de examen rendida por un usuario. El estado y la nota de
+// UserSubject se derivan del historial de intentos.
+type ExamAttempt struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:191"`
+	UserID    string    `json:"user_id" gorm:"not null;size:191;index:idx_exam_attempt_user_subject"`
+	SubjectID string    `json:"subject_id" gorm:"not null;size:191;index:idx_exam_attempt_user_subject"`
+	User      User      `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	Subject   Subject   `json:"-" gorm:"foreignKey:SubjectID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	Date      time.Time `json:"date" gorm:"type:date;not null"`
+	Grade     float64   `json:"grade"`
+	Passed    bool      `json:"passed" gorm:"not null;default:false"`
+	Type      ExamType  `json:"type" gorm:"type:enum('final','promotion','equivalence');not null;default:'final'"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 type SubjectRequirement struct {
 	SubjectID     string               `gorm:"primaryKey;size:191;index:uniq_subject_req,unique"`
 	RequirementID string               `gorm:"primaryKey;size:191;index:uniq_subject_req,unique"`
@@ -121,6 +199,56 @@ type SubjectRequirement struct {
 
 func (SubjectRequirement) TableName() string { return "subject_requirements" }
 
+// SubjectCorequisite indica que SubjectID se cursa junto con CorequisiteID: para cursarla, la
+// co-requisito tiene que estar en curso o ya aprobada. Dos materias pueden ser co-requisito mutuo.
+type SubjectCorequisite struct {
+	SubjectID     string    `json:"subject_id" gorm:"primaryKey;size:191"`
+	CorequisiteID string    `json:"corequisite_id" gorm:"primaryKey;size:191;index"`
+	Subject       Subject   `json:"-" gorm:"foreignKey:SubjectID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	Corequisite   Subject   `json:"-" gorm:"foreignKey:CorequisiteID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (SubjectCorequisite) TableName() string { return "subject_corequisites" }
+
+type RequirementGroupKind string
+
+const (
+	GroupAll          RequirementGroupKind = "all"
+	GroupAny          RequirementGroupKind = "any"
+	GroupCredits      RequirementGroupKind = "credits"
+	GroupHours        RequirementGroupKind = "hours"
+	GroupSubjectCount RequirementGroupKind = "subject_count"
+)
+
+// RequirementGroup es una condición compuesta para cursar una materia, que se suma (AND) a sus
+// correlativas simples. all/any combinan materias y subgrupos (any con MinCount es "N de M");
+// credits/hours/subject_count exigen un mínimo sobre las materias que alcanzan MinStatus.
+// Sin materias explícitas, Year toma todas las materias de ese año (o del plan si es nil en los umbrales).
+type RequirementGroup struct {
+	ID           string                    `json:"id" gorm:"primaryKey;size:191"`
+	SubjectID    string                    `json:"subject_id" gorm:"not null;size:191;index"`
+	Subject      Subject                   `json:"-" gorm:"foreignKey:SubjectID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	ParentID     *string                   `json:"parent_id,omitempty" gorm:"size:191;index"`
+	Kind         RequirementGroupKind      `json:"kind" gorm:"type:enum('all','any','credits','hours','subject_count');not null"`
+	MinStatus    RequirementMinStatus      `json:"min_status" gorm:"type:enum('passed','final_pending');not null;default:'passed'"`
+	MinCount     *int                      `json:"min_count,omitempty"`
+	MinimumValue *float64                  `json:"minimum_value,omitempty"`
+	Year         *int                      `json:"year,omitempty"`
+	Subjects     []RequirementGroupSubject `json:"subjects" gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	Children     []RequirementGroup        `json:"children" gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+}
+
+type RequirementGroupSubject struct {
+	GroupID   string  `json:"-" gorm:"primaryKey;size:191"`
+	SubjectID string  `json:"subject_id" gorm:"primaryKey;size:191;index"`
+	Subject   Subject `json:"-" gorm:"foreignKey:SubjectID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+}
+
+func (RequirementGroupSubject) TableName() string { return "requirement_group_subjects" }
+
 type Session struct {
 	ID        string    `gorm:"type:char(36);primaryKey"`
 	UserID    string    `gorm:"not null;index"`
@@ -142,12 +270,29 @@ type PasswordResetToken struct {
 	UpdatedAt time.Time
 }
 
+// ProgressShareToken deja ver, sin cuenta y sólo lectura, el avance de un usuario en uno de sus
+// programas. Como con PasswordResetToken, se guarda sólo el hash del token.
+type ProgressShareToken struct {
+	ID              string        `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID          string        `json:"-" gorm:"not null;size:191;index"`
+	User            User          `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	DegreeProgramID string        `json:"degree_program_id" gorm:"not null;size:191;index"`
+	DegreeProgram   DegreeProgram `json:"-" gorm:"foreignKey:DegreeProgramID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	TokenHash       string        `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	Label           string        `json:"label,omitempty" gorm:"size:191"`
+	ExpiresAt       *time.Time    `json:"expires_at,omitempty" gorm:"index"`
+	LastViewedAt    *time.Time    `json:"last_viewed_at,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
 type ElectivePool struct {
 	ID              string        `json:"id" gorm:"primaryKey;size:191"`
 	DegreeProgramID string        `json:"degree_program_id" gorm:"not null;size:191;index"`
 	DegreeProgram   DegreeProgram `json:"degree_program,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	Name            string        `json:"name" gorm:"not null;size:191"`
 	Description     string        `json:"description,omitempty" gorm:"size:191"`
+	PlanVersionID   *string       `json:"plan_version_id,omitempty" gorm:"size:191;index"`
 	Subjects        []Subject     `json:"subjects,omitempty" gorm:"many2many:elective_pool_subjects"`
 	CreatedAt       time.Time     `json:"created_at"`
 	UpdatedAt       time.Time     `json:"updated_at"`
@@ -180,10 +325,45 @@ type ElectiveRule struct {
 	AppliesToYear   *int                    `json:"applies_to_year,omitempty"`
 	RequirementType ElectiveRequirementType `json:"requirement_type" gorm:"type:enum('hours','credits','subject_count');not null"`
 	MinimumValue    float64                 `json:"minimum_value" gorm:"not null"`
+	PlanVersionID   *string                 `json:"plan_version_id,omitempty" gorm:"size:191;index"`
 	CreatedAt       time.Time               `json:"created_at"`
 	UpdatedAt       time.Time               `json:"updated_at"`
 }
 
+// SubjectEquivalence indica que aprobar todas las materias de origen equivale a aprobar la
+// materia destino (A ≙ B, A+B ≙ C). El origen puede ser otro programa o otra versión del plan.
+type SubjectEquivalence struct {
+	ID              string                     `json:"id" gorm:"primaryKey;size:191"`
+	DegreeProgramID string                     `json:"degree_program_id" gorm:"not null;size:191;index"`
+	DegreeProgram   DegreeProgram              `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	SourceProgramID string                     `json:"source_program_id" gorm:"not null;size:191;index"`
+	TargetSubjectID string                     `json:"target_subject_id" gorm:"not null;size:191;index"`
+	TargetSubject   Subject                    `json:"-" gorm:"foreignKey:TargetSubjectID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	Sources         []SubjectEquivalenceSource `json:"sources" gorm:"foreignKey:EquivalenceID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	CreatedAt       time.Time                  `json:"created_at"`
+	UpdatedAt       time.Time                  `json:"updated_at"`
+}
+
+type SubjectEquivalenceSource struct {
+	EquivalenceID string  `json:"-" gorm:"primaryKey;size:191"`
+	SubjectID     string  `json:"subject_id" gorm:"primaryKey;size:191;index"`
+	Subject       Subject `json:"-" gorm:"foreignKey:SubjectID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+}
+
+func (SubjectEquivalenceSource) TableName() string { return "subject_equivalence_sources" }
+
+// SubjectRecognition vincula dos materias de programas distintos que se reconocen entre sí.
+// El vínculo es simétrico: se guarda una sola fila con SubjectID < RecognizedSubjectID.
+type SubjectRecognition struct {
+	SubjectID           string    `json:"subject_id" gorm:"primaryKey;size:191"`
+	RecognizedSubjectID string    `json:"recognized_subject_id" gorm:"primaryKey;size:191;index"`
+	Subject             Subject   `json:"-" gorm:"foreignKey:SubjectID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	RecognizedSubject   Subject   `json:"-" gorm:"foreignKey:RecognizedSubjectID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+func (SubjectRecognition) TableName() string { return "subject_recognitions" }
+
 type AdditionalInformation struct {
 	ID           string    `json:"id" gorm:"primaryKey;size:191"`
 	UniversityID string    `json:"university_id" gorm:"not null;size:191;index"`
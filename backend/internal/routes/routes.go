@@ -146,6 +146,7 @@ func SetUpRoutes(r *gin.Engine, db *gorm.DB) {
 		users.PUT("/:id", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin"), handlers.UpdateUser)
 		users.DELETE("/:id", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin"), handlers.DeleteUser)
 		users.POST("/:id/session/revoke", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin"), handlers.RevokeSession)
+		users.GET("/:id/programs/:programId/audit", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin", "staff"), handlers.GetUserProgramAudit)
 	}
 	degreeProgram := r.Group("/degreeProgram")
 	{
@@ -158,6 +159,20 @@ func SetUpRoutes(r *gin.Engine, db *gorm.DB) {
 		degreeProgram.POST("/:id/publish", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin", "staff"), handlers.PublishProgram)
 		degreeProgram.POST("/:id/unapprove", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin", "staff"), handlers.UnapproveProgram)
 		degreeProgram.POST("/:id/unpublish", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin", "staff"), handlers.UnpublishProgram)
+		degreeProgram.GET("/:id/graph", middleware.OptionalAuth(db, sessSvc, cookies), handlers.GetProgramGraph)
+		degreeProgram.GET("/:id/export", middleware.OptionalAuth(db, sessSvc, cookies), handlers.ExportProgram)
+		degreeProgram.GET("/:id/validate", middleware.OptionalAuth(db, sessSvc, cookies), handlers.ValidateProgram)
+		degreeProgram.GET("/:id/graph/analysis", middleware.OptionalAuth(db, sessSvc, cookies), handlers.GetProgramGraphAnalysis)
+		degreeProgram.GET("/:id/versions", middleware.OptionalAuth(db, sessSvc, cookies), handlers.GetPlanVersionsByProgram)
+		degreeProgram.POST("/:id/versions", middleware.AuthRequired(db, sessSvc, cookies), handlers.CreatePlanVersion)
+		degreeProgram.PUT("/:id/versions/:versionId", middleware.AuthRequired(db, sessSvc, cookies), handlers.UpdatePlanVersion)
+		degreeProgram.POST("/:id/versions/:versionId/publish", middleware.AuthRequired(db, sessSvc, cookies), handlers.PublishPlanVersion)
+		degreeProgram.DELETE("/:id/versions/:versionId", middleware.AuthRequired(db, sessSvc, cookies), handlers.DeletePlanVersion)
+		degreeProgram.GET("/:id/requirementGroups", middleware.OptionalAuth(db, sessSvc, cookies), handlers.GetRequirementGroupsByProgram)
+		degreeProgram.GET("/:id/equivalences", middleware.OptionalAuth(db, sessSvc, cookies), handlers.GetEquivalencesByProgram)
+		degreeProgram.POST("/:id/equivalences", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin", "staff"), handlers.CreateEquivalence)
+		degreeProgram.PUT("/:id/equivalences/:equivalenceId", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin", "staff"), handlers.UpdateEquivalence)
+		degreeProgram.DELETE("/:id/equivalences/:equivalenceId", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin", "staff"), handlers.DeleteEquivalence)
 		
 		degreeProgram.POST("/:id/electivePools", middleware.AuthRequired(db, sessSvc, cookies), handlers.CreateElectivePool)
 		degreeProgram.GET("/:id/electivePools", handlers.GetElectivePoolsByProgram)
@@ -174,13 +189,28 @@ func SetUpRoutes(r *gin.Engine, db *gorm.DB) {
 		degreeProgram.DELETE("/:id/electiveRules/:ruleId", middleware.AuthRequired(db, sessSvc, cookies), handlers.DeleteElectiveRule)
 		
 		degreeProgram.POST("/seed", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin", "staff"), handlers.UploadSeed)
+		degreeProgram.POST("/:id/import", middleware.AuthRequired(db, sessSvc, cookies), handlers.ImportSubjects)
+		degreeProgram.PUT("/:id/seed", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin", "staff"), handlers.UpsertSeed)
 	}
 	subjects := r.Group("/subjects")
 	{
 		subjects.POST("", middleware.AuthRequired(db, sessSvc, cookies), handlers.CreateSubject)
-		subjects.GET("/:programId", handlers.GetAllSubjectsFromProgram)
+		subjects.GET("/:id", handlers.GetAllSubjectsFromProgram)
 		subjects.PUT("/:id", middleware.AuthRequired(db, sessSvc, cookies), handlers.UpdateSubject)
 		subjects.DELETE("/:id", middleware.AuthRequired(db, sessSvc, cookies), handlers.DeleteSubject)
+		subjects.GET("/:id/requirements", middleware.OptionalAuth(db, sessSvc, cookies), handlers.GetSubjectRequirements)
+		subjects.POST("/:id/requirements", middleware.AuthRequired(db, sessSvc, cookies), handlers.AddSubjectRequirement)
+		subjects.PUT("/:id/requirements/:requirementId", middleware.AuthRequired(db, sessSvc, cookies), handlers.UpdateSubjectRequirement)
+		subjects.DELETE("/:id/requirements/:requirementId", middleware.AuthRequired(db, sessSvc, cookies), handlers.DeleteSubjectRequirement)
+		subjects.POST("/:id/requirementGroups", middleware.AuthRequired(db, sessSvc, cookies), handlers.CreateRequirementGroup)
+		subjects.PUT("/:id/requirementGroups/:groupId", middleware.AuthRequired(db, sessSvc, cookies), handlers.UpdateRequirementGroup)
+		subjects.DELETE("/:id/requirementGroups/:groupId", middleware.AuthRequired(db, sessSvc, cookies), handlers.DeleteRequirementGroup)
+	}
+	recognitions := r.Group("/recognitions")
+	{
+		recognitions.GET("/:subjectId", middleware.OptionalAuth(db, sessSvc, cookies), handlers.GetSubjectRecognitions)
+		recognitions.POST("", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin", "staff"), handlers.CreateRecognition)
+		recognitions.DELETE("/:subjectId/:recognizedSubjectId", middleware.AuthRequired(db, sessSvc, cookies), middleware.RoleRequired("admin", "staff"), handlers.DeleteRecognition)
 	}
 	universities := r.Group("/universities")
 	{
@@ -206,6 +236,14 @@ func SetUpRoutes(r *gin.Engine, db *gorm.DB) {
 	{
 		me.GET("/subjects/:programId", handlers.GetMySubjectsFromProgram)
 		me.POST("/subjects/:programId", handlers.SaveMySubjectsFromProgram)
+		me.POST("/subjects/:programId/import", handlers.ImportMyTranscript)
+		me.POST("/subjects/:programId/import/apply", handlers.ApplyMyTranscript)
+		me.GET("/attempts/:subjectId", handlers.GetMyExamAttempts)
+		me.POST("/attempts/:subjectId", handlers.CreateMyExamAttempt)
+		me.PUT("/attempts/:subjectId/:attemptId", handlers.UpdateMyExamAttempt)
+		me.DELETE("/attempts/:subjectId/:attemptId", handlers.DeleteMyExamAttempt)
+		me.GET("/shares", handlers.GetMyProgressShares)
+		me.DELETE("/shares/:shareId", handlers.RevokeMyProgressShare)
 		program := me.Group("/programs")
 		{
 			program.GET("", handlers.GetMyPrograms)
@@ -213,6 +251,17 @@ func SetUpRoutes(r *gin.Engine, db *gorm.DB) {
 			program.DELETE("/:id/enroll", handlers.UnenrollProgram)
 			program.POST("/:id/favorite", handlers.FavoriteProgram)
 			program.DELETE("/:id/favorite", handlers.UnfavoriteProgram)
+			program.GET("/:id/progress", handlers.GetMyProgramProgress)
+			program.GET("/:id/record", handlers.GetMyProgramRecord)
+			program.GET("/:id/shares", handlers.GetMyProgressShares)
+			program.POST("/:id/shares", handlers.CreateMyProgressShare)
+			program.GET("/:id/electives", handlers.GetMyProgramElectives)
+			program.GET("/:id/audit", handlers.GetMyProgramAudit)
+			program.GET("/:id/plan", handlers.GetMyProgramPlan)
+			program.GET("/:id/regularizations", handlers.GetMyProgramRegularizations)
+			program.PUT("/:id/version", handlers.SetMyPlanVersion)
+			program.GET("/:id/migration", handlers.PreviewMyMigration)
+			program.POST("/:id/migration", handlers.MigrateMyProgram)
 		}
 	}
 
@@ -229,6 +278,8 @@ func SetUpRoutes(r *gin.Engine, db *gorm.DB) {
 		ToEmail: suggestionsToEmail,
 	}
 	r.POST("/suggestions", middleware.RateLimit(10, time.Minute), suggestionHandlers.Submit)
+	r.GET("/shared/:token", middleware.RateLimit(30, time.Minute), handlers.GetSharedProgress)
+	r.GET("/search", middleware.OptionalAuth(db, sessSvc, cookies), middleware.RateLimit(60, time.Minute), handlers.Search)
 }
 
 func startMaintenanceJobs(db *gorm.DB, sessions *services.Service) {
@@ -244,11 +295,21 @@ func startMaintenanceJobs(db *gorm.DB, sessions *services.Service) {
 			Delete(&models.PasswordResetToken{})
 		if tx.Error != nil {
 			slog.Warn("failed to delete expired password reset tokens", slog.Any("error", tx.Error))
-			return
-		}
-		if tx.RowsAffected > 0 {
+		} else if tx.RowsAffected > 0 {
 			slog.Info("expired password reset tokens deleted", slog.Int64("count", tx.RowsAffected))
 		}
+
+		if deletedShares, err := services.DeleteExpiredProgressShares(now); err != nil {
+			slog.Warn("failed to delete expired share links", slog.Any("error", err))
+		} else if deletedShares > 0 {
+			slog.Info("expired share links deleted", slog.Int64("count", deletedShares))
+		}
+
+		if expired, err := services.MarkExpiredRegularizations(now); err != nil {
+			slog.Warn("failed to flag expired regularizations", slog.Any("error", err))
+		} else if expired > 0 {
+			slog.Info("expired regularizations flagged", slog.Int64("count", expired))
+		}
 	}
 
 	runCleanup()
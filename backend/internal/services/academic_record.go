@@ -0,0 +1,288 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecordEntry es una materia de la historia académica. Grade sólo viene en las aprobadas con nota.
+type RecordEntry struct {
+	SubjectID      string               `json:"subject_id"`
+	Name           string               `json:"name"`
+	Year           *int                 `json:"year"`
+	Term           string               `json:"term"`
+	IsElective     bool                 `json:"is_elective"`
+	Status         models.SubjectStatus `json:"status"`
+	Grade          *float64             `json:"grade"`
+	Credits        float64              `json:"credits"`
+	Hours          float64              `json:"hours"`
+	RegularizedAt  *time.Time           `json:"regularized_at,omitempty"`
+	RecognizedFrom string               `json:"recognized_from,omitempty"`
+}
+
+// RecordTotals resume el avance: créditos y horas son los aprobados (obligatorias y electivas)
+// y el porcentaje es el de ComputeProgress sobre las obligatorias.
+type RecordTotals struct {
+	Subjects            int      `json:"subjects"`
+	Passed              int      `json:"passed"`
+	FinalsPending       int      `json:"finals_pending"`
+	InProgress          int      `json:"in_progress"`
+	Percentage          float64  `json:"percentage"`
+	Credits             float64  `json:"credits"`
+	Hours               float64  `json:"hours"`
+	Average             *float64 `json:"average"`
+	AverageWithFailures *float64 `json:"average_with_failures"`
+}
+
+type AcademicRecord struct {
+	ProgramID   string        `json:"program_id"`
+	Program     string        `json:"program"`
+	University  string        `json:"university"`
+	Student     string        `json:"student"`
+	GeneratedAt time.Time     `json:"generated_at"`
+	Subjects    []RecordEntry `json:"subjects"`
+	Totals      RecordTotals  `json:"totals"`
+}
+
+var recordStatusLabels = map[models.SubjectStatus]string{
+	models.StatusAvailable:      "Disponible",
+	models.StatusInProgress:     "Cursando",
+	models.StatusFinalPending:   "Final pendiente",
+	models.StatusPassed:         "Aprobada",
+	models.StatusPassedWithDist: "Aprobada con distinción",
+}
+
+var recordTermLabels = map[string]string{
+	string(models.TermAnnual):    "Anual",
+	string(models.TermSemester):  "Cuatrimestral",
+	string(models.TermQuarterly): "Trimestral",
+	string(models.TermBimonthly): "Bimestral",
+}
+
+// LoadAcademicRecord arma la historia académica del usuario en su versión del plan.
+func LoadAcademicRecord(user models.User, programID string, now time.Time) (*AcademicRecord, error) {
+	var program models.DegreeProgram
+	if err := db.Db.Preload("University").Where("id = ?", programID).First(&program).Error; err != nil {
+		return nil, err
+	}
+	state, err := LoadUserProgramState(user.ID, programID)
+	if err != nil {
+		return nil, err
+	}
+	attempts, err := LoadProgramExamAttempts(user.ID, programID)
+	if err != nil {
+		return nil, err
+	}
+	record := BuildAcademicRecord(program, user.Email, state, attempts, now)
+	return &record, nil
+}
+
+// BuildAcademicRecord lista las materias con algún avance (las disponibles no aparecen),
+// ordenadas por año y nombre, con los totales y promedios de ComputeProgress.
+func BuildAcademicRecord(program models.DegreeProgram, student string, state *UserProgramState, attempts []models.ExamAttempt, now time.Time) AcademicRecord {
+	progress := ComputeProgress(state.Subjects, state.UserSubjects, attempts)
+	record := AcademicRecord{
+		ProgramID:   program.ID,
+		Program:     program.Name,
+		University:  program.University.Name,
+		Student:     student,
+		GeneratedAt: now,
+		Subjects:    make([]RecordEntry, 0),
+		Totals: RecordTotals{
+			Subjects:            len(state.Subjects),
+			FinalsPending:       progress.FinalsPending,
+			InProgress:          progress.InProgress,
+			Percentage:          progress.Subjects.Percentage,
+			Credits:             progress.Credits.Completed + progress.ElectiveCredits,
+			Hours:               progress.Hours.Completed + progress.ElectiveHours,
+			Average:             progress.Average,
+			AverageWithFailures: progress.AverageWithFailures,
+		},
+	}
+
+	bySubject := make(map[string]models.UserSubject, len(state.UserSubjects))
+	for _, us := range state.UserSubjects {
+		bySubject[us.SubjectID] = us
+	}
+	for _, s := range sortSubjectsForExport(state.Subjects) {
+		us, ok := bySubject[s.ID]
+		if !ok || us.Status == models.StatusAvailable || us.Status == "" {
+			continue
+		}
+		entry := RecordEntry{
+			SubjectID:      s.ID,
+			Name:           s.Name,
+			Year:           s.Year,
+			Term:           s.Term,
+			IsElective:     s.IsElective,
+			Status:         us.Status,
+			Credits:        s.Credits,
+			Hours:          s.Hours,
+			RecognizedFrom: state.Recognized[s.ID],
+		}
+		if IsPassed(us.Status) {
+			record.Totals.Passed++
+			if us.FinalCalification > 0 {
+				grade := us.FinalCalification
+				entry.Grade = &grade
+			}
+		}
+		if us.Status == models.StatusFinalPending {
+			entry.RegularizedAt = us.RegularizedAt
+		}
+		record.Subjects = append(record.Subjects, entry)
+	}
+	return record
+}
+
+// RenderRecordCSV escribe una fila por materia y, después de una fila vacía, los totales como
+// pares clave/valor.
+func RenderRecordCSV(record AcademicRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := [][]string{{"subject", "year", "term", "status", "grade", "credits", "hours", "regularized_at"}}
+	for _, e := range record.Subjects {
+		year, grade, regularizedAt := "", "", ""
+		if e.Year != nil {
+			year = strconv.Itoa(*e.Year)
+		}
+		if e.Grade != nil {
+			grade = strconv.FormatFloat(*e.Grade, 'f', -1, 64)
+		}
+		if e.RegularizedAt != nil {
+			regularizedAt = e.RegularizedAt.Format(time.DateOnly)
+		}
+		rows = append(rows, []string{e.Name, year, e.Term, string(e.Status), grade,
+			strconv.FormatFloat(e.Credits, 'f', -1, 64), strconv.FormatFloat(e.Hours, 'f', -1, 64), regularizedAt})
+	}
+	optional := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	}
+	t := record.Totals
+	rows = append(rows,
+		[]string{},
+		[]string{"subjects", strconv.Itoa(t.Subjects)},
+		[]string{"passed", strconv.Itoa(t.Passed)},
+		[]string{"finals_pending", strconv.Itoa(t.FinalsPending)},
+		[]string{"in_progress", strconv.Itoa(t.InProgress)},
+		[]string{"percentage", strconv.FormatFloat(t.Percentage, 'f', -1, 64)},
+		[]string{"credits", strconv.FormatFloat(t.Credits, 'f', -1, 64)},
+		[]string{"hours", strconv.FormatFloat(t.Hours, 'f', -1, 64)},
+		[]string{"average", optional(t.Average)},
+		[]string{"average_with_failures", optional(t.AverageWithFailures)},
+	)
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// recordNumber usa coma decimal, como se lee en los certificados en español.
+func recordNumber(v float64) string {
+	return strings.Replace(strconv.FormatFloat(v, 'f', -1, 64), ".", ",", 1)
+}
+
+// RenderRecordPDF arma la historia académica en A4: encabezado, una tabla que sigue en páginas
+// nuevas (repitiendo los títulos) y el resumen al final.
+func RenderRecordPDF(record AcademicRecord) []byte {
+	const (
+		margin   = 50.0
+		rowSize  = 9.0
+		rowStep  = 14.0
+		minY     = 70.0
+		colName  = margin
+		colYear  = 295.0
+		colTerm  = 325.0
+		colState = 400.0
+		colGrade = 525.0
+	)
+	doc := newPDFDocument()
+	y := pdfPageHeight - margin
+
+	doc.AddPage()
+	doc.Text(margin, y, 16, true, "Historia académica")
+	y -= 24
+	title := record.Program
+	if record.University != "" {
+		title += " - " + record.University
+	}
+	doc.Text(margin, y, 11, false, pdfFit(title, 11, pdfPageWidth-2*margin))
+	y -= 16
+	doc.Text(margin, y, 10, false, "Estudiante: "+record.Student)
+	y -= 14
+	doc.Text(margin, y, 10, false, "Generado el "+record.GeneratedAt.Format("02/01/2006"))
+	y -= 28
+
+	header := func() {
+		doc.Text(colName, y, rowSize, true, "Materia")
+		doc.Text(colYear, y, rowSize, true, "Año")
+		doc.Text(colTerm, y, rowSize, true, "Cursada")
+		doc.Text(colState, y, rowSize, true, "Estado")
+		doc.Text(colGrade, y, rowSize, true, "Nota")
+		doc.Line(margin, y-4, pdfPageWidth-margin, y-4)
+		y -= rowStep + 2
+	}
+	header()
+	if len(record.Subjects) == 0 {
+		doc.Text(colName, y, rowSize, false, "Todavía no hay materias con avance registrado.")
+		y -= rowStep
+	}
+	for _, e := range record.Subjects {
+		if y < minY {
+			doc.AddPage()
+			y = pdfPageHeight - margin
+			header()
+		}
+		name := e.Name
+		if e.IsElective {
+			name += " (electiva)"
+		}
+		doc.Text(colName, y, rowSize, false, pdfFit(name, rowSize, colYear-colName-8))
+		if e.Year != nil {
+			doc.Text(colYear, y, rowSize, false, strconv.Itoa(*e.Year))
+		}
+		doc.Text(colTerm, y, rowSize, false, recordTermLabels[e.Term])
+		doc.Text(colState, y, rowSize, false, pdfFit(recordStatusLabels[e.Status], rowSize, colGrade-colState-8))
+		if e.Grade != nil {
+			doc.Text(colGrade, y, rowSize, false, recordNumber(*e.Grade))
+		}
+		y -= rowStep
+	}
+
+	t := record.Totals
+	lines := []string{
+		fmt.Sprintf("Materias aprobadas: %d de %d (%s%% de las obligatorias)", t.Passed, t.Subjects, recordNumber(t.Percentage)),
+		fmt.Sprintf("Finales pendientes: %d", t.FinalsPending),
+		fmt.Sprintf("Cursando: %d", t.InProgress),
+		fmt.Sprintf("Créditos aprobados: %s", recordNumber(t.Credits)),
+		fmt.Sprintf("Horas aprobadas: %s", recordNumber(t.Hours)),
+	}
+	if t.Average != nil {
+		lines = append(lines, "Promedio: "+recordNumber(*t.Average))
+	}
+	if t.AverageWithFailures != nil {
+		lines = append(lines, "Promedio con aplazos: "+recordNumber(*t.AverageWithFailures))
+	}
+	if y-float64(len(lines)+2)*rowStep < minY {
+		doc.AddPage()
+		y = pdfPageHeight - margin
+	} else {
+		y -= rowStep
+	}
+	doc.Text(margin, y, 11, true, "Resumen")
+	y -= rowStep + 4
+	for _, line := range lines {
+		doc.Text(margin, y, 10, false, line)
+		y -= rowStep
+	}
+	return doc.Bytes()
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func recordFixture() AcademicRecord {
+	regularized := time.Date(2024, 7, 20, 0, 0, 0, 0, time.UTC)
+	state := &UserProgramState{
+		Subjects: []models.Subject{
+			{ID: "am2", Name: "Análisis Matemático II", Year: intPtr(2), Term: "semester", Credits: 6},
+			{ID: "am1", Name: "Análisis Matemático I", Year: intPtr(1), Term: "annual", Credits: 8},
+			{ID: "fis", Name: "Física I", Year: intPtr(1), Term: "annual", Credits: 8},
+			{ID: "ele", Name: "Taller (electiva)", Year: intPtr(3), IsElective: true, Credits: 3},
+			{ID: "alg", Name: "Álgebra", Year: intPtr(1), Term: "semester", Credits: 6},
+		},
+		UserSubjects: []models.UserSubject{
+			{SubjectID: "am1", Status: models.StatusPassed, FinalCalification: 8},
+			{SubjectID: "fis", Status: models.StatusFinalPending, RegularizedAt: &regularized},
+			{SubjectID: "am2", Status: models.StatusInProgress},
+			{SubjectID: "ele", Status: models.StatusPassedWithDist, FinalCalification: 10},
+			{SubjectID: "alg", Status: models.StatusAvailable},
+		},
+		Recognized: map[string]string{"ele": "other-ele"},
+	}
+	program := models.DegreeProgram{ID: "p1", Name: "Ingeniería", University: models.University{Name: "UTN"}}
+	return BuildAcademicRecord(program, "ana@example.com", state, nil, time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC))
+}
+
+func TestBuildAcademicRecord(t *testing.T) {
+	t.Parallel()
+
+	record := recordFixture()
+	names := make([]string, 0, len(record.Subjects))
+	for _, e := range record.Subjects {
+		names = append(names, e.SubjectID)
+	}
+	if got := strings.Join(names, ","); got != "am1,fis,am2,ele" {
+		t.Fatalf("subjects = %s, want available subjects left out and sorted by year and name", got)
+	}
+	am1, fis, ele := record.Subjects[0], record.Subjects[1], record.Subjects[3]
+	if am1.Grade == nil || *am1.Grade != 8 || fis.Grade != nil || fis.RegularizedAt == nil || ele.RecognizedFrom != "other-ele" {
+		t.Fatalf("entries = %+v", record.Subjects)
+	}
+
+	want := RecordTotals{Subjects: 5, Passed: 2, FinalsPending: 1, InProgress: 1, Percentage: 25, Credits: 11}
+	got := record.Totals
+	if got.Subjects != want.Subjects || got.Passed != want.Passed || got.FinalsPending != want.FinalsPending ||
+		got.InProgress != want.InProgress || got.Percentage != want.Percentage || got.Credits != want.Credits {
+		t.Fatalf("totals = %+v, want %+v", got, want)
+	}
+	if got.Average == nil || *got.Average != 9 {
+		t.Fatalf("average = %v, want 9", got.Average)
+	}
+}
+
+func TestRenderRecordCSV(t *testing.T) {
+	t.Parallel()
+
+	out, err := RenderRecordCSV(recordFixture())
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if lines[0] != "subject,year,term,status,grade,credits,hours,regularized_at" {
+		t.Fatalf("header = %q", lines[0])
+	}
+	if lines[2] != "Física I,1,annual,final_pending,,8,0,2024-07-20" {
+		t.Fatalf("row = %q", lines[2])
+	}
+	if !strings.Contains(string(out), "\npassed,2\n") || !strings.Contains(string(out), "\naverage,9\n") {
+		t.Fatalf("totals missing in %q", out)
+	}
+}
+
+func TestRenderRecordPDF(t *testing.T) {
+	t.Parallel()
+
+	record := recordFixture()
+	for i := 0; i < 80; i++ {
+		record.Subjects = append(record.Subjects, RecordEntry{Name: fmt.Sprintf("Materia %d", i), Status: models.StatusInProgress})
+	}
+	out := RenderRecordPDF(record)
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+	if !bytes.Contains(out, []byte("/Count 2")) {
+		t.Fatal("expected the table to continue on a second page")
+	}
+	if !bytes.Contains(out, []byte(`(Historia acad\351mica)`)) || !bytes.Contains(out, []byte(`Taller \(electiva\)`)) {
+		t.Fatal("expected WinAnsi text with escaped parentheses")
+	}
+
+	// Cada entrada de la tabla xref tiene que apuntar al comienzo de su objeto.
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point to the xref table", xref)
+	}
+	for i, m := range regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1) {
+		offset, _ := strconv.Atoi(string(m[1]))
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Fatalf("xref entry %d points to %q", i+1, out[offset:offset+10])
+		}
+	}
+}
+
+func TestPDFEscape(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in, want string
+	}{
+		{"Año", `A\361o`},
+		{`(a\b)`, `\(a\\b\)`},
+		{"Nota – 8 €", `Nota \226 8 \200`},
+		{"日本", "??"},
+	}
+	for _, tt := range tests {
+		if got := pdfEscape(tt.in); got != tt.want {
+			t.Fatalf("pdfEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+)
+
+const (
+	AuditCheckMandatorySubjects = "mandatory_subjects"
+	AuditCheckElectiveRules     = "elective_rules"
+	AuditCheckNoFinalsPending   = "no_finals_pending"
+	AuditCheckRequirements      = "requirements"
+)
+
+// AuditItem es un elemento pendiente dentro de un chequeo del egreso.
+type AuditItem struct {
+	ID        string               `json:"id"`
+	Name      string               `json:"name"`
+	Status    models.SubjectStatus `json:"status,omitempty"`
+	Remaining float64              `json:"remaining,omitempty"`
+}
+
+type AuditCheck struct {
+	Key       string      `json:"key"`
+	Satisfied bool        `json:"satisfied"`
+	Unmet     []AuditItem `json:"unmet"`
+}
+
+type GraduationAudit struct {
+	ProgramID   string       `json:"program_id"`
+	UserID      string       `json:"user_id"`
+	CanGraduate bool         `json:"can_graduate"`
+	Checks      []AuditCheck `json:"checks"`
+}
+
+// AuditUserProgram arma el reporte "¿puedo recibirme?" de un usuario en un programa.
+func AuditUserProgram(userID string, programID string) (*GraduationAudit, error) {
+	state, err := LoadUserProgramState(userID, programID)
+	if err != nil {
+		return nil, err
+	}
+	electives, err := LoadProgramElectives(programID, state.PlanVersionID)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := BuildGraduationAudit(state.Subjects, state.StatusBySubject(), state.Eligibility(), electives)
+	audit.ProgramID = programID
+	audit.UserID = userID
+	return &audit, nil
+}
+
+// BuildGraduationAudit exige todas las obligatorias aprobadas, todas las reglas de electivas
+// cumplidas (sin importar el rango de años), ningún final pendiente y que las aprobadas cumplan
+// sus correlativas y grupos de requisitos según eligibility.
+func BuildGraduationAudit(subjects []models.Subject, statuses map[string]models.SubjectStatus, eligibility map[string]SubjectEligibility, electives *ProgramElectives) GraduationAudit {
+	mandatory := AuditCheck{Key: AuditCheckMandatorySubjects, Unmet: make([]AuditItem, 0)}
+	finals := AuditCheck{Key: AuditCheckNoFinalsPending, Unmet: make([]AuditItem, 0)}
+	requirements := AuditCheck{Key: AuditCheckRequirements, Unmet: make([]AuditItem, 0)}
+	for _, subject := range subjects {
+		status, ok := statuses[subject.ID]
+		if !ok {
+			status = models.StatusAvailable
+		}
+		if status == models.StatusFinalPending {
+			finals.Unmet = append(finals.Unmet, AuditItem{ID: subject.ID, Name: subject.Name, Status: status})
+		}
+		if !subject.IsElective && !IsPassed(status) {
+			mandatory.Unmet = append(mandatory.Unmet, AuditItem{ID: subject.ID, Name: subject.Name, Status: status})
+		}
+		if e, ok := eligibility[subject.ID]; ok && IsPassed(status) && (len(e.UnmetForFinal) > 0 || len(e.UnmetGroupsFinal) > 0) {
+			requirements.Unmet = append(requirements.Unmet, AuditItem{ID: subject.ID, Name: subject.Name, Status: status})
+		}
+	}
+
+	rules := AuditCheck{Key: AuditCheckElectiveRules, Unmet: make([]AuditItem, 0)}
+	if electives != nil {
+		for _, rule := range EvaluateElectiveRules(electives, subjects, statuses, 0) {
+			if !rule.Satisfied {
+				rules.Unmet = append(rules.Unmet, AuditItem{ID: rule.RuleID, Name: rule.PoolName, Remaining: rule.Remaining})
+			}
+		}
+	}
+
+	checks := []AuditCheck{mandatory, rules, finals, requirements}
+	canGraduate := true
+	for i := range checks {
+		checks[i].Satisfied = len(checks[i].Unmet) == 0
+		canGraduate = canGraduate && checks[i].Satisfied
+	}
+
+	return GraduationAudit{CanGraduate: canGraduate, Checks: checks}
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"testing"
+)
+
+func TestBuildGraduationAudit(t *testing.T) {
+	t.Parallel()
+
+	subjects := []models.Subject{
+		{ID: "m1", Name: "Análisis I"},
+		{ID: "m2", Name: "Física I"},
+		{ID: "e1", Name: "Electiva", Credits: 4, IsElective: true},
+	}
+	// m2 pide aprobar alguna entre m1 y e1.
+	groups := []models.RequirementGroup{{ID: "g", SubjectID: "m2", Kind: models.GroupAny, Subjects: groupSubjects("m1", "e1")}}
+	electives := &ProgramElectives{
+		Rules:        []models.ElectiveRule{{ID: "r1", PoolID: "p1", AppliesFromYear: 1, RequirementType: models.RequirementCredits, MinimumValue: 4}},
+		PoolSubjects: []models.ElectivePoolSubject{{ElectivePoolID: "p1", SubjectID: "e1"}},
+	}
+
+	tests := []struct {
+		name      string
+		statuses  map[string]models.SubjectStatus
+		want      bool
+		wantUnmet map[string]int
+	}{
+		{
+			name:      "todo cumplido",
+			statuses:  map[string]models.SubjectStatus{"m1": models.StatusPassed, "m2": models.StatusPassedWithDist, "e1": models.StatusPassed},
+			want:      true,
+			wantUnmet: map[string]int{AuditCheckMandatorySubjects: 0, AuditCheckElectiveRules: 0, AuditCheckNoFinalsPending: 0},
+		},
+		{
+			name:      "final pendiente y electiva sin aprobar",
+			statuses:  map[string]models.SubjectStatus{"m1": models.StatusPassed, "m2": models.StatusFinalPending, "e1": models.StatusFinalPending},
+			wantUnmet: map[string]int{AuditCheckMandatorySubjects: 1, AuditCheckElectiveRules: 1, AuditCheckNoFinalsPending: 2},
+		},
+		{
+			name:      "aprobada sin cumplir su grupo",
+			statuses:  map[string]models.SubjectStatus{"m1": models.StatusFinalPending, "m2": models.StatusPassed, "e1": models.StatusFinalPending},
+			wantUnmet: map[string]int{AuditCheckMandatorySubjects: 1, AuditCheckElectiveRules: 1, AuditCheckNoFinalsPending: 2, AuditCheckRequirements: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			eligibility := EvaluateEligibilityWithGroups(subjects, nil, groups, tt.statuses)
+			got := BuildGraduationAudit(subjects, tt.statuses, eligibility, electives)
+			if got.CanGraduate != tt.want {
+				t.Fatalf("CanGraduate = %v, want %v", got.CanGraduate, tt.want)
+			}
+			for _, check := range got.Checks {
+				if len(check.Unmet) != tt.wantUnmet[check.Key] {
+					t.Fatalf("check %q unmet = %+v, want %d items", check.Key, check.Unmet, tt.wantUnmet[check.Key])
+				}
+			}
+		})
+	}
+}
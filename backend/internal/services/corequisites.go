@@ -0,0 +1,148 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"errors"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+var ErrCorequisiteConflict = errors.New("corequisite conflicts with a prerequisite")
+
+// UnmetCorequisite es un co-requisito que todavía no se está cursando. CanEnroll indica si se
+// puede anotar a la vez, que es lo que permite cursar la materia que lo pide.
+type UnmetCorequisite struct {
+	ID            string               `json:"id"`
+	CurrentStatus models.SubjectStatus `json:"currentStatus"`
+	CanEnroll     bool                 `json:"canEnroll"`
+}
+
+// SatisfiesCorequisite indica si la materia ya se cursa o se cursó.
+func SatisfiesCorequisite(status models.SubjectStatus) bool {
+	return status == models.StatusInProgress || status == models.StatusFinalPending || IsPassed(status)
+}
+
+func LoadCorequisites(tx *gorm.DB, subjectIDs []string) ([]models.SubjectCorequisite, error) {
+	if len(subjectIDs) == 0 {
+		return nil, nil
+	}
+	var corequisites []models.SubjectCorequisite
+	if err := tx.Where("subject_id IN ?", subjectIDs).Find(&corequisites).Error; err != nil {
+		return nil, err
+	}
+	return corequisites, nil
+}
+
+// ApplyCorequisites completa la elegibilidad con los co-requisitos. Una materia se puede cursar
+// si cada co-requisito ya se cursa o, si no, se puede cursar al mismo tiempo (sus correlativas
+// se cumplen). Los co-requisitos no afectan al final.
+func ApplyCorequisites(eligibility map[string]SubjectEligibility, corequisites []models.SubjectCorequisite, statuses map[string]models.SubjectStatus) {
+	base := make(map[string]bool, len(eligibility))
+	for id, e := range eligibility {
+		base[id] = e.CanEnroll
+	}
+
+	for _, co := range corequisites {
+		e, ok := eligibility[co.SubjectID]
+		if !ok {
+			continue
+		}
+		current, ok := statuses[co.CorequisiteID]
+		if !ok {
+			current = models.StatusAvailable
+		}
+		if SatisfiesCorequisite(current) {
+			continue
+		}
+		canEnroll := base[co.CorequisiteID]
+		e.UnmetCorequisites = append(e.UnmetCorequisites, UnmetCorequisite{ID: co.CorequisiteID, CurrentStatus: current, CanEnroll: canEnroll})
+		if !canEnroll {
+			e.CanEnroll = false
+		}
+		eligibility[co.SubjectID] = e
+	}
+}
+
+// ValidateCorequisites revisa que toda materia que se cursa o se cursó tenga sus co-requisitos
+// al menos en curso.
+func ValidateCorequisites(corequisites []models.SubjectCorequisite, statuses map[string]models.SubjectStatus) []RequirementViolation {
+	violations := make([]RequirementViolation, 0)
+	for _, co := range corequisites {
+		status, ok := statuses[co.SubjectID]
+		if !ok || !SatisfiesCorequisite(status) {
+			continue
+		}
+		current, ok := statuses[co.CorequisiteID]
+		if !ok {
+			current = models.StatusAvailable
+		}
+		if SatisfiesCorequisite(current) {
+			continue
+		}
+		violations = append(violations, RequirementViolation{
+			SubjectID:      co.SubjectID,
+			Status:         status,
+			RequirementID:  co.CorequisiteID,
+			RequiredStatus: models.ReqInProgress,
+			CurrentStatus:  current,
+		})
+	}
+	return violations
+}
+
+// FindCorequisiteConflict busca un co-requisito entre materias donde una es correlativa
+// (directa o indirecta) de la otra: no se podrían cursar a la vez.
+func FindCorequisiteConflict(subjectIDs []string, requirements []models.SubjectRequirement, corequisites []models.SubjectCorequisite) (models.SubjectCorequisite, bool) {
+	graph := NewRequirementGraph(subjectIDs, requirements)
+	sorted := append([]models.SubjectCorequisite(nil), corequisites...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].SubjectID != sorted[j].SubjectID {
+			return sorted[i].SubjectID < sorted[j].SubjectID
+		}
+		return sorted[i].CorequisiteID < sorted[j].CorequisiteID
+	})
+	for _, co := range sorted {
+		if co.SubjectID == co.CorequisiteID {
+			return co, true
+		}
+		for _, pair := range [][2]string{{co.SubjectID, co.CorequisiteID}, {co.CorequisiteID, co.SubjectID}} {
+			for _, dependent := range graph.TransitiveDependents(pair[0]) {
+				if dependent == pair[1] {
+					return co, true
+				}
+			}
+		}
+	}
+	return models.SubjectCorequisite{}, false
+}
+
+// CheckCorequisiteConflicts carga el plan de la materia con tx y devuelve ErrCorequisiteConflict
+// si algún co-requisito choca con las correlativas, contando las materias de los grupos de requisitos.
+func CheckCorequisiteConflicts(tx *gorm.DB, programID string, versionID *string) error {
+	var subjectIDs []string
+	if err := tx.Model(&models.Subject{}).Where("degree_program_id = ?", programID).
+		Scopes(ScopePlanVersion("plan_version_id", versionID)).Pluck("id", &subjectIDs).Error; err != nil {
+		return err
+	}
+	if len(subjectIDs) == 0 {
+		return nil
+	}
+	corequisites, err := LoadCorequisites(tx, subjectIDs)
+	if err != nil || len(corequisites) == 0 {
+		return err
+	}
+	var requirements []models.SubjectRequirement
+	if err := tx.Where("subject_id IN ?", subjectIDs).Find(&requirements).Error; err != nil {
+		return err
+	}
+	var groups []models.RequirementGroup
+	if err := tx.Preload("Subjects").Where("subject_id IN ?", subjectIDs).Find(&groups).Error; err != nil {
+		return err
+	}
+	requirements = append(requirements, GroupRequirementEdges(BuildGroupTree(groups))...)
+	if _, conflict := FindCorequisiteConflict(subjectIDs, requirements, corequisites); conflict {
+		return ErrCorequisiteConflict
+	}
+	return nil
+}
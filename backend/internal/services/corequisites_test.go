@@ -0,0 +1,80 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"testing"
+)
+
+func TestApplyCorequisites(t *testing.T) {
+	t.Parallel()
+
+	subjects := []models.Subject{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}
+	requirements := []models.SubjectRequirement{{SubjectID: "d", RequirementID: "a", MinStatus: models.ReqPassed}}
+	corequisites := []models.SubjectCorequisite{
+		{SubjectID: "b", CorequisiteID: "c"},
+		{SubjectID: "c", CorequisiteID: "b"},
+		{SubjectID: "a", CorequisiteID: "d"},
+	}
+	statuses := map[string]models.SubjectStatus{"c": models.StatusInProgress}
+
+	eligibility := EvaluateEligibility(subjects, requirements, statuses)
+	ApplyCorequisites(eligibility, corequisites, statuses)
+
+	if e := eligibility["b"]; !e.CanEnroll || len(e.UnmetCorequisites) != 0 {
+		t.Fatalf("b = %+v, corequisite c is in progress", e)
+	}
+	if e := eligibility["c"]; !e.CanEnroll || len(e.UnmetCorequisites) != 1 || !e.UnmetCorequisites[0].CanEnroll {
+		t.Fatalf("c = %+v, want enrollable together with b", e)
+	}
+	if e := eligibility["a"]; e.CanEnroll || len(e.UnmetCorequisites) != 1 || e.UnmetCorequisites[0].ID != "d" {
+		t.Fatalf("a = %+v, corequisite d cannot be taken yet", e)
+	}
+}
+
+func TestValidateCorequisites(t *testing.T) {
+	t.Parallel()
+
+	corequisites := []models.SubjectCorequisite{{SubjectID: "a", CorequisiteID: "b"}, {SubjectID: "c", CorequisiteID: "d"}}
+	statuses := map[string]models.SubjectStatus{
+		"a": models.StatusInProgress,
+		"c": models.StatusInProgress,
+		"d": models.StatusPassed,
+	}
+
+	violations := ValidateCorequisites(corequisites, statuses)
+	if len(violations) != 1 || violations[0].SubjectID != "a" || violations[0].RequirementID != "b" || violations[0].RequiredStatus != models.ReqInProgress {
+		t.Fatalf("violations = %+v, want a missing b", violations)
+	}
+}
+
+func TestFindCorequisiteConflict(t *testing.T) {
+	t.Parallel()
+
+	ids := []string{"a", "b", "c"}
+	tests := []struct {
+		name         string
+		requirements []models.SubjectRequirement
+		corequisites []models.SubjectCorequisite
+		want         bool
+	}{
+		{name: "mutual corequisites", corequisites: []models.SubjectCorequisite{{SubjectID: "a", CorequisiteID: "b"}, {SubjectID: "b", CorequisiteID: "a"}}},
+		{name: "direct prerequisite", requirements: edges("b", "a"), corequisites: []models.SubjectCorequisite{{SubjectID: "b", CorequisiteID: "a"}}, want: true},
+		{name: "transitive prerequisite", requirements: edges("b", "a", "c", "b"), corequisites: []models.SubjectCorequisite{{SubjectID: "a", CorequisiteID: "c"}}, want: true},
+		{name: "unrelated prerequisite", requirements: edges("c", "a"), corequisites: []models.SubjectCorequisite{{SubjectID: "a", CorequisiteID: "b"}}},
+		{name: "self", corequisites: []models.SubjectCorequisite{{SubjectID: "a", CorequisiteID: "a"}}, want: true},
+		{
+			name:         "group names the corequisite",
+			requirements: GroupRequirementEdges([]models.RequirementGroup{{ID: "g", SubjectID: "b", Kind: models.GroupAny, Subjects: groupSubjects("a", "c")}}),
+			corequisites: []models.SubjectCorequisite{{SubjectID: "b", CorequisiteID: "a"}},
+			want:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, got := FindCorequisiteConflict(ids, tt.requirements, tt.corequisites); got != tt.want {
+				t.Fatalf("conflict = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
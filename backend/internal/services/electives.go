@@ -0,0 +1,125 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+)
+
+// ElectiveRuleStatus informa cuánto lleva acumulado el usuario para una regla de electivas.
+type ElectiveRuleStatus struct {
+	RuleID           string                         `json:"rule_id"`
+	PoolID           string                         `json:"pool_id"`
+	PoolName         string                         `json:"pool_name"`
+	RequirementType  models.ElectiveRequirementType `json:"requirement_type"`
+	AppliesFromYear  int                            `json:"applies_from_year"`
+	AppliesToYear    *int                           `json:"applies_to_year,omitempty"`
+	MinimumValue     float64                        `json:"minimum_value"`
+	Achieved         float64                        `json:"achieved"`
+	Remaining        float64                        `json:"remaining"`
+	Satisfied        bool                           `json:"satisfied"`
+	PassedSubjectIDs []string                       `json:"passed_subject_ids"`
+}
+
+type ProgramElectives struct {
+	Rules        []models.ElectiveRule
+	PoolSubjects []models.ElectivePoolSubject
+}
+
+// LoadProgramElectives trae las reglas y pools de una versión del plan (nil = plan base).
+func LoadProgramElectives(programID string, versionID *string) (*ProgramElectives, error) {
+	electives := &ProgramElectives{}
+
+	if err := db.Db.Where("degree_program_id = ?", programID).Scopes(ScopePlanVersion("plan_version_id", versionID)).Preload("Pool").Find(&electives.Rules).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Db.
+		Joins("JOIN elective_pools ON elective_pools.id = elective_pool_subjects.elective_pool_id").
+		Where("elective_pools.degree_program_id = ?", programID).
+		Scopes(ScopePlanVersion("elective_pools.plan_version_id", versionID)).
+		Find(&electives.PoolSubjects).Error; err != nil {
+		return nil, err
+	}
+
+	return electives, nil
+}
+
+// RuleAppliesToYear indica si el rango de años de la regla incluye el año dado.
+func RuleAppliesToYear(rule models.ElectiveRule, year int) bool {
+	if year < rule.AppliesFromYear {
+		return false
+	}
+	return rule.AppliesToYear == nil || year <= *rule.AppliesToYear
+}
+
+// CurrentPlanYear estima en qué año del plan está el usuario: el año más alto con alguna
+// materia cursada, regularizada o aprobada. Sin actividad se considera primer año.
+func CurrentPlanYear(subjects []models.Subject, statuses map[string]models.SubjectStatus) int {
+	year := 1
+	for _, subject := range subjects {
+		if subject.Year == nil || *subject.Year <= year {
+			continue
+		}
+		if status, ok := statuses[subject.ID]; ok && status != models.StatusAvailable {
+			year = *subject.Year
+		}
+	}
+	return year
+}
+
+// EvaluateElectiveRules suma, para cada regla, las materias aprobadas de su pool.
+// Si onlyYear es mayor que 0 se descartan las reglas cuyo rango no incluye ese año.
+func EvaluateElectiveRules(electives *ProgramElectives, subjects []models.Subject, statuses map[string]models.SubjectStatus, onlyYear int) []ElectiveRuleStatus {
+	subjectsByID := make(map[string]models.Subject, len(subjects))
+	for _, s := range subjects {
+		subjectsByID[s.ID] = s
+	}
+	subjectsByPool := make(map[string][]string)
+	for _, ps := range electives.PoolSubjects {
+		subjectsByPool[ps.ElectivePoolID] = append(subjectsByPool[ps.ElectivePoolID], ps.SubjectID)
+	}
+
+	result := make([]ElectiveRuleStatus, 0, len(electives.Rules))
+	for _, rule := range electives.Rules {
+		if onlyYear > 0 && !RuleAppliesToYear(rule, onlyYear) {
+			continue
+		}
+
+		achieved := 0.0
+		passedIDs := make([]string, 0)
+		for _, subjectID := range subjectsByPool[rule.PoolID] {
+			subject, ok := subjectsByID[subjectID]
+			if !ok || !IsPassed(statuses[subjectID]) {
+				continue
+			}
+			passedIDs = append(passedIDs, subjectID)
+			switch rule.RequirementType {
+			case models.RequirementHours:
+				achieved += subject.Hours
+			case models.RequirementCredits:
+				achieved += subject.Credits
+			case models.RequirementSubjectCount:
+				achieved++
+			}
+		}
+
+		remaining := rule.MinimumValue - achieved
+		if remaining < 0 {
+			remaining = 0
+		}
+		result = append(result, ElectiveRuleStatus{
+			RuleID:           rule.ID,
+			PoolID:           rule.PoolID,
+			PoolName:         rule.Pool.Name,
+			RequirementType:  rule.RequirementType,
+			AppliesFromYear:  rule.AppliesFromYear,
+			AppliesToYear:    rule.AppliesToYear,
+			MinimumValue:     rule.MinimumValue,
+			Achieved:         achieved,
+			Remaining:        remaining,
+			Satisfied:        achieved >= rule.MinimumValue,
+			PassedSubjectIDs: passedIDs,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"testing"
+)
+
+func TestEvaluateElectiveRules(t *testing.T) {
+	t.Parallel()
+
+	subjects := []models.Subject{
+		{ID: "e1", Year: intPtr(3), Credits: 4, Hours: 64, IsElective: true},
+		{ID: "e2", Year: intPtr(3), Credits: 6, Hours: 96, IsElective: true},
+		{ID: "e3", Year: intPtr(4), Credits: 4, Hours: 64, IsElective: true},
+	}
+	electives := &ProgramElectives{
+		Rules: []models.ElectiveRule{
+			{ID: "credits", PoolID: "p1", AppliesFromYear: 3, AppliesToYear: intPtr(4), RequirementType: models.RequirementCredits, MinimumValue: 8},
+			{ID: "count", PoolID: "p1", AppliesFromYear: 3, RequirementType: models.RequirementSubjectCount, MinimumValue: 1},
+			{ID: "hours", PoolID: "p2", AppliesFromYear: 5, RequirementType: models.RequirementHours, MinimumValue: 64},
+		},
+		PoolSubjects: []models.ElectivePoolSubject{
+			{ElectivePoolID: "p1", SubjectID: "e1"},
+			{ElectivePoolID: "p1", SubjectID: "e2"},
+			{ElectivePoolID: "p2", SubjectID: "e3"},
+		},
+	}
+	statuses := map[string]models.SubjectStatus{
+		"e1": models.StatusPassed,
+		"e2": models.StatusFinalPending,
+		"e3": models.StatusPassed,
+	}
+
+	got := EvaluateElectiveRules(electives, subjects, statuses, 3)
+	if len(got) != 2 {
+		t.Fatalf("len(rules) = %d, want 2 (year 3 excludes the hours rule)", len(got))
+	}
+	if got[0].RuleID != "credits" || got[0].Achieved != 4 || got[0].Remaining != 4 || got[0].Satisfied {
+		t.Fatalf("credits rule = %+v, want 4 achieved / 4 remaining", got[0])
+	}
+	if got[1].RuleID != "count" || !got[1].Satisfied || got[1].Remaining != 0 {
+		t.Fatalf("count rule = %+v, want satisfied", got[1])
+	}
+
+	all := EvaluateElectiveRules(electives, subjects, statuses, 0)
+	if len(all) != 3 || !all[2].Satisfied {
+		t.Fatalf("all rules = %+v, want 3 rules with hours satisfied", all)
+	}
+}
+
+func TestCurrentPlanYear(t *testing.T) {
+	t.Parallel()
+
+	subjects := []models.Subject{{ID: "a", Year: intPtr(1)}, {ID: "b", Year: intPtr(2)}, {ID: "c", Year: intPtr(3)}}
+
+	if got := CurrentPlanYear(subjects, map[string]models.SubjectStatus{}); got != 1 {
+		t.Fatalf("CurrentPlanYear(sin actividad) = %d, want 1", got)
+	}
+	statuses := map[string]models.SubjectStatus{"b": models.StatusInProgress, "c": models.StatusAvailable}
+	if got := CurrentPlanYear(subjects, statuses); got != 2 {
+		t.Fatalf("CurrentPlanYear = %d, want 2", got)
+	}
+}
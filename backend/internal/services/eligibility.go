@@ -0,0 +1,256 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+)
+
+// UnmetRequirement describe una correlativa que todavía no alcanza el estado pedido.
+type UnmetRequirement struct {
+	ID            string                      `json:"id"`
+	MinStatus     models.RequirementMinStatus `json:"minStatus"`
+	CurrentStatus models.SubjectStatus        `json:"currentStatus"`
+}
+
+type SubjectEligibility struct {
+	SubjectID         string               `json:"subject_id"`
+	Status            models.SubjectStatus `json:"status"`
+	CanEnroll         bool                 `json:"can_enroll"`
+	CanTakeFinal      bool                 `json:"can_take_final"`
+	UnmetRequirements []UnmetRequirement   `json:"unmet_requirements"`
+	UnmetForFinal     []UnmetRequirement   `json:"unmet_for_final"`
+	UnmetGroups       []UnmetGroup         `json:"unmet_groups"`
+	UnmetGroupsFinal  []UnmetGroup         `json:"unmet_groups_for_final"`
+	UnmetCorequisites []UnmetCorequisite   `json:"unmet_corequisites"`
+}
+
+// UserProgramState agrupa todo lo necesario para evaluar correlativas de un usuario en un programa.
+type UserProgramState struct {
+	PlanVersionID *string
+	Subjects      []models.Subject
+	Requirements  []models.SubjectRequirement
+	Groups        []models.RequirementGroup
+	Corequisites  []models.SubjectCorequisite
+	UserSubjects  []models.UserSubject
+	// Recognized indica, para las materias aprobadas por reconocimiento, la materia de otro programa de la que vienen.
+	Recognized map[string]string
+}
+
+// LoadProgramSubjects trae las materias de una versión del plan (nil = plan base) junto con sus correlativas.
+func LoadProgramSubjects(programID string, versionID *string) ([]models.Subject, []models.SubjectRequirement, error) {
+	var subjects []models.Subject
+	if err := db.Db.Where("degree_program_id = ?", programID).Scopes(ScopePlanVersion("plan_version_id", versionID)).Find(&subjects).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(subjects) == 0 {
+		return subjects, nil, nil
+	}
+
+	subjectIDs := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		subjectIDs = append(subjectIDs, s.ID)
+	}
+	var requirements []models.SubjectRequirement
+	if err := db.Db.Where("subject_id IN ?", subjectIDs).Find(&requirements).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return subjects, requirements, nil
+}
+
+// LoadUserProgramState carga el plan en el que está inscripto el usuario y su avance.
+func LoadUserProgramState(userID string, programID string) (*UserProgramState, error) {
+	versionID, err := UserPlanVersionID(userID, programID)
+	if err != nil {
+		return nil, err
+	}
+	subjects, requirements, err := LoadProgramSubjects(programID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	state := &UserProgramState{PlanVersionID: versionID, Subjects: subjects, Requirements: requirements}
+	if len(state.Subjects) == 0 {
+		return state, nil
+	}
+
+	subjectIDs := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		subjectIDs = append(subjectIDs, s.ID)
+	}
+	if state.Groups, err = LoadRequirementGroups(subjectIDs); err != nil {
+		return nil, err
+	}
+	if state.Corequisites, err = LoadCorequisites(db.Db, subjectIDs); err != nil {
+		return nil, err
+	}
+
+	userSubjects, err := GetAllUserSubjects(userID, programID)
+	if err != nil {
+		return nil, err
+	}
+	links, linkedProgress, err := loadRecognizedProgress(userID, subjects)
+	if err != nil {
+		return nil, err
+	}
+	state.UserSubjects, state.Recognized = ApplyRecognitions(subjects, userSubjects, links, linkedProgress)
+
+	return state, nil
+}
+
+// StatusBySubject devuelve el estado de cada materia del programa; las que no tienen fila quedan como available.
+func (s *UserProgramState) StatusBySubject() map[string]models.SubjectStatus {
+	statuses := make(map[string]models.SubjectStatus, len(s.Subjects))
+	for _, subject := range s.Subjects {
+		statuses[subject.ID] = models.StatusAvailable
+	}
+	for _, us := range s.UserSubjects {
+		statuses[us.SubjectID] = us.Status
+	}
+	return statuses
+}
+
+func (s *UserProgramState) Eligibility() map[string]SubjectEligibility {
+	statuses := s.StatusBySubject()
+	eligibility := EvaluateEligibilityWithGroups(s.Subjects, s.Requirements, s.Groups, statuses)
+	ApplyCorequisites(eligibility, s.Corequisites, statuses)
+	return eligibility
+}
+
+// IsPassed indica si el estado cuenta como materia aprobada.
+func IsPassed(status models.SubjectStatus) bool {
+	return status == models.StatusPassed || status == models.StatusPassedWithDist
+}
+
+// SatisfiesMinStatus compara el estado de una correlativa contra el mínimo exigido.
+// final_pending equivale a "regularizada": la aprobada también la cumple.
+func SatisfiesMinStatus(status models.SubjectStatus, min models.RequirementMinStatus) bool {
+	switch min {
+	case models.ReqFinalPending:
+		return status == models.StatusFinalPending || IsPassed(status)
+	default:
+		return IsPassed(status)
+	}
+}
+
+// EvaluateEligibility calcula, para cada materia, si se puede cursar y rendir el final.
+// Para cursar se exige el MinStatus de cada correlativa; para rendir el final la materia
+// tiene que estar regularizada y todas sus correlativas aprobadas.
+func EvaluateEligibility(subjects []models.Subject, requirements []models.SubjectRequirement, statuses map[string]models.SubjectStatus) map[string]SubjectEligibility {
+	return EvaluateEligibilityWithGroups(subjects, requirements, nil, statuses)
+}
+
+// EvaluateEligibilityWithGroups suma a las correlativas simples los grupos de requisitos de
+// cada materia (groups son los grupos raíz); todos tienen que cumplirse.
+func EvaluateEligibilityWithGroups(subjects []models.Subject, requirements []models.SubjectRequirement, groups []models.RequirementGroup, statuses map[string]models.SubjectStatus) map[string]SubjectEligibility {
+	groupsBySubject := GroupsBySubject(groups)
+	reqsBySubject := make(map[string][]models.SubjectRequirement, len(subjects))
+	for _, r := range requirements {
+		reqsBySubject[r.SubjectID] = append(reqsBySubject[r.SubjectID], r)
+	}
+
+	result := make(map[string]SubjectEligibility, len(subjects))
+	for _, subject := range subjects {
+		status, ok := statuses[subject.ID]
+		if !ok {
+			status = models.StatusAvailable
+		}
+
+		unmet := make([]UnmetRequirement, 0)
+		unmetForFinal := make([]UnmetRequirement, 0)
+		for _, req := range reqsBySubject[subject.ID] {
+			minStatus := req.MinStatus
+			if minStatus == "" {
+				minStatus = models.ReqPassed
+			}
+			current, ok := statuses[req.RequirementID]
+			if !ok {
+				current = models.StatusAvailable
+			}
+			if !SatisfiesMinStatus(current, minStatus) {
+				unmet = append(unmet, UnmetRequirement{
+					ID:            req.RequirementID,
+					MinStatus:     minStatus,
+					CurrentStatus: current,
+				})
+			}
+			if !IsPassed(current) {
+				unmetForFinal = append(unmetForFinal, UnmetRequirement{
+					ID:            req.RequirementID,
+					MinStatus:     models.ReqPassed,
+					CurrentStatus: current,
+				})
+			}
+		}
+
+		unmetGroups := make([]UnmetGroup, 0)
+		unmetGroupsFinal := make([]UnmetGroup, 0)
+		if subjectGroups := groupsBySubject[subject.ID]; len(subjectGroups) > 0 {
+			unmetGroups = EvaluateGroups(subjectGroups, subjects, statuses, false)
+			unmetGroupsFinal = EvaluateGroups(subjectGroups, subjects, statuses, true)
+		}
+
+		result[subject.ID] = SubjectEligibility{
+			SubjectID:         subject.ID,
+			Status:            status,
+			CanEnroll:         len(unmet) == 0 && len(unmetGroups) == 0,
+			CanTakeFinal:      status == models.StatusFinalPending && len(unmetForFinal) == 0 && len(unmetGroupsFinal) == 0,
+			UnmetRequirements: unmet,
+			UnmetForFinal:     unmetForFinal,
+			UnmetGroups:       unmetGroups,
+			UnmetGroupsFinal:  unmetGroupsFinal,
+			UnmetCorequisites: make([]UnmetCorequisite, 0),
+		}
+	}
+
+	return result
+}
+
+// RequirementViolation describe un estado guardado que no respeta una correlativa.
+type RequirementViolation struct {
+	SubjectID      string                      `json:"subject_id"`
+	Status         models.SubjectStatus        `json:"status"`
+	RequirementID  string                      `json:"requirement_id,omitempty"`
+	RequiredStatus models.RequirementMinStatus `json:"required_status,omitempty"`
+	CurrentStatus  models.SubjectStatus        `json:"current_status,omitempty"`
+	// Group es el grupo de requisitos que no se cumple; en ese caso no hay RequirementID.
+	Group *UnmetGroup `json:"group,omitempty"`
+}
+
+// ValidateStatuses revisa un conjunto completo de estados contra subject_requirements y los
+// grupos de requisitos (groups son los grupos raíz). Cursar o regularizar exige el MinStatus de
+// cada correlativa y los grupos cumplidos; aprobar exige todo aprobado.
+func ValidateStatuses(subjects []models.Subject, requirements []models.SubjectRequirement, groups []models.RequirementGroup, statuses map[string]models.SubjectStatus) []RequirementViolation {
+	eligibility := EvaluateEligibilityWithGroups(subjects, requirements, groups, statuses)
+
+	violations := make([]RequirementViolation, 0)
+	for _, subject := range subjects {
+		e := eligibility[subject.ID]
+		var unmet []UnmetRequirement
+		var unmetGroups []UnmetGroup
+		switch {
+		case IsPassed(e.Status):
+			unmet, unmetGroups = e.UnmetForFinal, e.UnmetGroupsFinal
+		case e.Status == models.StatusInProgress || e.Status == models.StatusFinalPending:
+			unmet, unmetGroups = e.UnmetRequirements, e.UnmetGroups
+		default:
+			continue
+		}
+		for _, u := range unmet {
+			violations = append(violations, RequirementViolation{
+				SubjectID:      subject.ID,
+				Status:         e.Status,
+				RequirementID:  u.ID,
+				RequiredStatus: u.MinStatus,
+				CurrentStatus:  u.CurrentStatus,
+			})
+		}
+		for i := range unmetGroups {
+			violations = append(violations, RequirementViolation{
+				SubjectID: subject.ID,
+				Status:    e.Status,
+				Group:     &unmetGroups[i],
+			})
+		}
+	}
+	return violations
+}
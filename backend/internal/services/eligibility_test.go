@@ -0,0 +1,203 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"testing"
+)
+
+func TestSatisfiesMinStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		status models.SubjectStatus
+		min    models.RequirementMinStatus
+		want   bool
+	}{
+		{"available no cumple aprobada", models.StatusAvailable, models.ReqPassed, false},
+		{"en curso no cumple regularizada", models.StatusInProgress, models.ReqFinalPending, false},
+		{"regularizada cumple regularizada", models.StatusFinalPending, models.ReqFinalPending, true},
+		{"regularizada no cumple aprobada", models.StatusFinalPending, models.ReqPassed, false},
+		{"aprobada cumple regularizada", models.StatusPassed, models.ReqFinalPending, true},
+		{"promocionada cumple aprobada", models.StatusPassedWithDist, models.ReqPassed, true},
+		{"min vacío se trata como aprobada", models.StatusFinalPending, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := SatisfiesMinStatus(tt.status, tt.min); got != tt.want {
+				t.Fatalf("SatisfiesMinStatus(%q, %q) = %v, want %v", tt.status, tt.min, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateEligibility(t *testing.T) {
+	t.Parallel()
+
+	subjects := []models.Subject{{ID: "am1"}, {ID: "am2"}, {ID: "fis1"}, {ID: "fis2"}}
+	requirements := []models.SubjectRequirement{
+		{SubjectID: "am2", RequirementID: "am1", MinStatus: models.ReqFinalPending},
+		{SubjectID: "fis2", RequirementID: "am1", MinStatus: models.ReqPassed},
+		{SubjectID: "fis2", RequirementID: "fis1", MinStatus: models.ReqFinalPending},
+	}
+
+	tests := []struct {
+		name          string
+		statuses      map[string]models.SubjectStatus
+		subject       string
+		wantEnroll    bool
+		wantFinal     bool
+		wantUnmet     []string
+		wantUnmetFin  []string
+		wantMissingAs map[string]models.RequirementMinStatus
+	}{
+		{
+			name:       "sin correlativas siempre se puede cursar",
+			statuses:   map[string]models.SubjectStatus{},
+			subject:    "am1",
+			wantEnroll: true,
+		},
+		{
+			name:          "correlativa sin cursar bloquea",
+			statuses:      map[string]models.SubjectStatus{},
+			subject:       "am2",
+			wantUnmet:     []string{"am1"},
+			wantUnmetFin:  []string{"am1"},
+			wantMissingAs: map[string]models.RequirementMinStatus{"am1": models.ReqFinalPending},
+		},
+		{
+			name:         "regularizada alcanza para cursar pero no para rendir",
+			statuses:     map[string]models.SubjectStatus{"am1": models.StatusFinalPending, "am2": models.StatusFinalPending},
+			subject:      "am2",
+			wantEnroll:   true,
+			wantUnmetFin: []string{"am1"},
+		},
+		{
+			name:       "todo aprobado habilita el final",
+			statuses:   map[string]models.SubjectStatus{"am1": models.StatusPassed, "am2": models.StatusFinalPending},
+			subject:    "am2",
+			wantEnroll: true,
+			wantFinal:  true,
+		},
+		{
+			name:       "no se rinde final si la materia no está regularizada",
+			statuses:   map[string]models.SubjectStatus{"am1": models.StatusPassed, "am2": models.StatusInProgress},
+			subject:    "am2",
+			wantEnroll: true,
+		},
+		{
+			name:          "mezcla de min status",
+			statuses:      map[string]models.SubjectStatus{"am1": models.StatusFinalPending, "fis1": models.StatusFinalPending},
+			subject:       "fis2",
+			wantUnmet:     []string{"am1"},
+			wantUnmetFin:  []string{"am1", "fis1"},
+			wantMissingAs: map[string]models.RequirementMinStatus{"am1": models.ReqPassed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := EvaluateEligibility(subjects, requirements, tt.statuses)[tt.subject]
+			if got.CanEnroll != tt.wantEnroll {
+				t.Fatalf("CanEnroll = %v, want %v", got.CanEnroll, tt.wantEnroll)
+			}
+			if got.CanTakeFinal != tt.wantFinal {
+				t.Fatalf("CanTakeFinal = %v, want %v", got.CanTakeFinal, tt.wantFinal)
+			}
+			requireUnmetIDs(t, got.UnmetRequirements, tt.wantUnmet)
+			requireUnmetIDs(t, got.UnmetForFinal, tt.wantUnmetFin)
+			for _, u := range got.UnmetRequirements {
+				if want, ok := tt.wantMissingAs[u.ID]; ok && u.MinStatus != want {
+					t.Fatalf("unmet %q MinStatus = %q, want %q", u.ID, u.MinStatus, want)
+				}
+			}
+		})
+	}
+}
+
+func requireUnmetIDs(t *testing.T, got []UnmetRequirement, want []string) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("unmet = %v, want ids %v", got, want)
+	}
+	for i := range want {
+		if got[i].ID != want[i] {
+			t.Fatalf("unmet[%d] = %q, want %q", i, got[i].ID, want[i])
+		}
+	}
+}
+
+func TestValidateStatuses(t *testing.T) {
+	t.Parallel()
+
+	subjects := []models.Subject{{ID: "am1"}, {ID: "am2"}, {ID: "am3"}}
+	requirements := []models.SubjectRequirement{
+		{SubjectID: "am2", RequirementID: "am1", MinStatus: models.ReqFinalPending},
+		{SubjectID: "am3", RequirementID: "am2", MinStatus: models.ReqPassed},
+	}
+
+	tests := []struct {
+		name     string
+		statuses map[string]models.SubjectStatus
+		want     []RequirementViolation
+	}{
+		{
+			name:     "estados consistentes",
+			statuses: map[string]models.SubjectStatus{"am1": models.StatusPassed, "am2": models.StatusInProgress},
+		},
+		{
+			name:     "cursar con correlativa regularizada es válido",
+			statuses: map[string]models.SubjectStatus{"am1": models.StatusFinalPending, "am2": models.StatusFinalPending},
+		},
+		{
+			name:     "aprobar exige correlativas aprobadas",
+			statuses: map[string]models.SubjectStatus{"am1": models.StatusFinalPending, "am2": models.StatusPassed},
+			want: []RequirementViolation{
+				{SubjectID: "am2", Status: models.StatusPassed, RequirementID: "am1", RequiredStatus: models.ReqPassed, CurrentStatus: models.StatusFinalPending},
+			},
+		},
+		{
+			name:     "cursar sin correlativa",
+			statuses: map[string]models.SubjectStatus{"am3": models.StatusInProgress},
+			want: []RequirementViolation{
+				{SubjectID: "am3", Status: models.StatusInProgress, RequirementID: "am2", RequiredStatus: models.ReqPassed, CurrentStatus: models.StatusAvailable},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := ValidateStatuses(subjects, requirements, nil, tt.statuses)
+			if len(got) != len(tt.want) {
+				t.Fatalf("violations = %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("violations[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateStatuses_Groups(t *testing.T) {
+	t.Parallel()
+
+	subjects := []models.Subject{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	groups := []models.RequirementGroup{{ID: "g", SubjectID: "c", Kind: models.GroupAny, Subjects: groupSubjects("a", "b")}}
+
+	if got := ValidateStatuses(subjects, nil, groups, map[string]models.SubjectStatus{"b": models.StatusPassed, "c": models.StatusPassed}); len(got) != 0 {
+		t.Fatalf("violations = %+v, want none", got)
+	}
+	got := ValidateStatuses(subjects, nil, groups, map[string]models.SubjectStatus{"a": models.StatusFinalPending, "c": models.StatusInProgress})
+	if len(got) != 1 || got[0].SubjectID != "c" || got[0].Group == nil || got[0].Group.GroupID != "g" {
+		t.Fatalf("violations = %+v, want group g unmet for c", got)
+	}
+}
@@ -0,0 +1,400 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrEquivalenceNotFound = errors.New("equivalence not found")
+	ErrInvalidEquivalence  = errors.New("invalid equivalence")
+	ErrSameMigrationTarget = errors.New("source and target plans are the same")
+)
+
+type MigrationAction string
+
+const (
+	MigrationCreate    MigrationAction = "create"
+	MigrationUpdate    MigrationAction = "update"
+	MigrationUnchanged MigrationAction = "unchanged"
+)
+
+// MigrationChange es una fila del diff: cómo queda una materia del plan destino.
+type MigrationChange struct {
+	SubjectID         string               `json:"subject_id"`
+	Name              string               `json:"name"`
+	EquivalenceID     string               `json:"equivalence_id"`
+	FromSubjectIDs    []string             `json:"from_subject_ids"`
+	CurrentStatus     models.SubjectStatus `json:"current_status"`
+	NewStatus         models.SubjectStatus `json:"new_status"`
+	FinalCalification float64              `json:"final_calification"`
+	RegularizedAt     *time.Time           `json:"regularized_at,omitempty"`
+	Action            MigrationAction      `json:"action"`
+}
+
+// MigrationSkipped es una equivalencia que no aplica porque faltan materias de origen.
+type MigrationSkipped struct {
+	EquivalenceID     string   `json:"equivalence_id"`
+	SubjectID         string   `json:"subject_id"`
+	MissingSubjectIDs []string `json:"missing_subject_ids"`
+}
+
+type MigrationPreview struct {
+	SourceProgramID     string             `json:"source_program_id"`
+	SourcePlanVersionID *string            `json:"source_plan_version_id"`
+	TargetProgramID     string             `json:"target_program_id"`
+	TargetPlanVersionID *string            `json:"target_plan_version_id"`
+	Changes             []MigrationChange  `json:"changes"`
+	Skipped             []MigrationSkipped `json:"skipped"`
+	// UnmappedSubjectIDs son materias con avance en el plan de origen que ninguna equivalencia usa.
+	UnmappedSubjectIDs []string `json:"unmapped_subject_ids"`
+}
+
+// ListEquivalences devuelve las equivalencias hacia un programa, opcionalmente filtradas por programa de origen.
+func ListEquivalences(programID string, sourceProgramID string) ([]models.SubjectEquivalence, error) {
+	tx := db.Db.Where("degree_program_id = ?", programID)
+	if sourceProgramID != "" {
+		tx = tx.Where("source_program_id = ?", sourceProgramID)
+	}
+	var equivalences []models.SubjectEquivalence
+	if err := tx.Preload("Sources").Order("created_at ASC").Find(&equivalences).Error; err != nil {
+		return nil, err
+	}
+	return equivalences, nil
+}
+
+func GetEquivalence(programID string, equivalenceID string) (*models.SubjectEquivalence, error) {
+	var equivalence models.SubjectEquivalence
+	if err := db.Db.Preload("Sources").Where("id = ? AND degree_program_id = ?", equivalenceID, programID).First(&equivalence).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEquivalenceNotFound
+		}
+		return nil, err
+	}
+	return &equivalence, nil
+}
+
+// SaveEquivalence crea o reemplaza una equivalencia. La materia destino tiene que ser del
+// programa y las de origen del programa de origen; ninguna puede repetirse ni ser la destino.
+func SaveEquivalence(equivalence *models.SubjectEquivalence, sourceSubjectIDs []string) error {
+	if len(sourceSubjectIDs) == 0 {
+		return ErrInvalidEquivalence
+	}
+	seen := make(map[string]struct{}, len(sourceSubjectIDs))
+	for _, id := range sourceSubjectIDs {
+		if _, dup := seen[id]; dup || id == equivalence.TargetSubjectID {
+			return ErrInvalidEquivalence
+		}
+		seen[id] = struct{}{}
+	}
+
+	var target int64
+	if err := db.Db.Model(&models.Subject{}).Where("id = ? AND degree_program_id = ?", equivalence.TargetSubjectID, equivalence.DegreeProgramID).Count(&target).Error; err != nil {
+		return err
+	}
+	var sources int64
+	if err := db.Db.Model(&models.Subject{}).Where("id IN ? AND degree_program_id = ?", sourceSubjectIDs, equivalence.SourceProgramID).Count(&sources).Error; err != nil {
+		return err
+	}
+	if target != 1 || int(sources) != len(sourceSubjectIDs) {
+		return ErrInvalidEquivalence
+	}
+
+	if equivalence.ID == "" {
+		equivalence.ID = uuid.NewString()
+	}
+	equivalence.Sources = make([]models.SubjectEquivalenceSource, 0, len(sourceSubjectIDs))
+	for _, id := range sourceSubjectIDs {
+		equivalence.Sources = append(equivalence.Sources, models.SubjectEquivalenceSource{EquivalenceID: equivalence.ID, SubjectID: id})
+	}
+
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Sources", "DegreeProgram", "TargetSubject").Save(equivalence).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("equivalence_id = ?", equivalence.ID).Delete(&models.SubjectEquivalenceSource{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Subject").Create(&equivalence.Sources).Error
+	})
+}
+
+func DeleteEquivalence(programID string, equivalenceID string) error {
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("equivalence_id IN (?)", tx.Model(&models.SubjectEquivalence{}).Select("id").Where("id = ? AND degree_program_id = ?", equivalenceID, programID)).
+			Delete(&models.SubjectEquivalenceSource{}).Error; err != nil {
+			return err
+		}
+		res := tx.Where("id = ? AND degree_program_id = ?", equivalenceID, programID).Delete(&models.SubjectEquivalence{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrEquivalenceNotFound
+		}
+		return nil
+	})
+}
+
+// PreviewMigration arma el diff para pasar al usuario de su plan actual en sourceProgramID al
+// plan destino (targetVersionID nil = plan base). No escribe nada.
+func PreviewMigration(userID string, sourceProgramID string, targetProgramID string, targetVersionID *string) (*MigrationPreview, error) {
+	sourceVersionID, err := UserPlanVersionID(userID, sourceProgramID)
+	if err != nil {
+		return nil, err
+	}
+	if sourceProgramID == targetProgramID && sameVersion(sourceVersionID, targetVersionID) {
+		return nil, ErrSameMigrationTarget
+	}
+	if targetVersionID != nil {
+		version, err := GetPlanVersion(targetProgramID, *targetVersionID)
+		if err != nil {
+			return nil, err
+		}
+		if version.Status != models.PlanVersionPublished {
+			return nil, ErrPlanVersionNotFound
+		}
+	}
+
+	sourceSubjects, _, err := LoadProgramSubjects(sourceProgramID, sourceVersionID)
+	if err != nil {
+		return nil, err
+	}
+	targetSubjects, _, err := LoadProgramSubjects(targetProgramID, targetVersionID)
+	if err != nil {
+		return nil, err
+	}
+	equivalences, err := ListEquivalences(targetProgramID, sourceProgramID)
+	if err != nil {
+		return nil, err
+	}
+	sourceUserSubjects, err := GetAllUserSubjects(userID, sourceProgramID)
+	if err != nil {
+		return nil, err
+	}
+	targetUserSubjects := sourceUserSubjects
+	if targetProgramID != sourceProgramID {
+		if targetUserSubjects, err = GetAllUserSubjects(userID, targetProgramID); err != nil {
+			return nil, err
+		}
+	}
+
+	preview := BuildMigrationPreview(equivalences, sourceSubjects, sourceUserSubjects, targetSubjects, targetUserSubjects)
+	preview.SourceProgramID = sourceProgramID
+	preview.SourcePlanVersionID = sourceVersionID
+	preview.TargetProgramID = targetProgramID
+	preview.TargetPlanVersionID = targetVersionID
+	return &preview, nil
+}
+
+// ApplyMigration escribe las filas create/update del diff y deja al usuario en la versión destino,
+// todo en una transacción. Las materias del plan de origen no se tocan.
+func ApplyMigration(userID string, preview *MigrationPreview) error {
+	now := time.Now().UTC()
+	rows := make([]models.UserSubject, 0, len(preview.Changes))
+	for _, change := range preview.Changes {
+		if change.Action == MigrationUnchanged {
+			continue
+		}
+		rows = append(rows, models.UserSubject{
+			UserID:            userID,
+			SubjectID:         change.SubjectID,
+			Status:            change.NewStatus,
+			FinalCalification: change.FinalCalification,
+			RegularizedAt:     change.RegularizedAt,
+			UpdatedAt:         now,
+		})
+	}
+
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		if len(rows) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "subject_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"status", "final_calification", "regularized_at", "regularization_expired", "updated_at"}),
+			}).Omit("User", "Subject").Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+		if preview.TargetPlanVersionID == nil {
+			return tx.Where("user_id = ? AND degree_program_id = ?", userID, preview.TargetProgramID).Delete(&models.UserPlanVersion{}).Error
+		}
+		return tx.Save(&models.UserPlanVersion{UserID: userID, DegreeProgramID: preview.TargetProgramID, PlanVersionID: *preview.TargetPlanVersionID}).Error
+	})
+}
+
+// BuildMigrationPreview aplica las equivalencias al avance del usuario en el plan de origen:
+//
+//   - Si todas las materias de origen están aprobadas, la destino queda aprobada con el
+//     promedio de sus notas.
+//   - Si todas están al menos regularizadas, la destino queda con final pendiente y conserva
+//     la fecha de regularización más vieja.
+//   - Nunca se empeora el estado que el usuario ya tiene en el plan destino.
+//
+// Las equivalencias cuya materia destino no está en targetSubjects se ignoran.
+func BuildMigrationPreview(
+	equivalences []models.SubjectEquivalence,
+	sourceSubjects []models.Subject,
+	sourceUserSubjects []models.UserSubject,
+	targetSubjects []models.Subject,
+	targetUserSubjects []models.UserSubject,
+) MigrationPreview {
+	preview := MigrationPreview{
+		Changes:            make([]MigrationChange, 0),
+		Skipped:            make([]MigrationSkipped, 0),
+		UnmappedSubjectIDs: make([]string, 0),
+	}
+
+	sourceIDs := make(map[string]struct{}, len(sourceSubjects))
+	for _, s := range sourceSubjects {
+		sourceIDs[s.ID] = struct{}{}
+	}
+	sourceProgress := make(map[string]models.UserSubject, len(sourceUserSubjects))
+	for _, us := range sourceUserSubjects {
+		if _, ok := sourceIDs[us.SubjectID]; ok {
+			sourceProgress[us.SubjectID] = us
+		}
+	}
+	targetNames := make(map[string]string, len(targetSubjects))
+	for _, s := range targetSubjects {
+		targetNames[s.ID] = s.Name
+	}
+	targetProgress := make(map[string]models.UserSubject, len(targetUserSubjects))
+	for _, us := range targetUserSubjects {
+		targetProgress[us.SubjectID] = us
+	}
+
+	best := make(map[string]MigrationChange)
+	used := make(map[string]struct{})
+	for _, eq := range equivalences {
+		name, ok := targetNames[eq.TargetSubjectID]
+		if !ok {
+			continue
+		}
+		change, missing := applyEquivalence(eq, sourceProgress)
+		for _, src := range eq.Sources {
+			used[src.SubjectID] = struct{}{}
+		}
+		if len(missing) > 0 {
+			preview.Skipped = append(preview.Skipped, MigrationSkipped{EquivalenceID: eq.ID, SubjectID: eq.TargetSubjectID, MissingSubjectIDs: missing})
+			continue
+		}
+		change.Name = name
+		if prev, ok := best[change.SubjectID]; ok && !betterMigration(change, prev) {
+			continue
+		}
+		best[change.SubjectID] = change
+	}
+
+	for _, change := range best {
+		current, exists := targetProgress[change.SubjectID]
+		change.CurrentStatus = models.StatusAvailable
+		if exists {
+			change.CurrentStatus = current.Status
+		}
+		switch {
+		case exists && statusRank(current.Status) >= statusRank(change.NewStatus):
+			change.Action = MigrationUnchanged
+			change.NewStatus = current.Status
+			change.FinalCalification = current.FinalCalification
+			change.RegularizedAt = current.RegularizedAt
+		case exists:
+			change.Action = MigrationUpdate
+		default:
+			change.Action = MigrationCreate
+		}
+		preview.Changes = append(preview.Changes, change)
+	}
+	sort.Slice(preview.Changes, func(i, j int) bool {
+		if preview.Changes[i].Name != preview.Changes[j].Name {
+			return preview.Changes[i].Name < preview.Changes[j].Name
+		}
+		return preview.Changes[i].SubjectID < preview.Changes[j].SubjectID
+	})
+
+	for id, us := range sourceProgress {
+		if _, ok := used[id]; ok || statusRank(us.Status) < statusRank(models.StatusFinalPending) {
+			continue
+		}
+		preview.UnmappedSubjectIDs = append(preview.UnmappedSubjectIDs, id)
+	}
+	sort.Strings(preview.UnmappedSubjectIDs)
+
+	return preview
+}
+
+// applyEquivalence devuelve el resultado de la equivalencia o las materias de origen que no llegan a regularizadas.
+func applyEquivalence(eq models.SubjectEquivalence, progress map[string]models.UserSubject) (MigrationChange, []string) {
+	change := MigrationChange{
+		SubjectID:      eq.TargetSubjectID,
+		EquivalenceID:  eq.ID,
+		FromSubjectIDs: make([]string, 0, len(eq.Sources)),
+		NewStatus:      models.StatusPassed,
+	}
+	var missing []string
+	var gradeSum float64
+	for _, src := range eq.Sources {
+		change.FromSubjectIDs = append(change.FromSubjectIDs, src.SubjectID)
+		us, ok := progress[src.SubjectID]
+		if !ok || statusRank(us.Status) < statusRank(models.StatusFinalPending) {
+			missing = append(missing, src.SubjectID)
+			continue
+		}
+		if !IsPassed(us.Status) {
+			change.NewStatus = models.StatusFinalPending
+			if us.RegularizedAt != nil && (change.RegularizedAt == nil || us.RegularizedAt.Before(*change.RegularizedAt)) {
+				at := *us.RegularizedAt
+				change.RegularizedAt = &at
+			}
+		}
+		gradeSum += us.FinalCalification
+	}
+	sort.Strings(change.FromSubjectIDs)
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return change, missing
+	}
+
+	if change.NewStatus == models.StatusPassed {
+		change.FinalCalification = math.Round(gradeSum/float64(len(eq.Sources))*100) / 100
+		change.RegularizedAt = nil
+	}
+	return change, nil
+}
+
+func betterMigration(a, b MigrationChange) bool {
+	if statusRank(a.NewStatus) != statusRank(b.NewStatus) {
+		return statusRank(a.NewStatus) > statusRank(b.NewStatus)
+	}
+	if a.FinalCalification != b.FinalCalification {
+		return a.FinalCalification > b.FinalCalification
+	}
+	return a.EquivalenceID < b.EquivalenceID
+}
+
+func statusRank(status models.SubjectStatus) int {
+	switch status {
+	case models.StatusInProgress:
+		return 1
+	case models.StatusFinalPending:
+		return 2
+	case models.StatusPassed, models.StatusPassedWithDist:
+		return 3
+	default:
+		return 0
+	}
+}
+
+func sameVersion(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
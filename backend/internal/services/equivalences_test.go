@@ -0,0 +1,97 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func equivalence(id string, target string, sources ...string) models.SubjectEquivalence {
+	eq := models.SubjectEquivalence{ID: id, TargetSubjectID: target}
+	for _, s := range sources {
+		eq.Sources = append(eq.Sources, models.SubjectEquivalenceSource{EquivalenceID: id, SubjectID: s})
+	}
+	return eq
+}
+
+func TestBuildMigrationPreview(t *testing.T) {
+	t.Parallel()
+
+	regularized := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	sourceSubjects := []models.Subject{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}}
+	targetSubjects := []models.Subject{{ID: "x", Name: "X"}, {ID: "y", Name: "Y"}, {ID: "z", Name: "Z"}, {ID: "w", Name: "W"}}
+	sourceProgress := []models.UserSubject{
+		{SubjectID: "a", Status: models.StatusPassed, FinalCalification: 8},
+		{SubjectID: "b", Status: models.StatusPassedWithDist, FinalCalification: 9},
+		{SubjectID: "c", Status: models.StatusFinalPending, RegularizedAt: &regularized},
+		{SubjectID: "d", Status: models.StatusInProgress},
+		{SubjectID: "e", Status: models.StatusPassed, FinalCalification: 7},
+	}
+	targetProgress := []models.UserSubject{
+		{SubjectID: "w", Status: models.StatusPassed, FinalCalification: 10},
+	}
+	equivalences := []models.SubjectEquivalence{
+		equivalence("eq1", "x", "a", "b"),
+		equivalence("eq2", "y", "c"),
+		equivalence("eq3", "z", "d"),
+		equivalence("eq4", "w", "a"),
+		equivalence("eq5", "other-plan", "e"),
+	}
+
+	preview := BuildMigrationPreview(equivalences, sourceSubjects, sourceProgress, targetSubjects, targetProgress)
+
+	want := map[string]struct {
+		status models.SubjectStatus
+		grade  float64
+		action MigrationAction
+	}{
+		"x": {models.StatusPassed, 8.5, MigrationCreate},
+		"y": {models.StatusFinalPending, 0, MigrationCreate},
+		"w": {models.StatusPassed, 10, MigrationUnchanged},
+	}
+	if len(preview.Changes) != len(want) {
+		t.Fatalf("changes = %+v, want %d", preview.Changes, len(want))
+	}
+	for _, change := range preview.Changes {
+		w, ok := want[change.SubjectID]
+		if !ok {
+			t.Fatalf("unexpected change for %s", change.SubjectID)
+		}
+		if change.NewStatus != w.status || change.FinalCalification != w.grade || change.Action != w.action {
+			t.Fatalf("%s = %s/%v/%s, want %s/%v/%s", change.SubjectID, change.NewStatus, change.FinalCalification, change.Action, w.status, w.grade, w.action)
+		}
+	}
+	if y := preview.Changes[2]; y.SubjectID != "y" || y.RegularizedAt == nil || !y.RegularizedAt.Equal(regularized) {
+		t.Fatalf("final_pending change = %+v, want regularized_at %v", y, regularized)
+	}
+
+	if len(preview.Skipped) != 1 || preview.Skipped[0].EquivalenceID != "eq3" || !reflect.DeepEqual(preview.Skipped[0].MissingSubjectIDs, []string{"d"}) {
+		t.Fatalf("skipped = %+v, want eq3 missing d", preview.Skipped)
+	}
+	if !reflect.DeepEqual(preview.UnmappedSubjectIDs, []string{"e"}) {
+		t.Fatalf("unmapped = %v, want [e]", preview.UnmappedSubjectIDs)
+	}
+}
+
+func TestBuildMigrationPreview_PicksBestEquivalence(t *testing.T) {
+	t.Parallel()
+
+	sourceSubjects := []models.Subject{{ID: "a"}, {ID: "b"}}
+	targetSubjects := []models.Subject{{ID: "x", Name: "X"}}
+	sourceProgress := []models.UserSubject{
+		{SubjectID: "a", Status: models.StatusFinalPending},
+		{SubjectID: "b", Status: models.StatusPassed, FinalCalification: 6},
+	}
+	targetProgress := []models.UserSubject{{SubjectID: "x", Status: models.StatusInProgress}}
+	equivalences := []models.SubjectEquivalence{equivalence("eq1", "x", "a"), equivalence("eq2", "x", "b")}
+
+	preview := BuildMigrationPreview(equivalences, sourceSubjects, sourceProgress, targetSubjects, targetProgress)
+	if len(preview.Changes) != 1 {
+		t.Fatalf("changes = %+v, want 1", preview.Changes)
+	}
+	got := preview.Changes[0]
+	if got.EquivalenceID != "eq2" || got.NewStatus != models.StatusPassed || got.Action != MigrationUpdate || got.CurrentStatus != models.StatusInProgress {
+		t.Fatalf("change = %+v, want eq2 passed update from in_progress", got)
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrExamAttemptNotFound = errors.New("exam attempt not found")
+
+// ListExamAttempts devuelve los intentos de un usuario en una materia, del más viejo al más nuevo.
+func ListExamAttempts(userID string, subjectID string) ([]models.ExamAttempt, error) {
+	var attempts []models.ExamAttempt
+	if err := db.Db.Where("user_id = ? AND subject_id = ?", userID, subjectID).
+		Order("date ASC, created_at ASC").
+		Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// LoadProgramExamAttempts trae todos los intentos del usuario en materias del programa.
+func LoadProgramExamAttempts(userID string, programID string) ([]models.ExamAttempt, error) {
+	var attempts []models.ExamAttempt
+	if err := db.Db.Model(&models.ExamAttempt{}).
+		Joins("JOIN subjects ON subjects.id = exam_attempts.subject_id").
+		Where("exam_attempts.user_id = ? AND subjects.degree_program_id = ?", userID, programID).
+		Order("exam_attempts.date ASC, exam_attempts.created_at ASC").
+		Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// CreateExamAttempt guarda el intento y recalcula el estado de la materia en la misma transacción.
+func CreateExamAttempt(attempt *models.ExamAttempt) (*models.UserSubject, error) {
+	var result *models.UserSubject
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+		synced, err := syncUserSubjectFromAttempts(tx, attempt.UserID, attempt.SubjectID)
+		result = synced
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateExamAttempt aplica los cambios sobre un intento del usuario y recalcula el estado de la materia.
+func UpdateExamAttempt(userID string, subjectID string, attemptID string, apply func(*models.ExamAttempt)) (*models.ExamAttempt, *models.UserSubject, error) {
+	var attempt models.ExamAttempt
+	var result *models.UserSubject
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ? AND subject_id = ?", attemptID, userID, subjectID).First(&attempt).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrExamAttemptNotFound
+			}
+			return err
+		}
+		apply(&attempt)
+		if err := tx.Save(&attempt).Error; err != nil {
+			return err
+		}
+		synced, err := syncUserSubjectFromAttempts(tx, userID, subjectID)
+		result = synced
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &attempt, result, nil
+}
+
+func DeleteExamAttempt(userID string, subjectID string, attemptID string) (*models.UserSubject, error) {
+	var result *models.UserSubject
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND user_id = ? AND subject_id = ?", attemptID, userID, subjectID).Delete(&models.ExamAttempt{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrExamAttemptNotFound
+		}
+		synced, err := syncUserSubjectFromAttempts(tx, userID, subjectID)
+		result = synced
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func syncUserSubjectFromAttempts(tx *gorm.DB, userID string, subjectID string) (*models.UserSubject, error) {
+	var attempts []models.ExamAttempt
+	if err := tx.Where("user_id = ? AND subject_id = ?", userID, subjectID).Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+
+	current := models.UserSubject{UserID: userID, SubjectID: subjectID, Status: models.StatusAvailable}
+	var previous *models.UserSubject
+	var existing models.UserSubject
+	err := tx.Where("user_id = ? AND subject_id = ?", userID, subjectID).First(&existing).Error
+	if err == nil {
+		current = existing
+		previous = &existing
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	derived := DeriveSubjectResult(current, attempts)
+	TrackRegularization(previous, &derived, time.Now().UTC())
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "subject_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "final_calification", "regularized_at", "regularization_expired", "manual_status", "manual_calification", "updated_at"}),
+	}).Create(&derived).Error; err != nil {
+		return nil, err
+	}
+	return &derived, nil
+}
+
+// DeriveSubjectResult calcula el estado y la nota de una materia a partir de sus intentos.
+//
+//   - Con algún intento aprobado la materia queda aprobada con la nota del último aprobado
+//     (si ya estaba aprobada con distinción, se conserva).
+//   - Con intentos pero ninguno aprobado queda con final pendiente y sin nota, salvo que el
+//     usuario la haya cargado aprobada a mano: un aplazo no pisa esa aprobación.
+//   - Sin intentos se respeta lo que el usuario cargó a mano.
+//
+// Cuando el estado sale de los intentos, lo cargado a mano queda en ManualStatus y
+// ManualCalification, y se vuelve a eso si después los intentos dejan de definirlo.
+func DeriveSubjectResult(current models.UserSubject, attempts []models.ExamAttempt) models.UserSubject {
+	manual := current
+	if current.ManualStatus != "" {
+		manual.Status = current.ManualStatus
+		manual.FinalCalification = current.ManualCalification
+	}
+	manual.ManualStatus = ""
+	manual.ManualCalification = 0
+
+	sorted := append([]models.ExamAttempt(nil), attempts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].Date.Equal(sorted[j].Date) {
+			return sorted[i].Date.Before(sorted[j].Date)
+		}
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+	var lastPassed *models.ExamAttempt
+	for i := range sorted {
+		if sorted[i].Passed {
+			lastPassed = &sorted[i]
+		}
+	}
+	if len(attempts) == 0 || (lastPassed == nil && IsPassed(manual.Status)) {
+		return manual
+	}
+
+	result := manual
+	result.ManualStatus = manual.Status
+	result.ManualCalification = manual.FinalCalification
+	if lastPassed == nil {
+		result.Status = models.StatusFinalPending
+		result.FinalCalification = 0
+		return result
+	}
+	if manual.Status != models.StatusPassedWithDist {
+		result.Status = models.StatusPassed
+	}
+	result.FinalCalification = lastPassed.Grade
+	return result
+}
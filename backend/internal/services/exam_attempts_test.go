@@ -0,0 +1,111 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"testing"
+	"time"
+)
+
+func TestDeriveSubjectResult(t *testing.T) {
+	t.Parallel()
+
+	day := func(d int) time.Time { return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		current   models.UserSubject
+		attempts  []models.ExamAttempt
+		wantState models.SubjectStatus
+		wantGrade float64
+	}{
+		{
+			name:      "sin intentos respeta lo cargado",
+			current:   models.UserSubject{Status: models.StatusPassed, FinalCalification: 7},
+			wantState: models.StatusPassed,
+			wantGrade: 7,
+		},
+		{
+			name:    "solo desaprobados deja final pendiente",
+			current: models.UserSubject{Status: models.StatusInProgress},
+			attempts: []models.ExamAttempt{
+				{Date: day(1), Grade: 2},
+			},
+			wantState: models.StatusFinalPending,
+		},
+		{
+			name:    "un aplazo no pisa una aprobada a mano",
+			current: models.UserSubject{Status: models.StatusPassed, FinalCalification: 7},
+			attempts: []models.ExamAttempt{
+				{Date: day(1), Grade: 2},
+			},
+			wantState: models.StatusPassed,
+			wantGrade: 7,
+		},
+		{
+			name:      "sin intentos vuelve a lo cargado a mano",
+			current:   models.UserSubject{Status: models.StatusPassed, FinalCalification: 9, ManualStatus: models.StatusFinalPending},
+			wantState: models.StatusFinalPending,
+		},
+		{
+			name:    "toma la nota del último aprobado",
+			current: models.UserSubject{Status: models.StatusFinalPending},
+			attempts: []models.ExamAttempt{
+				{Date: day(20), Grade: 9, Passed: true, Type: models.ExamFinal},
+				{Date: day(1), Grade: 2},
+				{Date: day(10), Grade: 6, Passed: true, Type: models.ExamEquivalence},
+			},
+			wantState: models.StatusPassed,
+			wantGrade: 9,
+		},
+		{
+			name:    "conserva la distinción",
+			current: models.UserSubject{Status: models.StatusPassedWithDist},
+			attempts: []models.ExamAttempt{
+				{Date: day(1), Grade: 10, Passed: true, Type: models.ExamPromotion},
+			},
+			wantState: models.StatusPassedWithDist,
+			wantGrade: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := DeriveSubjectResult(tt.current, tt.attempts)
+			if got.Status != tt.wantState || got.FinalCalification != tt.wantGrade {
+				t.Fatalf("DeriveSubjectResult() = %s/%v, want %s/%v", got.Status, got.FinalCalification, tt.wantState, tt.wantGrade)
+			}
+		})
+	}
+}
+
+func TestDeriveSubjectResult_DeletingLastPassedAttemptReverts(t *testing.T) {
+	t.Parallel()
+
+	day := func(d int) time.Time { return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC) }
+	manual := models.UserSubject{Status: models.StatusFinalPending}
+	failed := models.ExamAttempt{Date: day(1), Grade: 2}
+	passed := models.ExamAttempt{Date: day(20), Grade: 8, Passed: true}
+
+	withPassed := DeriveSubjectResult(manual, []models.ExamAttempt{failed, passed})
+	if withPassed.Status != models.StatusPassed || withPassed.FinalCalification != 8 || withPassed.ManualStatus != models.StatusFinalPending {
+		t.Fatalf("with passed attempt = %+v", withPassed)
+	}
+
+	onlyFailed := DeriveSubjectResult(withPassed, []models.ExamAttempt{failed})
+	if onlyFailed.Status != models.StatusFinalPending || onlyFailed.FinalCalification != 0 {
+		t.Fatalf("after deleting the passed attempt = %+v, want final_pending without grade", onlyFailed)
+	}
+
+	none := DeriveSubjectResult(withPassed, nil)
+	if none.Status != models.StatusFinalPending || none.FinalCalification != 0 || none.ManualStatus != "" {
+		t.Fatalf("after deleting every attempt = %+v, want the manual final_pending back", none)
+	}
+
+	manualPassed := models.UserSubject{Status: models.StatusPassed, FinalCalification: 6}
+	derived := DeriveSubjectResult(manualPassed, []models.ExamAttempt{passed})
+	if reverted := DeriveSubjectResult(derived, nil); reverted.Status != models.StatusPassed || reverted.FinalCalification != 6 {
+		t.Fatalf("reverted = %+v, want the manual passed with 6", reverted)
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"sort"
+)
+
+// SubjectImpact resume cuánto del plan depende de una materia.
+type SubjectImpact struct {
+	ID                   string   `json:"id"`
+	Name                 string   `json:"name"`
+	Year                 *int     `json:"year,omitempty"`
+	DirectDependents     int      `json:"direct_dependents"`
+	TransitiveDependents int      `json:"transitive_dependents"`
+	UnlocksIDs           []string `json:"unlocks_ids"`
+	// ChainLength es la cadena de correlativas más larga que arranca en la materia (incluyéndola).
+	ChainLength int `json:"chain_length"`
+}
+
+type CriticalPathSubject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Year *int   `json:"year,omitempty"`
+}
+
+type GraphAnalysis struct {
+	ProgramID    string                `json:"program_id"`
+	CriticalPath []CriticalPathSubject `json:"critical_path"`
+	Subjects     []SubjectImpact       `json:"subjects"`
+}
+
+func AnalyzeProgramGraph(programID string, versionID *string) (*GraphAnalysis, error) {
+	subjects, requirements, err := LoadProgramSubjects(programID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	analysis, err := BuildGraphAnalysis(subjects, requirements)
+	if err != nil {
+		return nil, err
+	}
+	analysis.ProgramID = programID
+	return analysis, nil
+}
+
+// BuildGraphAnalysis calcula el camino crítico del programa y, para cada materia, cuántas
+// otras dependen de ella directa o indirectamente. Las materias se devuelven ordenadas de
+// mayor a menor impacto. Devuelve ErrRequirementCycle si las correlativas tienen un ciclo.
+func BuildGraphAnalysis(subjects []models.Subject, requirements []models.SubjectRequirement) (*GraphAnalysis, error) {
+	subjectsByID := make(map[string]models.Subject, len(subjects))
+	subjectIDs := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		subjectsByID[s.ID] = s
+		subjectIDs = append(subjectIDs, s.ID)
+	}
+
+	graph := NewRequirementGraph(subjectIDs, requirements)
+	heights, err := graph.Heights()
+	if err != nil {
+		return nil, err
+	}
+	chain, err := graph.LongestChain()
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &GraphAnalysis{
+		CriticalPath: make([]CriticalPathSubject, 0, len(chain)),
+		Subjects:     make([]SubjectImpact, 0, len(subjects)),
+	}
+	for _, id := range chain {
+		s := subjectsByID[id]
+		analysis.CriticalPath = append(analysis.CriticalPath, CriticalPathSubject{ID: s.ID, Name: s.Name, Year: s.Year})
+	}
+
+	for _, s := range subjects {
+		unlocks := graph.TransitiveDependents(s.ID)
+		analysis.Subjects = append(analysis.Subjects, SubjectImpact{
+			ID:                   s.ID,
+			Name:                 s.Name,
+			Year:                 s.Year,
+			DirectDependents:     len(graph.Dependents(s.ID)),
+			TransitiveDependents: len(unlocks),
+			UnlocksIDs:           unlocks,
+			ChainLength:          heights[s.ID],
+		})
+	}
+	sort.SliceStable(analysis.Subjects, func(i, j int) bool {
+		a, b := analysis.Subjects[i], analysis.Subjects[j]
+		if a.TransitiveDependents != b.TransitiveDependents {
+			return a.TransitiveDependents > b.TransitiveDependents
+		}
+		if a.ChainLength != b.ChainLength {
+			return a.ChainLength > b.ChainLength
+		}
+		return a.Name < b.Name
+	})
+
+	return analysis, nil
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"errors"
+	"testing"
+)
+
+func TestBuildGraphAnalysis(t *testing.T) {
+	t.Parallel()
+
+	subjects := []models.Subject{
+		{ID: "am1", Name: "Análisis I"},
+		{ID: "am2", Name: "Análisis II"},
+		{ID: "fis", Name: "Física"},
+		{ID: "am3", Name: "Análisis III"},
+		{ID: "ing", Name: "Inglés"},
+	}
+	analysis, err := BuildGraphAnalysis(subjects, edges("am2", "am1", "fis", "am1", "am3", "am2"))
+	if err != nil {
+		t.Fatalf("BuildGraphAnalysis() error = %v", err)
+	}
+
+	if len(analysis.CriticalPath) != 3 || analysis.CriticalPath[0].ID != "am1" || analysis.CriticalPath[2].ID != "am3" {
+		t.Fatalf("CriticalPath = %+v, want am1 -> am2 -> am3", analysis.CriticalPath)
+	}
+
+	first := analysis.Subjects[0]
+	if first.ID != "am1" || first.TransitiveDependents != 3 || first.DirectDependents != 2 || first.ChainLength != 3 {
+		t.Fatalf("Subjects[0] = %+v, want am1 unlocking 3 subjects", first)
+	}
+	last := analysis.Subjects[len(analysis.Subjects)-1]
+	if last.TransitiveDependents != 0 || len(last.UnlocksIDs) != 0 {
+		t.Fatalf("last subject = %+v, want no dependents", last)
+	}
+
+	if _, err := BuildGraphAnalysis(subjects[:2], edges("am2", "am1", "am1", "am2")); !errors.Is(err, ErrRequirementCycle) {
+		t.Fatalf("BuildGraphAnalysis() with cycle error = %v, want ErrRequirementCycle", err)
+	}
+}
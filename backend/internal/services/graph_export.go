@@ -0,0 +1,287 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type GraphNode struct {
+	ID         string               `json:"id"`
+	Name       string               `json:"name"`
+	Year       *int                 `json:"year,omitempty"`
+	Term       string               `json:"term"`
+	IsElective bool                 `json:"is_elective"`
+	Status     models.SubjectStatus `json:"status,omitempty"`
+}
+
+// GraphEdge va de la correlativa (From) a la materia que la exige (To).
+type GraphEdge struct {
+	From      string                      `json:"from"`
+	To        string                      `json:"to"`
+	MinStatus models.RequirementMinStatus `json:"min_status"`
+}
+
+type ProgramGraph struct {
+	ProgramID string      `json:"program_id"`
+	Name      string      `json:"name"`
+	Nodes     []GraphNode `json:"nodes"`
+	Edges     []GraphEdge `json:"edges"`
+}
+
+// BuildProgramGraph arma el grafo de correlativas ordenado por año y nombre. Si statuses no es
+// nil, cada nodo lleva el estado del usuario.
+func BuildProgramGraph(program models.DegreeProgram, subjects []models.Subject, requirements []models.SubjectRequirement, statuses map[string]models.SubjectStatus) ProgramGraph {
+	graph := ProgramGraph{
+		ProgramID: program.ID,
+		Name:      program.Name,
+		Nodes:     make([]GraphNode, 0, len(subjects)),
+		Edges:     make([]GraphEdge, 0, len(requirements)),
+	}
+
+	known := make(map[string]struct{}, len(subjects))
+	for _, s := range subjects {
+		known[s.ID] = struct{}{}
+		node := GraphNode{ID: s.ID, Name: s.Name, Year: s.Year, Term: s.Term, IsElective: s.IsElective}
+		if statuses != nil {
+			node.Status = models.StatusAvailable
+			if status, ok := statuses[s.ID]; ok {
+				node.Status = status
+			}
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	sort.SliceStable(graph.Nodes, func(i, j int) bool {
+		a, b := graph.Nodes[i], graph.Nodes[j]
+		if nodeYear(a) != nodeYear(b) {
+			return nodeYear(a) < nodeYear(b)
+		}
+		return a.Name < b.Name
+	})
+
+	for _, r := range requirements {
+		if _, ok := known[r.SubjectID]; !ok {
+			continue
+		}
+		if _, ok := known[r.RequirementID]; !ok {
+			continue
+		}
+		graph.Edges = append(graph.Edges, GraphEdge{From: r.RequirementID, To: r.SubjectID, MinStatus: r.MinStatus})
+	}
+	sort.SliceStable(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+
+	return graph
+}
+
+// nodeYear devuelve 0 para las materias sin año, que se agrupan aparte.
+func nodeYear(n GraphNode) int {
+	if n.Year == nil {
+		return 0
+	}
+	return *n.Year
+}
+
+func yearLabel(year int) string {
+	if year == 0 {
+		return "Sin año"
+	}
+	return fmt.Sprintf("Año %d", year)
+}
+
+// nodeGroups agrupa los nodos (ya ordenados) por año, respetando el orden.
+func nodeGroups(nodes []GraphNode) [][]GraphNode {
+	groups := make([][]GraphNode, 0)
+	for i, n := range nodes {
+		if i == 0 || nodeYear(n) != nodeYear(nodes[i-1]) {
+			groups = append(groups, make([]GraphNode, 0))
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], n)
+	}
+	return groups
+}
+
+var statusColors = map[models.SubjectStatus]string{
+	models.StatusAvailable:      "#ffffff",
+	models.StatusInProgress:     "#fff3b0",
+	models.StatusFinalPending:   "#ffd6a5",
+	models.StatusPassed:         "#caffbf",
+	models.StatusPassedWithDist: "#9bf6ff",
+}
+
+func dotQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// RenderDOT genera el grafo en formato Graphviz. Las correlativas que piden aprobada van con
+// línea continua y las que piden regularizada con línea punteada.
+func RenderDOT(graph ProgramGraph) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(graph.Name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=\"#ffffff\"];\n")
+
+	for _, group := range nodeGroups(graph.Nodes) {
+		year := nodeYear(group[0])
+		fmt.Fprintf(&b, "  subgraph cluster_year_%d {\n", year)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(yearLabel(year)))
+		for _, n := range group {
+			attrs := []string{"label=" + dotQuote(n.Name)}
+			if n.IsElective {
+				attrs = append(attrs, "peripheries=2")
+			}
+			if color, ok := statusColors[n.Status]; ok && n.Status != "" {
+				attrs = append(attrs, "fillcolor="+dotQuote(color))
+			}
+			fmt.Fprintf(&b, "    %s [%s];\n", dotQuote(n.ID), strings.Join(attrs, ", "))
+		}
+		b.WriteString("  }\n")
+	}
+
+	for _, e := range graph.Edges {
+		style := "solid"
+		if e.MinStatus == models.ReqFinalPending {
+			style = "dashed"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [style=%s];\n", dotQuote(e.From), dotQuote(e.To), style)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func mermaidLabel(value string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(value) + `"`
+}
+
+// RenderMermaid genera un flowchart de Mermaid. Los IDs de las materias se reemplazan por
+// identificadores cortos (n0, n1, ...) porque Mermaid no acepta guiones en todos los contextos.
+func RenderMermaid(graph ProgramGraph) string {
+	aliases := make(map[string]string, len(graph.Nodes))
+	for i, n := range graph.Nodes {
+		aliases[n.ID] = "n" + strconv.Itoa(i)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, group := range nodeGroups(graph.Nodes) {
+		year := nodeYear(group[0])
+		fmt.Fprintf(&b, "  subgraph year_%d[%s]\n", year, mermaidLabel(yearLabel(year)))
+		for _, n := range group {
+			fmt.Fprintf(&b, "    %s[%s]\n", aliases[n.ID], mermaidLabel(n.Name))
+		}
+		b.WriteString("  end\n")
+	}
+
+	for _, e := range graph.Edges {
+		arrow := "-->"
+		if e.MinStatus == models.ReqFinalPending {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s %s\n", aliases[e.From], arrow, aliases[e.To])
+	}
+
+	byStatus := make(map[models.SubjectStatus][]string)
+	for _, n := range graph.Nodes {
+		if n.Status != "" {
+			byStatus[n.Status] = append(byStatus[n.Status], aliases[n.ID])
+		}
+	}
+	statuses := make([]string, 0, len(byStatus))
+	for status := range byStatus {
+		statuses = append(statuses, string(status))
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		fmt.Fprintf(&b, "  classDef %s fill:%s\n", status, statusColors[models.SubjectStatus(status)])
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(byStatus[models.SubjectStatus(status)], ","), status)
+	}
+
+	return b.String()
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+// RenderGraphML genera el grafo en GraphML con el nombre, año, modalidad y estado de cada
+// materia y el estado mínimo de cada correlativa como atributos.
+func RenderGraphML(graph ProgramGraph) ([]byte, error) {
+	doc := graphMLDocument{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "name", For: "node", Name: "name", Type: "string"},
+			{ID: "year", For: "node", Name: "year", Type: "int"},
+			{ID: "term", For: "node", Name: "term", Type: "string"},
+			{ID: "elective", For: "node", Name: "is_elective", Type: "boolean"},
+			{ID: "status", For: "node", Name: "status", Type: "string"},
+			{ID: "min_status", For: "edge", Name: "min_status", Type: "string"},
+		},
+		Graph: graphMLGraph{ID: graph.ProgramID, EdgeDefault: "directed"},
+	}
+
+	for _, n := range graph.Nodes {
+		data := []graphMLData{
+			{Key: "name", Value: n.Name},
+			{Key: "year", Value: strconv.Itoa(nodeYear(n))},
+			{Key: "term", Value: n.Term},
+			{Key: "elective", Value: strconv.FormatBool(n.IsElective)},
+		}
+		if n.Status != "" {
+			data = append(data, graphMLData{Key: "status", Value: string(n.Status)})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: n.ID, Data: data})
+	}
+	for _, e := range graph.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: e.From,
+			Target: e.To,
+			Data:   []graphMLData{{Key: "min_status", Value: string(e.MinStatus)}},
+		})
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func sampleProgramGraph(statuses map[string]models.SubjectStatus) ProgramGraph {
+	subjects := []models.Subject{
+		{ID: "am2", Name: "Análisis II", Year: intPtr(2)},
+		{ID: "am1", Name: `Análisis "I"`, Year: intPtr(1)},
+		{ID: "fis", Name: "Física", Year: intPtr(2)},
+	}
+	requirements := []models.SubjectRequirement{
+		{SubjectID: "am2", RequirementID: "am1", MinStatus: models.ReqFinalPending},
+		{SubjectID: "fis", RequirementID: "am1", MinStatus: models.ReqPassed},
+		{SubjectID: "fis", RequirementID: "other-program", MinStatus: models.ReqPassed},
+	}
+	return BuildProgramGraph(models.DegreeProgram{ID: "p1", Name: "Ingeniería"}, subjects, requirements, statuses)
+}
+
+func TestBuildProgramGraph(t *testing.T) {
+	t.Parallel()
+
+	graph := sampleProgramGraph(nil)
+	if graph.Nodes[0].ID != "am1" {
+		t.Fatalf("Nodes[0] = %q, want am1 (year 1 first)", graph.Nodes[0].ID)
+	}
+	if len(graph.Edges) != 2 {
+		t.Fatalf("Edges = %+v, want 2 edges inside the program", graph.Edges)
+	}
+	if graph.Edges[0].From != "am1" || graph.Edges[0].To != "am2" {
+		t.Fatalf("Edges[0] = %+v, want am1 -> am2", graph.Edges[0])
+	}
+	if graph.Nodes[0].Status != "" {
+		t.Fatalf("Status = %q without overlay, want empty", graph.Nodes[0].Status)
+	}
+
+	overlay := sampleProgramGraph(map[string]models.SubjectStatus{"am1": models.StatusPassed})
+	if overlay.Nodes[0].Status != models.StatusPassed || overlay.Nodes[1].Status != models.StatusAvailable {
+		t.Fatalf("overlay statuses = %q, %q", overlay.Nodes[0].Status, overlay.Nodes[1].Status)
+	}
+}
+
+func TestRenderDOT(t *testing.T) {
+	t.Parallel()
+
+	out := RenderDOT(sampleProgramGraph(map[string]models.SubjectStatus{"am1": models.StatusPassed}))
+	for _, want := range []string{
+		`subgraph cluster_year_1 {`,
+		`label="Año 2";`,
+		`"am1" [label="Análisis \"I\"", fillcolor="#caffbf"];`,
+		`"am1" -> "am2" [style=dashed];`,
+		`"am1" -> "fis" [style=solid];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("RenderDOT() missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderMermaid(t *testing.T) {
+	t.Parallel()
+
+	out := RenderMermaid(sampleProgramGraph(map[string]models.SubjectStatus{"am1": models.StatusPassed}))
+	for _, want := range []string{
+		"flowchart LR",
+		`n0["Análisis #quot;I#quot;"]`,
+		"n0 -.-> n1",
+		"n0 --> n2",
+		"class n0 passed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("RenderMermaid() missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderGraphML(t *testing.T) {
+	t.Parallel()
+
+	out, err := RenderGraphML(sampleProgramGraph(nil))
+	if err != nil {
+		t.Fatalf("RenderGraphML() error = %v", err)
+	}
+	var doc graphMLDocument
+	if err := xml.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("RenderGraphML() produced invalid XML: %v", err)
+	}
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 2 {
+		t.Fatalf("GraphML has %d nodes and %d edges, want 3 and 2", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+}
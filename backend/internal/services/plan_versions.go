@@ -0,0 +1,299 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPlanVersionNotFound  = errors.New("plan version not found")
+	ErrPlanVersionPublished = errors.New("plan version is published and read-only")
+)
+
+// ScopePlanVersion filtra por versión del plan; nil selecciona el plan base (sin versión).
+func ScopePlanVersion(column string, versionID *string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if versionID == nil {
+			return tx.Where(column + " IS NULL")
+		}
+		return tx.Where(column+" = ?", *versionID)
+	}
+}
+
+func GetPlanVersion(programID string, versionID string) (*models.PlanVersion, error) {
+	var version models.PlanVersion
+	if err := db.Db.Where("id = ? AND degree_program_id = ?", versionID, programID).First(&version).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlanVersionNotFound
+		}
+		return nil, err
+	}
+	return &version, nil
+}
+
+// EnsurePlanVersionWritable devuelve ErrPlanVersionPublished si la versión ya fue publicada.
+// El plan base (nil) siempre es editable.
+func EnsurePlanVersionWritable(versionID *string) error {
+	if versionID == nil {
+		return nil
+	}
+	var version models.PlanVersion
+	if err := db.Db.Select("id", "status").Where("id = ?", *versionID).First(&version).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPlanVersionNotFound
+		}
+		return err
+	}
+	if version.Status == models.PlanVersionPublished {
+		return ErrPlanVersionPublished
+	}
+	return nil
+}
+
+// UserPlanVersionID devuelve la versión elegida por el usuario en el programa o nil si usa el plan base.
+func UserPlanVersionID(userID string, programID string) (*string, error) {
+	var row models.UserPlanVersion
+	err := db.Db.Where("user_id = ? AND degree_program_id = ?", userID, programID).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &row.PlanVersionID, nil
+}
+
+// SetUserPlanVersion inscribe al usuario en una versión publicada; versionID nil lo devuelve al plan base.
+func SetUserPlanVersion(userID string, programID string, versionID *string) error {
+	if versionID == nil {
+		return db.Db.Where("user_id = ? AND degree_program_id = ?", userID, programID).Delete(&models.UserPlanVersion{}).Error
+	}
+	version, err := GetPlanVersion(programID, *versionID)
+	if err != nil {
+		return err
+	}
+	if version.Status != models.PlanVersionPublished {
+		return ErrPlanVersionNotFound
+	}
+	return db.Db.Save(&models.UserPlanVersion{UserID: userID, DegreeProgramID: programID, PlanVersionID: version.ID}).Error
+}
+
+// CreatePlanVersion crea una versión en borrador. Con copyContents se copian las materias,
+// correlativas, pools y reglas de sourceID (o del plan base si sourceID es nil).
+func CreatePlanVersion(programID string, name string, copyContents bool, sourceID *string) (*models.PlanVersion, error) {
+	version := models.PlanVersion{
+		ID:              uuid.NewString(),
+		DegreeProgramID: programID,
+		Name:            name,
+		Status:          models.PlanVersionDraft,
+	}
+
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		if copyContents && sourceID != nil {
+			var source models.PlanVersion
+			if err := tx.Where("id = ? AND degree_program_id = ?", *sourceID, programID).First(&source).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrPlanVersionNotFound
+				}
+				return err
+			}
+		}
+		if err := tx.Create(&version).Error; err != nil {
+			return err
+		}
+		if !copyContents {
+			return nil
+		}
+		return copyPlanContents(tx, programID, sourceID, version.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func copyPlanContents(tx *gorm.DB, programID string, sourceID *string, targetID string) error {
+	var subjects []models.Subject
+	if err := tx.Where("degree_program_id = ?", programID).Scopes(ScopePlanVersion("plan_version_id", sourceID)).Find(&subjects).Error; err != nil {
+		return err
+	}
+	subjectIDs := make(map[string]string, len(subjects))
+	for i := range subjects {
+		newID := uuid.NewString()
+		subjectIDs[subjects[i].ID] = newID
+		subjects[i].ID = newID
+		subjects[i].PlanVersionID = &targetID
+		subjects[i].CreatedAt = time.Time{}
+		subjects[i].UpdatedAt = time.Time{}
+	}
+	if len(subjects) == 0 {
+		return nil
+	}
+	if err := tx.Omit("Requirements", "ElectivePools").Create(&subjects).Error; err != nil {
+		return err
+	}
+
+	oldSubjectIDs := make([]string, 0, len(subjectIDs))
+	for oldID := range subjectIDs {
+		oldSubjectIDs = append(oldSubjectIDs, oldID)
+	}
+	var requirements []models.SubjectRequirement
+	if err := tx.Where("subject_id IN ?", oldSubjectIDs).Find(&requirements).Error; err != nil {
+		return err
+	}
+	copiedRequirements := make([]models.SubjectRequirement, 0, len(requirements))
+	for _, r := range requirements {
+		requirementID, ok := subjectIDs[r.RequirementID]
+		if !ok {
+			continue
+		}
+		copiedRequirements = append(copiedRequirements, models.SubjectRequirement{
+			SubjectID:     subjectIDs[r.SubjectID],
+			RequirementID: requirementID,
+			MinStatus:     r.MinStatus,
+		})
+	}
+	if len(copiedRequirements) > 0 {
+		if err := tx.Create(&copiedRequirements).Error; err != nil {
+			return err
+		}
+	}
+	if err := copyRequirementGroups(tx, subjectIDs); err != nil {
+		return err
+	}
+	var corequisites []models.SubjectCorequisite
+	if err := tx.Where("subject_id IN ?", oldSubjectIDs).Find(&corequisites).Error; err != nil {
+		return err
+	}
+	copiedCorequisites := make([]models.SubjectCorequisite, 0, len(corequisites))
+	for _, co := range corequisites {
+		corequisiteID, ok := subjectIDs[co.CorequisiteID]
+		if !ok {
+			continue
+		}
+		copiedCorequisites = append(copiedCorequisites, models.SubjectCorequisite{SubjectID: subjectIDs[co.SubjectID], CorequisiteID: corequisiteID})
+	}
+	if len(copiedCorequisites) > 0 {
+		if err := tx.Omit("Subject", "Corequisite").Create(&copiedCorequisites).Error; err != nil {
+			return err
+		}
+	}
+
+	var pools []models.ElectivePool
+	if err := tx.Where("degree_program_id = ?", programID).Scopes(ScopePlanVersion("plan_version_id", sourceID)).Find(&pools).Error; err != nil {
+		return err
+	}
+	poolIDs := make(map[string]string, len(pools))
+	oldPoolIDs := make([]string, 0, len(pools))
+	for i := range pools {
+		newID := uuid.NewString()
+		poolIDs[pools[i].ID] = newID
+		oldPoolIDs = append(oldPoolIDs, pools[i].ID)
+		pools[i].ID = newID
+		pools[i].PlanVersionID = &targetID
+		pools[i].CreatedAt = time.Time{}
+		pools[i].UpdatedAt = time.Time{}
+	}
+	if len(pools) == 0 {
+		return nil
+	}
+	if err := tx.Omit("DegreeProgram", "Subjects").Create(&pools).Error; err != nil {
+		return err
+	}
+
+	var poolSubjects []models.ElectivePoolSubject
+	if err := tx.Where("elective_pool_id IN ?", oldPoolIDs).Find(&poolSubjects).Error; err != nil {
+		return err
+	}
+	copiedPoolSubjects := make([]models.ElectivePoolSubject, 0, len(poolSubjects))
+	for _, ps := range poolSubjects {
+		subjectID, ok := subjectIDs[ps.SubjectID]
+		if !ok {
+			continue
+		}
+		copiedPoolSubjects = append(copiedPoolSubjects, models.ElectivePoolSubject{ElectivePoolID: poolIDs[ps.ElectivePoolID], SubjectID: subjectID})
+	}
+	if len(copiedPoolSubjects) > 0 {
+		if err := tx.Omit("Pool", "Subject").Create(&copiedPoolSubjects).Error; err != nil {
+			return err
+		}
+	}
+
+	var rules []models.ElectiveRule
+	if err := tx.Where("degree_program_id = ? AND pool_id IN ?", programID, oldPoolIDs).Find(&rules).Error; err != nil {
+		return err
+	}
+	for i := range rules {
+		rules[i].ID = uuid.NewString()
+		rules[i].PoolID = poolIDs[rules[i].PoolID]
+		rules[i].PlanVersionID = &targetID
+		rules[i].CreatedAt = time.Time{}
+		rules[i].UpdatedAt = time.Time{}
+	}
+	if len(rules) > 0 {
+		if err := tx.Omit("DegreeProgram", "Pool").Create(&rules).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func PublishPlanVersion(programID string, versionID string) (*models.PlanVersion, error) {
+	version, err := GetPlanVersion(programID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	if version.Status == models.PlanVersionPublished {
+		return version, nil
+	}
+	now := time.Now().UTC()
+	version.Status = models.PlanVersionPublished
+	version.PublishedAt = &now
+	if err := db.Db.Model(version).Updates(map[string]interface{}{"status": version.Status, "published_at": now}).Error; err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// DeletePlanVersion borra un borrador junto con sus materias, pools y reglas.
+func DeletePlanVersion(programID string, versionID string) error {
+	version, err := GetPlanVersion(programID, versionID)
+	if err != nil {
+		return err
+	}
+	if version.Status == models.PlanVersionPublished {
+		return ErrPlanVersionPublished
+	}
+
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		subjectIDs := tx.Model(&models.Subject{}).Select("id").Where("plan_version_id = ?", version.ID)
+		poolIDs := tx.Model(&models.ElectivePool{}).Select("id").Where("plan_version_id = ?", version.ID)
+		if err := tx.Where("plan_version_id = ?", version.ID).Delete(&models.ElectiveRule{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("elective_pool_id IN (?)", poolIDs).Delete(&models.ElectivePoolSubject{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("plan_version_id = ?", version.ID).Delete(&models.ElectivePool{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subject_id IN (?) OR requirement_id IN (?)", subjectIDs, subjectIDs).Delete(&models.SubjectRequirement{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subject_id IN (?) OR corequisite_id IN (?)", subjectIDs, subjectIDs).Delete(&models.SubjectCorequisite{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subject_id IN (?)", subjectIDs).Delete(&models.UserSubject{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("plan_version_id = ?", version.ID).Delete(&models.Subject{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(version).Error
+	})
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"math"
+	"sort"
+)
+
+type PlanLoadUnit string
+
+const (
+	PlanLoadSubjects PlanLoadUnit = "subjects"
+	PlanLoadHours    PlanLoadUnit = "hours"
+	PlanLoadCredits  PlanLoadUnit = "credits"
+)
+
+const (
+	UnschedulableExceedsLoad = "exceeds_max_load"
+	UnschedulableBlocked     = "blocked_by_requirements"
+
+	defaultPlanMaxTerms = 40
+)
+
+type PlanOptions struct {
+	MaxLoad float64
+	Unit    PlanLoadUnit
+	// StartSemester es el cuatrimestre (1 o 2) en el que arranca el plan.
+	StartSemester int
+	// StartYear es el año calendario del primer período; 0 deja los años relativos.
+	StartYear int
+	// FinalLag es la cantidad de períodos entre regularizar una materia y aprobar el final.
+	FinalLag         int
+	IncludeElectives bool
+	MaxTerms         int
+}
+
+type PlannedSubject struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Term   string  `json:"term"`
+	Year   *int    `json:"year,omitempty"`
+	Load   float64 `json:"load"`
+	EndsAt int     `json:"ends_at_term"`
+}
+
+type PlanTerm struct {
+	Index    int              `json:"index"`
+	Year     int              `json:"year"`
+	Semester int              `json:"semester"`
+	Load     float64          `json:"load"`
+	Subjects []PlannedSubject `json:"subjects"`
+}
+
+type PlanTermRef struct {
+	Index    int `json:"index"`
+	Year     int `json:"year"`
+	Semester int `json:"semester"`
+}
+
+type UnschedulableSubject struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+type SemesterPlan struct {
+	Unit           PlanLoadUnit           `json:"unit"`
+	MaxLoad        float64                `json:"max_load"`
+	Terms          []PlanTerm             `json:"terms"`
+	GraduationTerm *PlanTermRef           `json:"graduation_term,omitempty"`
+	PendingFinals  []string               `json:"pending_finals"`
+	Unschedulable  []UnschedulableSubject `json:"unschedulable"`
+}
+
+// BuildSemesterPlan propone un plan cuatrimestre a cuatrimestre para las materias que faltan.
+//
+// Una materia cursada en el período t queda regularizada desde t+1 y aprobada desde
+// t+1+FinalLag; las que ya están en curso o con final pendiente se consideran regularizadas
+// al inicio del plan. Las anuales arrancan solo en el primer cuatrimestre y ocupan dos
+// períodos; el resto de las modalidades ocupa uno. Entre las materias habilitadas se
+// priorizan las que abren la cadena de correlativas más larga. Los grupos de requisitos (groups
+// son los grupos raíz) se evalúan con los estados simulados de cada período. GraduationTerm es el
+// período del último final (incluye FinalLag y los finales pendientes) y queda vacío si alguna
+// materia no se pudo planificar.
+func BuildSemesterPlan(subjects []models.Subject, requirements []models.SubjectRequirement, groups []models.RequirementGroup, statuses map[string]models.SubjectStatus, opts PlanOptions) SemesterPlan {
+	if opts.Unit == "" {
+		opts.Unit = PlanLoadSubjects
+	}
+	if opts.StartSemester != 2 {
+		opts.StartSemester = 1
+	}
+	if opts.FinalLag < 0 {
+		opts.FinalLag = 0
+	}
+	if opts.MaxTerms <= 0 {
+		opts.MaxTerms = defaultPlanMaxTerms
+	}
+
+	plan := SemesterPlan{
+		Unit:          opts.Unit,
+		MaxLoad:       opts.MaxLoad,
+		Terms:         make([]PlanTerm, 0),
+		PendingFinals: make([]string, 0),
+		Unschedulable: make([]UnschedulableSubject, 0),
+	}
+
+	const never = math.MaxInt32
+	regularizedAt := make(map[string]int, len(subjects))
+	passedAt := make(map[string]int, len(subjects))
+	subjectIDs := make([]string, 0, len(subjects))
+	toSchedule := make([]models.Subject, 0, len(subjects))
+	for _, s := range subjects {
+		subjectIDs = append(subjectIDs, s.ID)
+		status, ok := statuses[s.ID]
+		if !ok {
+			status = models.StatusAvailable
+		}
+		switch {
+		case IsPassed(status):
+			regularizedAt[s.ID] = 0
+			passedAt[s.ID] = 0
+		case status == models.StatusFinalPending || status == models.StatusInProgress:
+			regularizedAt[s.ID] = 0
+			passedAt[s.ID] = opts.FinalLag
+			if status == models.StatusFinalPending {
+				plan.PendingFinals = append(plan.PendingFinals, s.ID)
+			}
+		default:
+			regularizedAt[s.ID] = never
+			passedAt[s.ID] = never
+			if !s.IsElective || opts.IncludeElectives {
+				toSchedule = append(toSchedule, s)
+			}
+		}
+	}
+
+	graph := NewRequirementGraph(subjectIDs, requirements)
+	heights, err := graph.Heights()
+	if err != nil {
+		for _, s := range toSchedule {
+			plan.Unschedulable = append(plan.Unschedulable, UnschedulableSubject{ID: s.ID, Name: s.Name, Reason: UnschedulableBlocked})
+		}
+		return plan
+	}
+
+	minStatus := make(map[[2]string]models.RequirementMinStatus, len(requirements))
+	for _, r := range requirements {
+		minStatus[[2]string{r.SubjectID, r.RequirementID}] = r.MinStatus
+	}
+
+	remaining := make([]models.Subject, 0, len(toSchedule))
+	for _, s := range toSchedule {
+		if opts.MaxLoad > 0 && subjectTermLoad(s, opts.Unit) > opts.MaxLoad {
+			plan.Unschedulable = append(plan.Unschedulable, UnschedulableSubject{ID: s.ID, Name: s.Name, Reason: UnschedulableExceedsLoad})
+			continue
+		}
+		remaining = append(remaining, s)
+	}
+	sort.SliceStable(remaining, func(i, j int) bool {
+		a, b := remaining[i], remaining[j]
+		if heights[a.ID] != heights[b.ID] {
+			return heights[a.ID] > heights[b.ID]
+		}
+		ya, yb := subjectYearOrMax(a), subjectYearOrMax(b)
+		if ya != yb {
+			return ya < yb
+		}
+		return a.Name < b.Name
+	})
+
+	groupsBySubject := GroupsBySubject(groups)
+	statusesAt := func(term int) map[string]models.SubjectStatus {
+		at := make(map[string]models.SubjectStatus, len(subjects))
+		for _, s := range subjects {
+			switch {
+			case passedAt[s.ID] <= term:
+				at[s.ID] = models.StatusPassed
+			case regularizedAt[s.ID] <= term:
+				at[s.ID] = models.StatusFinalPending
+			default:
+				at[s.ID] = models.StatusAvailable
+			}
+		}
+		return at
+	}
+
+	ready := func(s models.Subject, term int) bool {
+		for _, reqID := range graph.Requirements(s.ID) {
+			if minStatus[[2]string{s.ID, reqID}] == models.ReqFinalPending {
+				if regularizedAt[reqID] > term {
+					return false
+				}
+			} else if passedAt[reqID] > term {
+				return false
+			}
+		}
+		if subjectGroups := groupsBySubject[s.ID]; len(subjectGroups) > 0 {
+			return len(EvaluateGroups(subjectGroups, subjects, statusesAt(term), false)) == 0
+		}
+		return true
+	}
+
+	terms := make([]PlanTerm, 0)
+	termAt := func(index int) *PlanTerm {
+		for len(terms) <= index {
+			i := len(terms)
+			offset := opts.StartSemester - 1 + i
+			year := offset/2 + 1
+			if opts.StartYear > 0 {
+				year = opts.StartYear + offset/2
+			}
+			terms = append(terms, PlanTerm{Index: i + 1, Year: year, Semester: offset%2 + 1, Subjects: make([]PlannedSubject, 0)})
+		}
+		return &terms[index]
+	}
+
+	for t := 0; t < opts.MaxTerms && len(remaining) > 0; t++ {
+		// las anuales ocupan también el período siguiente
+		termAt(t + 1)
+		current, following := &terms[t], &terms[t+1]
+		next := remaining[:0:0]
+		for _, s := range remaining {
+			annual := s.Term == string(models.TermAnnual)
+			if !ready(s, t) || (annual && current.Semester != 1) {
+				next = append(next, s)
+				continue
+			}
+			load := subjectTermLoad(s, opts.Unit)
+			if opts.MaxLoad > 0 && current.Load+load > opts.MaxLoad {
+				next = append(next, s)
+				continue
+			}
+			if annual {
+				if opts.MaxLoad > 0 && following.Load+load > opts.MaxLoad {
+					next = append(next, s)
+					continue
+				}
+				following.Load += load
+			}
+
+			end := t
+			if annual {
+				end = t + 1
+			}
+			current.Load += load
+			current.Subjects = append(current.Subjects, PlannedSubject{
+				ID:     s.ID,
+				Name:   s.Name,
+				Term:   s.Term,
+				Year:   s.Year,
+				Load:   load,
+				EndsAt: end + 1,
+			})
+			regularizedAt[s.ID] = end + 1
+			passedAt[s.ID] = end + 1 + opts.FinalLag
+		}
+		remaining = next
+	}
+
+	for _, s := range remaining {
+		plan.Unschedulable = append(plan.Unschedulable, UnschedulableSubject{ID: s.ID, Name: s.Name, Reason: UnschedulableBlocked})
+	}
+
+	// El egreso es el período en el que se rinde el último final de lo que falta (aprobada desde
+	// passedAt significa rendida en passedAt-1). Si algo no se pudo planificar no hay fecha posible.
+	graduation := -1
+	for _, s := range subjects {
+		if IsPassed(statuses[s.ID]) || passedAt[s.ID] == never {
+			continue
+		}
+		if passedAt[s.ID]-1 > graduation {
+			graduation = passedAt[s.ID] - 1
+		}
+	}
+	if graduation >= 0 && len(plan.Unschedulable) == 0 {
+		term := *termAt(graduation)
+		plan.GraduationTerm = &PlanTermRef{Index: term.Index, Year: term.Year, Semester: term.Semester}
+	}
+
+	lastEnd := -1
+	for _, term := range terms {
+		for _, s := range term.Subjects {
+			if s.EndsAt-1 > lastEnd {
+				lastEnd = s.EndsAt - 1
+			}
+		}
+	}
+	plan.Terms = terms[:lastEnd+1]
+
+	return plan
+}
+
+// subjectTermLoad es la carga que una materia ocupa en cada período que dura.
+func subjectTermLoad(s models.Subject, unit PlanLoadUnit) float64 {
+	periods := 1.0
+	if s.Term == string(models.TermAnnual) {
+		periods = 2
+	}
+	switch unit {
+	case PlanLoadHours:
+		return s.Hours / periods
+	case PlanLoadCredits:
+		return s.Credits / periods
+	default:
+		return 1
+	}
+}
+
+func subjectYearOrMax(s models.Subject) int {
+	if s.Year == nil {
+		return math.MaxInt32
+	}
+	return *s.Year
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"testing"
+)
+
+func plannedTermOf(plan SemesterPlan) map[string]int {
+	result := make(map[string]int)
+	for _, term := range plan.Terms {
+		for _, s := range term.Subjects {
+			result[s.ID] = term.Index
+		}
+	}
+	return result
+}
+
+func TestBuildSemesterPlan(t *testing.T) {
+	t.Parallel()
+
+	semester := string(models.TermSemester)
+	annual := string(models.TermAnnual)
+
+	tests := []struct {
+		name           string
+		subjects       []models.Subject
+		requirements   []models.SubjectRequirement
+		groups         []models.RequirementGroup
+		statuses       map[string]models.SubjectStatus
+		opts           PlanOptions
+		wantTerm       map[string]int
+		wantGraduation int
+		wantBlocked    []string
+	}{
+		{
+			name: "regularizada habilita antes que aprobada",
+			subjects: []models.Subject{
+				{ID: "am1", Name: "AM1", Term: semester},
+				{ID: "am2", Name: "AM2", Term: semester},
+				{ID: "fis", Name: "FIS", Term: semester},
+			},
+			requirements: []models.SubjectRequirement{
+				{SubjectID: "am2", RequirementID: "am1", MinStatus: models.ReqFinalPending},
+				{SubjectID: "fis", RequirementID: "am1", MinStatus: models.ReqPassed},
+			},
+			opts:           PlanOptions{MaxLoad: 5, FinalLag: 1},
+			wantTerm:       map[string]int{"am1": 1, "am2": 2, "fis": 3},
+			wantGraduation: 4,
+		},
+		{
+			name:           "anual solo arranca en el primer cuatrimestre",
+			subjects:       []models.Subject{{ID: "ann", Name: "Anual", Term: annual}},
+			opts:           PlanOptions{MaxLoad: 5, StartSemester: 2},
+			wantTerm:       map[string]int{"ann": 2},
+			wantGraduation: 3,
+		},
+		{
+			name: "respeta la carga máxima",
+			subjects: []models.Subject{
+				{ID: "a", Name: "A", Term: semester},
+				{ID: "b", Name: "B", Term: semester},
+			},
+			opts:           PlanOptions{MaxLoad: 1},
+			wantTerm:       map[string]int{"a": 1, "b": 2},
+			wantGraduation: 2,
+		},
+		{
+			name: "final pendiente cuenta como regularizada",
+			subjects: []models.Subject{
+				{ID: "x", Name: "X", Term: semester},
+				{ID: "y", Name: "Y", Term: semester},
+				{ID: "z", Name: "Z", Term: semester},
+			},
+			requirements: []models.SubjectRequirement{
+				{SubjectID: "y", RequirementID: "x", MinStatus: models.ReqPassed},
+				{SubjectID: "z", RequirementID: "x", MinStatus: models.ReqFinalPending},
+			},
+			statuses:       map[string]models.SubjectStatus{"x": models.StatusFinalPending},
+			opts:           PlanOptions{MaxLoad: 5, FinalLag: 1},
+			wantTerm:       map[string]int{"y": 2, "z": 1},
+			wantGraduation: 3,
+		},
+		{
+			name: "espera a que se cumplan los grupos de requisitos",
+			subjects: []models.Subject{
+				{ID: "a", Name: "A", Term: semester},
+				{ID: "b", Name: "B", Term: semester},
+				{ID: "tesis", Name: "Tesis", Term: semester},
+			},
+			groups:         []models.RequirementGroup{{ID: "g", SubjectID: "tesis", Kind: models.GroupAll, Subjects: groupSubjects("a", "b")}},
+			opts:           PlanOptions{MaxLoad: 5},
+			wantTerm:       map[string]int{"a": 1, "b": 1, "tesis": 2},
+			wantGraduation: 2,
+		},
+		{
+			name:           "el egreso espera los finales pendientes",
+			subjects:       []models.Subject{{ID: "x", Name: "X", Term: semester}},
+			statuses:       map[string]models.SubjectStatus{"x": models.StatusFinalPending},
+			opts:           PlanOptions{MaxLoad: 5, FinalLag: 2},
+			wantTerm:       map[string]int{},
+			wantGraduation: 2,
+		},
+		{
+			name: "sin egreso si alguna materia no se puede planificar",
+			subjects: []models.Subject{
+				{ID: "a", Name: "A", Term: semester, Hours: 10},
+				{ID: "big", Name: "Big", Term: semester, Hours: 200},
+			},
+			opts:        PlanOptions{MaxLoad: 100, Unit: PlanLoadHours},
+			wantTerm:    map[string]int{"a": 1},
+			wantBlocked: []string{"big"},
+		},
+		{
+			name: "horas que exceden la carga quedan fuera",
+			subjects: []models.Subject{
+				{ID: "big", Name: "Big", Term: semester, Hours: 200},
+				{ID: "after", Name: "After", Term: semester, Hours: 10},
+			},
+			requirements: []models.SubjectRequirement{
+				{SubjectID: "after", RequirementID: "big", MinStatus: models.ReqPassed},
+			},
+			opts:        PlanOptions{MaxLoad: 100, Unit: PlanLoadHours},
+			wantTerm:    map[string]int{},
+			wantBlocked: []string{"big", "after"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			plan := BuildSemesterPlan(tt.subjects, tt.requirements, tt.groups, tt.statuses, tt.opts)
+			got := plannedTermOf(plan)
+			if len(got) != len(tt.wantTerm) {
+				t.Fatalf("planned = %v, want %v", got, tt.wantTerm)
+			}
+			for id, want := range tt.wantTerm {
+				if got[id] != want {
+					t.Fatalf("subject %q planned in term %d, want %d (plan %v)", id, got[id], want, got)
+				}
+			}
+			if tt.wantGraduation == 0 {
+				if plan.GraduationTerm != nil {
+					t.Fatalf("GraduationTerm = %+v, want nil", plan.GraduationTerm)
+				}
+			} else if plan.GraduationTerm == nil || plan.GraduationTerm.Index != tt.wantGraduation {
+				t.Fatalf("GraduationTerm = %+v, want index %d", plan.GraduationTerm, tt.wantGraduation)
+			}
+			if len(plan.Unschedulable) != len(tt.wantBlocked) {
+				t.Fatalf("Unschedulable = %+v, want %v", plan.Unschedulable, tt.wantBlocked)
+			}
+			for i, id := range tt.wantBlocked {
+				if plan.Unschedulable[i].ID != id {
+					t.Fatalf("Unschedulable[%d] = %q, want %q", i, plan.Unschedulable[i].ID, id)
+				}
+			}
+		})
+	}
+}
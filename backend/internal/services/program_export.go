@@ -0,0 +1,287 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const maxCodeLen = 40
+
+var accentFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "ä", "a", "â", "a", "ã", "a",
+	"é", "e", "è", "e", "ë", "e", "ê", "e",
+	"í", "i", "ì", "i", "ï", "i", "î", "i",
+	"ó", "o", "ò", "o", "ö", "o", "ô", "o", "õ", "o",
+	"ú", "u", "ù", "u", "ü", "u", "û", "u",
+	"ñ", "n", "ç", "c",
+)
+
+// FoldAccents pasa el texto a minúsculas y le saca los acentos más comunes (á → a, ñ → n).
+func FoldAccents(s string) string {
+	return accentFolder.Replace(strings.ToLower(s))
+}
+
+// CodeFromName arma un código legible a partir del nombre: "Análisis Matemático I" → "ANALISIS-MATEMATICO-I".
+func CodeFromName(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range FoldAccents(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(unicode.ToUpper(r))
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	code := b.String()
+	if len(code) > maxCodeLen {
+		code = strings.TrimRight(code[:maxCodeLen], "-")
+	}
+	if code == "" {
+		code = "SUBJECT"
+	}
+	return code
+}
+
+// uniqueCodes asigna a cada clave un código derivado de su nombre; los repetidos llevan sufijo -2, -3...
+// keys tiene que venir en un orden estable para que los sufijos no cambien entre exportaciones.
+// Las claves de fixed conservan su código y los generados los esquivan.
+func uniqueCodes(keys []string, names map[string]string, fixed map[string]string) map[string]string {
+	codes := make(map[string]string, len(keys))
+	used := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if code, ok := fixed[key]; ok {
+			codes[key] = code
+			used[code] = struct{}{}
+		}
+	}
+	for _, key := range keys {
+		if _, ok := codes[key]; ok {
+			continue
+		}
+		base := CodeFromName(names[key])
+		code := base
+		for n := 2; ; n++ {
+			if _, taken := used[code]; !taken {
+				break
+			}
+			code = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[code] = struct{}{}
+		codes[key] = code
+	}
+	return codes
+}
+
+func sortPoolsForExport(in []models.ElectivePool) []models.ElectivePool {
+	pools := append([]models.ElectivePool(nil), in...)
+	sort.SliceStable(pools, func(i, j int) bool {
+		if pools[i].Name != pools[j].Name {
+			return pools[i].Name < pools[j].Name
+		}
+		return pools[i].ID < pools[j].ID
+	})
+	return pools
+}
+
+// ElectivePoolCodes asigna a cada pool (por id) el código con el que sale en el seed. Los pools no
+// guardan código, así que sale del nombre, igual que en SubjectCodes.
+func ElectivePoolCodes(pools []models.ElectivePool) map[string]string {
+	pools = sortPoolsForExport(pools)
+	poolIDs := make([]string, 0, len(pools))
+	poolNames := make(map[string]string, len(pools))
+	for _, p := range pools {
+		poolIDs = append(poolIDs, p.ID)
+		poolNames[p.ID] = p.Name
+	}
+	return uniqueCodes(poolIDs, poolNames, nil)
+}
+
+func sortSubjectsForExport(in []models.Subject) []models.Subject {
+	subjects := append([]models.Subject(nil), in...)
+	sort.SliceStable(subjects, func(i, j int) bool {
+		if subjectYearOrMax(subjects[i]) != subjectYearOrMax(subjects[j]) {
+			return subjectYearOrMax(subjects[i]) < subjectYearOrMax(subjects[j])
+		}
+		if subjects[i].Name != subjects[j].Name {
+			return subjects[i].Name < subjects[j].Name
+		}
+		return subjects[i].ID < subjects[j].ID
+	})
+	return subjects
+}
+
+// SubjectCodes devuelve el código de cada materia (por id): el guardado si tiene, o uno derivado
+// del nombre. Es el mismo código que usa la exportación, así un seed exportado se puede reimportar.
+func SubjectCodes(subjects []models.Subject) map[string]string {
+	sorted := sortSubjectsForExport(subjects)
+	ids := make([]string, 0, len(sorted))
+	names := make(map[string]string, len(sorted))
+	fixed := make(map[string]string)
+	for _, s := range sorted {
+		ids = append(ids, s.ID)
+		names[s.ID] = s.Name
+		if s.Code != "" {
+			fixed[s.ID] = s.Code
+		}
+	}
+	return uniqueCodes(ids, names, fixed)
+}
+
+func ExportProgram(program models.DegreeProgram, versionID *string) (*models.JsonToDegreeProgram, error) {
+	plan, err := LoadProgramPlan(program.ID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	export := BuildProgramExport(program, plan)
+	return &export, nil
+}
+
+// BuildProgramExport arma el plan en el formato de POST /degreeProgram/seed. Las materias se
+// ordenan por año y nombre y usan los códigos de SubjectCodes, así dos exportaciones del mismo
+// plan dan el mismo archivo.
+func BuildProgramExport(program models.DegreeProgram, plan *ProgramPlan) models.JsonToDegreeProgram {
+	subjects := sortSubjectsForExport(plan.Subjects)
+	codes := SubjectCodes(subjects)
+
+	requirementsBySubject := make(map[string][]models.SeedRequirement)
+	for _, r := range plan.Requirements {
+		code, ok := codes[r.RequirementID]
+		if !ok {
+			continue
+		}
+		requirementsBySubject[r.SubjectID] = append(requirementsBySubject[r.SubjectID], models.SeedRequirement{SubjectCode: code, Type: seedRequirementType(r.MinStatus)})
+	}
+	for _, co := range plan.Corequisites {
+		code, ok := codes[co.CorequisiteID]
+		if !ok {
+			continue
+		}
+		requirementsBySubject[co.SubjectID] = append(requirementsBySubject[co.SubjectID], models.SeedRequirement{SubjectCode: code, Type: models.SeedRequirementCorequisite})
+	}
+	groupsBySubject := GroupsBySubject(plan.Groups)
+
+	export := models.JsonToDegreeProgram{
+		DegreeProgram: models.SeedDegreeProgram{Name: program.Name, UniversityID: program.UniversityID},
+		Subjects:      make([]models.SeedSubject, 0, len(subjects)),
+	}
+	for _, s := range subjects {
+		requirements := requirementsBySubject[s.ID]
+		sort.Slice(requirements, func(i, j int) bool {
+			if requirements[i].SubjectCode != requirements[j].SubjectCode {
+				return requirements[i].SubjectCode < requirements[j].SubjectCode
+			}
+			return requirements[i].Type < requirements[j].Type
+		})
+		if requirements == nil {
+			requirements = []models.SeedRequirement{}
+		}
+		year := 0
+		if s.Year != nil {
+			year = *s.Year
+		}
+		// El seed exige un cuatrimestre válido; las materias viejas sin term se exportan como anuales,
+		// igual que el valor por defecto de CreateSubject.
+		term := models.SubjectTerm(s.Term)
+		if term == "" {
+			term = models.TermAnnual
+		}
+		seedSubject := models.SeedSubject{
+			Code:         codes[s.ID],
+			Name:         s.Name,
+			SubjectYear:  year,
+			Term:         term,
+			IsElective:   s.IsElective,
+			Credits:      s.Credits,
+			Hours:        s.Hours,
+			Requirements: requirements,
+		}
+		for _, g := range groupsBySubject[s.ID] {
+			seedSubject.RequirementGroups = append(seedSubject.RequirementGroups, seedGroupFromModel(g, codes))
+		}
+		export.Subjects = append(export.Subjects, seedSubject)
+	}
+
+	pools := sortPoolsForExport(plan.Pools)
+	poolCodes := ElectivePoolCodes(pools)
+	poolSubjects := make(map[string][]string)
+	for _, ps := range plan.PoolSubjects {
+		if code, ok := codes[ps.SubjectID]; ok {
+			poolSubjects[ps.ElectivePoolID] = append(poolSubjects[ps.ElectivePoolID], code)
+		}
+	}
+	for _, p := range pools {
+		subjectCodes := poolSubjects[p.ID]
+		sort.Strings(subjectCodes)
+		if subjectCodes == nil {
+			subjectCodes = []string{}
+		}
+		export.ElectivePools = append(export.ElectivePools, models.SeedElectivePool{
+			Code:         poolCodes[p.ID],
+			Name:         p.Name,
+			Description:  p.Description,
+			SubjectCodes: subjectCodes,
+		})
+	}
+
+	for _, rule := range plan.Rules {
+		code, ok := poolCodes[rule.PoolID]
+		if !ok {
+			continue
+		}
+		export.ElectiveRules = append(export.ElectiveRules, models.SeedElectiveRule{
+			PoolCode:        code,
+			AppliesFromYear: rule.AppliesFromYear,
+			AppliesToYear:   rule.AppliesToYear,
+			RequirementType: rule.RequirementType,
+			MinimumValue:    rule.MinimumValue,
+		})
+	}
+	sort.SliceStable(export.ElectiveRules, func(i, j int) bool {
+		a, b := export.ElectiveRules[i], export.ElectiveRules[j]
+		if a.PoolCode != b.PoolCode {
+			return a.PoolCode < b.PoolCode
+		}
+		if a.AppliesFromYear != b.AppliesFromYear {
+			return a.AppliesFromYear < b.AppliesFromYear
+		}
+		return a.RequirementType < b.RequirementType
+	})
+
+	return export
+}
+
+func seedRequirementType(status models.RequirementMinStatus) models.SeedRequirementType {
+	if status == models.ReqFinalPending {
+		return models.SeedRequirementRegularize
+	}
+	return models.SeedRequirementApproved
+}
+
+func seedGroupFromModel(g models.RequirementGroup, codes map[string]string) models.SeedRequirementGroup {
+	group := models.SeedRequirementGroup{
+		Kind:         g.Kind,
+		MinCount:     g.MinCount,
+		MinimumValue: g.MinimumValue,
+		Year:         g.Year,
+	}
+	if g.MinStatus == models.ReqFinalPending {
+		group.Type = models.SeedRequirementRegularize
+	}
+	for _, s := range g.Subjects {
+		if code, ok := codes[s.SubjectID]; ok {
+			group.SubjectCodes = append(group.SubjectCodes, code)
+		}
+	}
+	sort.Strings(group.SubjectCodes)
+	for _, child := range g.Children {
+		group.Groups = append(group.Groups, seedGroupFromModel(child, codes))
+	}
+	return group
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"testing"
+)
+
+func TestCodeFromName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Análisis Matemático I", want: "ANALISIS-MATEMATICO-I"},
+		{name: "  Diseño de Sistemas (2023) ", want: "DISENO-DE-SISTEMAS-2023"},
+		{name: "Física ñandú", want: "FISICA-NANDU"},
+		{name: "¿?", want: "SUBJECT"},
+		{name: "Una materia con un nombre realmente muy largo", want: "UNA-MATERIA-CON-UN-NOMBRE-REALMENTE-MUY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := CodeFromName(tt.name); got != tt.want {
+				t.Fatalf("CodeFromName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildProgramExport(t *testing.T) {
+	t.Parallel()
+
+	plan := &ProgramPlan{
+		Subjects: []models.Subject{
+			{ID: "c", Name: "Química", Year: intPtr(2), Term: "semester", Credits: 6, Hours: 96},
+			{ID: "a", Name: "Álgebra", Year: intPtr(1), Term: "annual"},
+			{ID: "b", Name: "Algebra", Year: intPtr(1)},
+			{ID: "e", Name: "Electiva", Year: intPtr(3), Term: "semester", IsElective: true, Credits: 4},
+		},
+		Requirements: []models.SubjectRequirement{
+			{SubjectID: "c", RequirementID: "b", MinStatus: models.ReqFinalPending},
+			{SubjectID: "c", RequirementID: "a", MinStatus: models.ReqPassed},
+		},
+		Corequisites: []models.SubjectCorequisite{{SubjectID: "e", CorequisiteID: "c"}},
+		Groups:       []models.RequirementGroup{{SubjectID: "e", Kind: models.GroupCredits, MinStatus: models.ReqFinalPending, MinimumValue: floatPtr(6)}},
+		Pools:        []models.ElectivePool{{ID: "p", Name: "Electivas"}},
+		PoolSubjects: []models.ElectivePoolSubject{{ElectivePoolID: "p", SubjectID: "e"}},
+		Rules:        []models.ElectiveRule{{ID: "r", PoolID: "p", AppliesFromYear: 3, RequirementType: models.RequirementCredits, MinimumValue: 4}},
+	}
+
+	export := BuildProgramExport(models.DegreeProgram{Name: "Ingeniería", UniversityID: "u"}, plan)
+	if export.DegreeProgram.Name != "Ingeniería" || export.DegreeProgram.UniversityID != "u" {
+		t.Fatalf("degreeProgram = %+v", export.DegreeProgram)
+	}
+
+	codes := make([]string, 0, len(export.Subjects))
+	for _, s := range export.Subjects {
+		codes = append(codes, s.Code)
+	}
+	wantCodes := []string{"ALGEBRA", "ALGEBRA-2", "QUIMICA", "ELECTIVA"}
+	for i := range wantCodes {
+		if codes[i] != wantCodes[i] {
+			t.Fatalf("codes = %v, want %v", codes, wantCodes)
+		}
+	}
+	if export.Subjects[0].Term != models.TermAnnual || export.Subjects[1].Term != models.TermAnnual {
+		t.Fatalf("subjects without term should export as annual: %+v", export.Subjects[:2])
+	}
+
+	quimica := export.Subjects[2]
+	if quimica.Credits != 6 || quimica.Hours != 96 || len(quimica.Requirements) != 2 ||
+		quimica.Requirements[0] != (models.SeedRequirement{SubjectCode: "ALGEBRA", Type: models.SeedRequirementRegularize}) ||
+		quimica.Requirements[1] != (models.SeedRequirement{SubjectCode: "ALGEBRA-2", Type: models.SeedRequirementApproved}) {
+		t.Fatalf("quimica = %+v", quimica)
+	}
+
+	electiva := export.Subjects[3]
+	if len(electiva.Requirements) != 1 || electiva.Requirements[0].Type != models.SeedRequirementCorequisite || electiva.Requirements[0].SubjectCode != "QUIMICA" {
+		t.Fatalf("electiva requirements = %+v", electiva.Requirements)
+	}
+	if len(electiva.RequirementGroups) != 1 || electiva.RequirementGroups[0].Type != models.SeedRequirementRegularize || *electiva.RequirementGroups[0].MinimumValue != 6 {
+		t.Fatalf("electiva groups = %+v", electiva.RequirementGroups)
+	}
+
+	if len(export.ElectivePools) != 1 || export.ElectivePools[0].Code != "ELECTIVAS" || len(export.ElectivePools[0].SubjectCodes) != 1 || export.ElectivePools[0].SubjectCodes[0] != "ELECTIVA" {
+		t.Fatalf("pools = %+v", export.ElectivePools)
+	}
+	if len(export.ElectiveRules) != 1 || export.ElectiveRules[0].PoolCode != "ELECTIVAS" || export.ElectiveRules[0].MinimumValue != 4 {
+		t.Fatalf("rules = %+v", export.ElectiveRules)
+	}
+}
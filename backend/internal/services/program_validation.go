@@ -0,0 +1,262 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"fmt"
+	"sort"
+)
+
+type ValidationSeverity string
+
+const (
+	SeverityError   ValidationSeverity = "error"
+	SeverityWarning ValidationSeverity = "warning"
+)
+
+// ValidationIssue es un problema encontrado en el plan. Code es estable para que el front
+// pueda agrupar o traducir los mensajes.
+type ValidationIssue struct {
+	Severity   ValidationSeverity `json:"severity"`
+	Code       string             `json:"code"`
+	Message    string             `json:"message"`
+	SubjectIDs []string           `json:"subject_ids,omitempty"`
+	PoolID     string             `json:"pool_id,omitempty"`
+	RuleIDs    []string           `json:"rule_ids,omitempty"`
+}
+
+type ProgramValidationReport struct {
+	ProgramID     string            `json:"program_id"`
+	PlanVersionID *string           `json:"plan_version_id,omitempty"`
+	Valid         bool              `json:"valid"`
+	Errors        int               `json:"errors"`
+	Warnings      int               `json:"warnings"`
+	Issues        []ValidationIssue `json:"issues"`
+}
+
+// ProgramPlan es el contenido completo de una versión del plan (nil = plan base).
+type ProgramPlan struct {
+	Subjects     []models.Subject
+	Requirements []models.SubjectRequirement
+	Groups       []models.RequirementGroup
+	Corequisites []models.SubjectCorequisite
+	Pools        []models.ElectivePool
+	PoolSubjects []models.ElectivePoolSubject
+	Rules        []models.ElectiveRule
+}
+
+func LoadProgramPlan(programID string, versionID *string) (*ProgramPlan, error) {
+	subjects, requirements, err := LoadProgramSubjects(programID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	subjectIDs := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		subjectIDs = append(subjectIDs, s.ID)
+	}
+	groups, err := LoadRequirementGroups(subjectIDs)
+	if err != nil {
+		return nil, err
+	}
+	corequisites, err := LoadCorequisites(db.Db, subjectIDs)
+	if err != nil {
+		return nil, err
+	}
+	var pools []models.ElectivePool
+	if err := db.Db.Where("degree_program_id = ?", programID).Scopes(ScopePlanVersion("plan_version_id", versionID)).Order("name ASC").Find(&pools).Error; err != nil {
+		return nil, err
+	}
+	electives, err := LoadProgramElectives(programID, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProgramPlan{
+		Subjects:     subjects,
+		Requirements: requirements,
+		Groups:       groups,
+		Corequisites: corequisites,
+		Pools:        pools,
+		PoolSubjects: electives.PoolSubjects,
+		Rules:        electives.Rules,
+	}, nil
+}
+
+func ValidateProgram(programID string, versionID *string) (*ProgramValidationReport, error) {
+	plan, err := LoadProgramPlan(programID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	report := BuildValidationReport(plan)
+	report.ProgramID = programID
+	report.PlanVersionID = versionID
+	return &report, nil
+}
+
+// ValidatePublishedPlans valida el plan base y cada versión publicada del programa, que son los
+// planes en los que un usuario se puede inscribir una vez aprobado.
+func ValidatePublishedPlans(programID string) ([]ProgramValidationReport, error) {
+	var versionIDs []string
+	if err := db.Db.Model(&models.PlanVersion{}).
+		Where("degree_program_id = ? AND status = ?", programID, models.PlanVersionPublished).
+		Order("published_at ASC").
+		Pluck("id", &versionIDs).Error; err != nil {
+		return nil, err
+	}
+
+	reports := make([]ProgramValidationReport, 0, len(versionIDs)+1)
+	report, err := ValidateProgram(programID, nil)
+	if err != nil {
+		return nil, err
+	}
+	reports = append(reports, *report)
+	for i := range versionIDs {
+		report, err := ValidateProgram(programID, &versionIDs[i])
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+// BuildValidationReport revisa el plan: ciclos de correlativas, co-requisitos que también son
+// correlativas y reglas de electivas imposibles de cumplir son errores; el resto son advertencias
+// que conviene revisar antes de aprobar el programa.
+func BuildValidationReport(plan *ProgramPlan) ProgramValidationReport {
+	issues := make([]ValidationIssue, 0)
+	add := func(severity ValidationSeverity, code string, message string, issue ValidationIssue) {
+		issue.Severity, issue.Code, issue.Message = severity, code, message
+		issues = append(issues, issue)
+	}
+
+	subjects := append([]models.Subject(nil), plan.Subjects...)
+	sort.SliceStable(subjects, func(i, j int) bool {
+		if subjectYearOrMax(subjects[i]) != subjectYearOrMax(subjects[j]) {
+			return subjectYearOrMax(subjects[i]) < subjectYearOrMax(subjects[j])
+		}
+		return subjects[i].Name < subjects[j].Name
+	})
+	subjectsByID := make(map[string]models.Subject, len(subjects))
+	subjectIDs := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		subjectsByID[s.ID] = s
+		subjectIDs = append(subjectIDs, s.ID)
+	}
+
+	edges := append(append([]models.SubjectRequirement(nil), plan.Requirements...), GroupRequirementEdges(plan.Groups)...)
+	if cycle := NewRequirementGraph(subjectIDs, edges).FindCycle(); cycle != nil {
+		add(SeverityError, "requirement_cycle", "Requirements contain a circular dependency", ValidationIssue{SubjectIDs: cycle})
+	}
+	if co, conflict := FindCorequisiteConflict(subjectIDs, edges, plan.Corequisites); conflict {
+		add(SeverityError, "corequisite_conflict",
+			fmt.Sprintf("%q has %q as corequisite and prerequisite", subjectsByID[co.SubjectID].Name, subjectsByID[co.CorequisiteID].Name),
+			ValidationIssue{SubjectIDs: []string{co.SubjectID, co.CorequisiteID}})
+	}
+
+	requirements := append([]models.SubjectRequirement(nil), plan.Requirements...)
+	sort.Slice(requirements, func(i, j int) bool {
+		if requirements[i].SubjectID != requirements[j].SubjectID {
+			return requirements[i].SubjectID < requirements[j].SubjectID
+		}
+		return requirements[i].RequirementID < requirements[j].RequirementID
+	})
+	for _, r := range requirements {
+		subject, ok := subjectsByID[r.SubjectID]
+		required, okReq := subjectsByID[r.RequirementID]
+		if !ok || !okReq || subject.Year == nil || required.Year == nil || *required.Year <= *subject.Year {
+			continue
+		}
+		add(SeverityWarning, "requirement_in_later_year",
+			fmt.Sprintf("%q (year %d) requires %q from year %d", subject.Name, *subject.Year, required.Name, *required.Year),
+			ValidationIssue{SubjectIDs: []string{subject.ID, required.ID}})
+	}
+
+	inPool := make(map[string]bool, len(plan.PoolSubjects))
+	poolSubjects := make(map[string][]string)
+	for _, ps := range plan.PoolSubjects {
+		inPool[ps.SubjectID] = true
+		poolSubjects[ps.ElectivePoolID] = append(poolSubjects[ps.ElectivePoolID], ps.SubjectID)
+	}
+	for _, s := range subjects {
+		if s.Term == "" {
+			add(SeverityWarning, "missing_term", fmt.Sprintf("%q has no term", s.Name), ValidationIssue{SubjectIDs: []string{s.ID}})
+		}
+		if s.IsElective && !inPool[s.ID] {
+			add(SeverityWarning, "elective_without_pool", fmt.Sprintf("Elective %q is not in any pool", s.Name), ValidationIssue{SubjectIDs: []string{s.ID}})
+		}
+	}
+
+	rulesByPool := make(map[string][]models.ElectiveRule)
+	for _, rule := range plan.Rules {
+		rulesByPool[rule.PoolID] = append(rulesByPool[rule.PoolID], rule)
+	}
+	for _, pool := range plan.Pools {
+		rules := rulesByPool[pool.ID]
+		if len(rules) == 0 {
+			add(SeverityWarning, "pool_without_rule", fmt.Sprintf("Pool %q has no elective rule", pool.Name), ValidationIssue{PoolID: pool.ID})
+			continue
+		}
+		sort.SliceStable(rules, func(i, j int) bool { return rules[i].AppliesFromYear < rules[j].AppliesFromYear })
+
+		for _, rule := range rules {
+			available := poolTotal(rule.RequirementType, poolSubjects[pool.ID], subjectsByID)
+			if rule.MinimumValue > available {
+				add(SeverityError, "rule_unreachable",
+					fmt.Sprintf("Rule on pool %q requires %g %s but the pool only offers %g", pool.Name, rule.MinimumValue, rule.RequirementType, available),
+					ValidationIssue{PoolID: pool.ID, RuleIDs: []string{rule.ID}})
+			}
+		}
+		for i := range rules {
+			for j := i + 1; j < len(rules); j++ {
+				if rules[i].RequirementType != rules[j].RequirementType || !yearRangesOverlap(rules[i], rules[j]) {
+					continue
+				}
+				add(SeverityWarning, "overlapping_rules",
+					fmt.Sprintf("Pool %q has %s rules with overlapping year ranges", pool.Name, rules[i].RequirementType),
+					ValidationIssue{PoolID: pool.ID, RuleIDs: []string{rules[i].ID, rules[j].ID}})
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Severity == SeverityError && issues[j].Severity != SeverityError
+	})
+	report := ProgramValidationReport{Issues: issues}
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	report.Valid = report.Errors == 0
+	return report
+}
+
+// poolTotal es lo máximo que se puede sumar con las materias del pool para el tipo de regla.
+func poolTotal(kind models.ElectiveRequirementType, subjectIDs []string, subjectsByID map[string]models.Subject) float64 {
+	total := 0.0
+	for _, id := range subjectIDs {
+		subject, ok := subjectsByID[id]
+		if !ok {
+			continue
+		}
+		switch kind {
+		case models.RequirementCredits:
+			total += subject.Credits
+		case models.RequirementHours:
+			total += subject.Hours
+		default:
+			total++
+		}
+	}
+	return total
+}
+
+func yearRangesOverlap(a models.ElectiveRule, b models.ElectiveRule) bool {
+	endsBefore := func(rule models.ElectiveRule, year int) bool {
+		return rule.AppliesToYear != nil && *rule.AppliesToYear < year
+	}
+	return !endsBefore(a, b.AppliesFromYear) && !endsBefore(b, a.AppliesFromYear)
+}
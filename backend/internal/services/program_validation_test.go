@@ -0,0 +1,82 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"testing"
+)
+
+func TestBuildValidationReport(t *testing.T) {
+	t.Parallel()
+
+	valid := func() *ProgramPlan {
+		return &ProgramPlan{
+			Subjects: []models.Subject{
+				{ID: "a", Name: "A", Year: intPtr(1), Term: "annual"},
+				{ID: "b", Name: "B", Year: intPtr(2), Term: "semester"},
+				{ID: "e", Name: "E", Year: intPtr(2), Term: "semester", IsElective: true, Credits: 4, Hours: 64},
+			},
+			Requirements: edges("b", "a"),
+			Pools:        []models.ElectivePool{{ID: "p", Name: "Pool"}},
+			PoolSubjects: []models.ElectivePoolSubject{{ElectivePoolID: "p", SubjectID: "e"}},
+			Rules:        []models.ElectiveRule{{ID: "r1", PoolID: "p", AppliesFromYear: 1, RequirementType: models.RequirementCredits, MinimumValue: 4}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		mutate   func(p *ProgramPlan)
+		wantCode string
+		wantSev  ValidationSeverity
+	}{
+		{name: "valid plan", mutate: func(p *ProgramPlan) {}},
+		{name: "cycle", mutate: func(p *ProgramPlan) {
+			p.Subjects[1].Year = intPtr(1)
+			p.Requirements = append(p.Requirements, edges("a", "b")...)
+		}, wantCode: "requirement_cycle", wantSev: SeverityError},
+		{name: "cycle through group", mutate: func(p *ProgramPlan) {
+			p.Groups = []models.RequirementGroup{{SubjectID: "a", Kind: models.GroupAny, Subjects: groupSubjects("b")}}
+		}, wantCode: "requirement_cycle", wantSev: SeverityError},
+		{name: "corequisite required through group", mutate: func(p *ProgramPlan) {
+			p.Requirements = nil
+			p.Groups = []models.RequirementGroup{{SubjectID: "b", Kind: models.GroupAny, Subjects: groupSubjects("a")}}
+			p.Corequisites = []models.SubjectCorequisite{{SubjectID: "b", CorequisiteID: "a"}}
+		}, wantCode: "corequisite_conflict", wantSev: SeverityError},
+		{name: "requirement in later year", mutate: func(p *ProgramPlan) { p.Requirements = edges("a", "b") }, wantCode: "requirement_in_later_year", wantSev: SeverityWarning},
+		{name: "missing term", mutate: func(p *ProgramPlan) { p.Subjects[0].Term = "" }, wantCode: "missing_term", wantSev: SeverityWarning},
+		{name: "elective without pool", mutate: func(p *ProgramPlan) { p.PoolSubjects = nil; p.Rules[0].MinimumValue = 0 }, wantCode: "elective_without_pool", wantSev: SeverityWarning},
+		{name: "pool without rule", mutate: func(p *ProgramPlan) { p.Rules = nil }, wantCode: "pool_without_rule", wantSev: SeverityWarning},
+		{name: "rule exceeds pool credits", mutate: func(p *ProgramPlan) { p.Rules[0].MinimumValue = 6 }, wantCode: "rule_unreachable", wantSev: SeverityError},
+		{name: "rule exceeds pool subjects", mutate: func(p *ProgramPlan) {
+			p.Rules[0].RequirementType = models.RequirementSubjectCount
+			p.Rules[0].MinimumValue = 2
+		}, wantCode: "rule_unreachable", wantSev: SeverityError},
+		{name: "overlapping rules", mutate: func(p *ProgramPlan) {
+			p.Rules[0].AppliesToYear = intPtr(3)
+			p.Rules = append(p.Rules, models.ElectiveRule{ID: "r2", PoolID: "p", AppliesFromYear: 3, RequirementType: models.RequirementCredits, MinimumValue: 2})
+		}, wantCode: "overlapping_rules", wantSev: SeverityWarning},
+		{name: "consecutive rules do not overlap", mutate: func(p *ProgramPlan) {
+			p.Rules[0].AppliesToYear = intPtr(2)
+			p.Rules = append(p.Rules, models.ElectiveRule{ID: "r2", PoolID: "p", AppliesFromYear: 3, RequirementType: models.RequirementCredits, MinimumValue: 2})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			plan := valid()
+			tt.mutate(plan)
+			report := BuildValidationReport(plan)
+			if tt.wantCode == "" {
+				if len(report.Issues) != 0 || !report.Valid {
+					t.Fatalf("issues = %+v, want none", report.Issues)
+				}
+				return
+			}
+			if len(report.Issues) != 1 || report.Issues[0].Code != tt.wantCode || report.Issues[0].Severity != tt.wantSev {
+				t.Fatalf("issues = %+v, want one %s %s", report.Issues, tt.wantSev, tt.wantCode)
+			}
+			if report.Valid != (tt.wantSev != SeverityError) {
+				t.Fatalf("valid = %v with %s", report.Valid, tt.wantSev)
+			}
+		})
+	}
+}
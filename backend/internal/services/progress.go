@@ -0,0 +1,153 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"math"
+	"sort"
+)
+
+type ProgressCounter struct {
+	Total      float64 `json:"total"`
+	Completed  float64 `json:"completed"`
+	Percentage float64 `json:"percentage"`
+}
+
+func (p *ProgressCounter) add(value float64, completed bool) {
+	p.Total += value
+	if completed {
+		p.Completed += value
+	}
+}
+
+func (p *ProgressCounter) finish() {
+	if p.Total > 0 {
+		p.Percentage = roundTo(p.Completed*100/p.Total, 2)
+	}
+}
+
+type YearProgress struct {
+	Year     int             `json:"year"`
+	Subjects ProgressCounter `json:"subjects"`
+	Credits  ProgressCounter `json:"credits"`
+	Hours    ProgressCounter `json:"hours"`
+}
+
+// ProgramProgress resume el avance de un usuario en un programa.
+// Los totales se calculan sobre las materias obligatorias; las electivas aprobadas se informan aparte.
+type ProgramProgress struct {
+	Subjects        ProgressCounter `json:"subjects"`
+	Credits         ProgressCounter `json:"credits"`
+	Hours           ProgressCounter `json:"hours"`
+	ByYear          []YearProgress  `json:"by_year"`
+	InProgress      int             `json:"in_progress"`
+	FinalsPending   int             `json:"finals_pending"`
+	ElectivesPassed int             `json:"electives_passed"`
+	ElectiveCredits float64         `json:"elective_credits"`
+	ElectiveHours   float64         `json:"elective_hours"`
+	Average         *float64        `json:"average"`
+	// AverageWithFailures suma a las notas de las materias aprobadas las de los intentos desaprobados.
+	AverageWithFailures *float64 `json:"average_with_failures"`
+}
+
+// ComputeProgress agrega las filas de user_subjects de un programa. Las materias sin año quedan en el año 0.
+// attempts son los intentos de examen del usuario; solo se usan para el promedio con aplazos.
+func ComputeProgress(subjects []models.Subject, userSubjects []models.UserSubject, attempts []models.ExamAttempt) ProgramProgress {
+	byID := make(map[string]models.UserSubject, len(userSubjects))
+	for _, us := range userSubjects {
+		byID[us.SubjectID] = us
+	}
+
+	var progress ProgramProgress
+	years := make(map[int]*YearProgress)
+	gradeSum := 0.0
+	gradeCount := 0
+
+	for _, subject := range subjects {
+		us, ok := byID[subject.ID]
+		status := models.StatusAvailable
+		if ok {
+			status = us.Status
+		}
+		passed := IsPassed(status)
+
+		switch status {
+		case models.StatusInProgress:
+			progress.InProgress++
+		case models.StatusFinalPending:
+			progress.FinalsPending++
+		}
+		if passed && us.FinalCalification > 0 {
+			gradeSum += us.FinalCalification
+			gradeCount++
+		}
+
+		if subject.IsElective {
+			if passed {
+				progress.ElectivesPassed++
+				progress.ElectiveCredits += subject.Credits
+				progress.ElectiveHours += subject.Hours
+			}
+			continue
+		}
+
+		year := 0
+		if subject.Year != nil {
+			year = *subject.Year
+		}
+		yp, exists := years[year]
+		if !exists {
+			yp = &YearProgress{Year: year}
+			years[year] = yp
+		}
+
+		progress.Subjects.add(1, passed)
+		progress.Credits.add(subject.Credits, passed)
+		progress.Hours.add(subject.Hours, passed)
+		yp.Subjects.add(1, passed)
+		yp.Credits.add(subject.Credits, passed)
+		yp.Hours.add(subject.Hours, passed)
+	}
+
+	progress.Subjects.finish()
+	progress.Credits.finish()
+	progress.Hours.finish()
+
+	progress.ByYear = make([]YearProgress, 0, len(years))
+	for _, yp := range years {
+		yp.Subjects.finish()
+		yp.Credits.finish()
+		yp.Hours.finish()
+		progress.ByYear = append(progress.ByYear, *yp)
+	}
+	sort.Slice(progress.ByYear, func(i, j int) bool {
+		return progress.ByYear[i].Year < progress.ByYear[j].Year
+	})
+
+	if gradeCount > 0 {
+		avg := roundTo(gradeSum/float64(gradeCount), 2)
+		progress.Average = &avg
+	}
+
+	inProgram := make(map[string]struct{}, len(subjects))
+	for _, subject := range subjects {
+		inProgram[subject.ID] = struct{}{}
+	}
+	for _, attempt := range attempts {
+		if _, ok := inProgram[attempt.SubjectID]; !ok || attempt.Passed || attempt.Grade <= 0 {
+			continue
+		}
+		gradeSum += attempt.Grade
+		gradeCount++
+	}
+	if gradeCount > 0 {
+		avg := roundTo(gradeSum/float64(gradeCount), 2)
+		progress.AverageWithFailures = &avg
+	}
+
+	return progress
+}
+
+func roundTo(value float64, decimals int) float64 {
+	pow := math.Pow(10, float64(decimals))
+	return math.Round(value*pow) / pow
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxSharesPerProgram limita los links activos que un usuario puede tener por programa.
+const MaxSharesPerProgram = 10
+
+var (
+	ErrShareNotFound = errors.New("share link not found")
+	ErrTooManyShares = errors.New("too many share links for this program")
+)
+
+// CreateProgressShare guarda un link nuevo con el hash del token. Los vencidos no cuentan para
+// el límite y se borran en el mismo paso.
+func CreateProgressShare(userID string, programID string, tokenHash string, label string, expiresAt *time.Time, now time.Time) (*models.ProgressShareToken, error) {
+	share := models.ProgressShareToken{
+		ID:              uuid.NewString(),
+		UserID:          userID,
+		DegreeProgramID: programID,
+		TokenHash:       tokenHash,
+		Label:           label,
+		ExpiresAt:       expiresAt,
+	}
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND degree_program_id = ? AND expires_at <= ?", userID, programID, now).
+			Delete(&models.ProgressShareToken{}).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.ProgressShareToken{}).Where("user_id = ? AND degree_program_id = ?", userID, programID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= MaxSharesPerProgram {
+			return ErrTooManyShares
+		}
+		return tx.Create(&share).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// ListProgressShares devuelve los links del usuario, del más nuevo al más viejo; con programID
+// vacío trae los de todos sus programas.
+func ListProgressShares(userID string, programID string) ([]models.ProgressShareToken, error) {
+	shares := make([]models.ProgressShareToken, 0)
+	query := db.Db.Where("user_id = ?", userID)
+	if programID != "" {
+		query = query.Where("degree_program_id = ?", programID)
+	}
+	if err := query.Order("created_at DESC").Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// RevokeProgressShare borra el link; el token deja de funcionar en el acto.
+func RevokeProgressShare(userID string, shareID string) error {
+	result := db.Db.Where("id = ? AND user_id = ?", shareID, userID).Delete(&models.ProgressShareToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// FindProgressShare busca el link por el hash del token. Un link vencido, o de un programa en el
+// que el usuario ya no está inscripto, se trata igual que uno inexistente.
+func FindProgressShare(tokenHash string, now time.Time) (*models.ProgressShareToken, error) {
+	var share models.ProgressShareToken
+	if err := db.Db.Where("token_hash = ?", tokenHash).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareNotFound
+		}
+		return nil, err
+	}
+	if share.ExpiresAt != nil && !now.Before(*share.ExpiresAt) {
+		return nil, ErrShareNotFound
+	}
+	var enrolled int64
+	if err := db.Db.Table("user_degree_programs").
+		Where("user_id = ? AND degree_program_id = ?", share.UserID, share.DegreeProgramID).
+		Count(&enrolled).Error; err != nil {
+		return nil, err
+	}
+	if enrolled == 0 {
+		return nil, ErrShareNotFound
+	}
+
+	if err := db.Db.Model(&share).UpdateColumn("last_viewed_at", now).Error; err != nil {
+		slog.Warn("failed to record share link view", "shareID", share.ID, slog.Any("error", err))
+	}
+	share.LastViewedAt = &now
+	return &share, nil
+}
+
+// DeleteExpiredProgressShares borra los links vencidos; lo corre el job de mantenimiento.
+func DeleteExpiredProgressShares(now time.Time) (int64, error) {
+	result := db.Db.Where("expires_at <= ?", now).Delete(&models.ProgressShareToken{})
+	return result.RowsAffected, result.Error
+}
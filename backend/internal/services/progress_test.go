@@ -0,0 +1,58 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"testing"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestComputeProgress(t *testing.T) {
+	t.Parallel()
+
+	subjects := []models.Subject{
+		{ID: "a", Year: intPtr(1), Credits: 6, Hours: 96},
+		{ID: "b", Year: intPtr(1), Credits: 4, Hours: 64},
+		{ID: "c", Year: intPtr(2), Credits: 10, Hours: 128},
+		{ID: "d", Year: intPtr(2), Credits: 5, Hours: 64},
+		{ID: "e", Year: intPtr(3), Credits: 3, Hours: 48, IsElective: true},
+	}
+	userSubjects := []models.UserSubject{
+		{SubjectID: "a", Status: models.StatusPassed, FinalCalification: 8},
+		{SubjectID: "b", Status: models.StatusPassedWithDist, FinalCalification: 9},
+		{SubjectID: "c", Status: models.StatusFinalPending},
+		{SubjectID: "d", Status: models.StatusInProgress},
+		{SubjectID: "e", Status: models.StatusPassed, FinalCalification: 7},
+	}
+
+	attempts := []models.ExamAttempt{
+		{SubjectID: "a", Grade: 3},
+		{SubjectID: "a", Grade: 8, Passed: true},
+		{SubjectID: "c", Grade: 2},
+		{SubjectID: "other", Grade: 1},
+	}
+
+	got := ComputeProgress(subjects, userSubjects, attempts)
+
+	if got.Subjects.Total != 4 || got.Subjects.Completed != 2 || got.Subjects.Percentage != 50 {
+		t.Fatalf("Subjects = %+v, want 2/4 (50%%)", got.Subjects)
+	}
+	if got.Credits.Total != 25 || got.Credits.Completed != 10 || got.Credits.Percentage != 40 {
+		t.Fatalf("Credits = %+v, want 10/25 (40%%)", got.Credits)
+	}
+	if got.FinalsPending != 1 || got.InProgress != 1 {
+		t.Fatalf("FinalsPending = %d, InProgress = %d, want 1 and 1", got.FinalsPending, got.InProgress)
+	}
+	if got.ElectivesPassed != 1 || got.ElectiveCredits != 3 {
+		t.Fatalf("ElectivesPassed = %d, ElectiveCredits = %v, want 1 and 3", got.ElectivesPassed, got.ElectiveCredits)
+	}
+	if got.Average == nil || *got.Average != 8 {
+		t.Fatalf("Average = %v, want 8", got.Average)
+	}
+	if got.AverageWithFailures == nil || *got.AverageWithFailures != 5.8 {
+		t.Fatalf("AverageWithFailures = %v, want 5.8", got.AverageWithFailures)
+	}
+	if len(got.ByYear) != 2 || got.ByYear[0].Year != 1 || got.ByYear[0].Subjects.Percentage != 100 {
+		t.Fatalf("ByYear = %+v, want years 1 and 2 with year 1 complete", got.ByYear)
+	}
+}
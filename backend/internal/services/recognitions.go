@@ -0,0 +1,269 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"errors"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRecognitionNotFound = errors.New("recognition not found")
+	ErrRecognitionExists   = errors.New("recognition already exists")
+	ErrInvalidRecognition  = errors.New("invalid recognition")
+)
+
+// RecognizedSubject es la materia del otro lado de un reconocimiento.
+type RecognizedSubject struct {
+	SubjectID       string `json:"subject_id"`
+	Name            string `json:"name"`
+	DegreeProgramID string `json:"degree_program_id"`
+}
+
+// recognitionKey ordena el par para que el vínculo sea simétrico.
+func recognitionKey(a, b string) (string, string) {
+	if a < b {
+		return a, b
+	}
+	return b, a
+}
+
+// ListRecognitions devuelve las materias reconocidas como equivalentes de subjectID.
+func ListRecognitions(subjectID string) ([]RecognizedSubject, error) {
+	var links []models.SubjectRecognition
+	if err := db.Db.Where("subject_id = ? OR recognized_subject_id = ?", subjectID, subjectID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	otherIDs := make([]string, 0, len(links))
+	for _, l := range links {
+		if other, ok := recognitionOther(l, subjectID); ok {
+			otherIDs = append(otherIDs, other)
+		}
+	}
+	out := make([]RecognizedSubject, 0, len(otherIDs))
+	if len(otherIDs) == 0 {
+		return out, nil
+	}
+	var subjects []models.Subject
+	if err := db.Db.Select("id", "name", "degree_program_id").Where("id IN ?", otherIDs).Order("name ASC").Find(&subjects).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range subjects {
+		out = append(out, RecognizedSubject{SubjectID: s.ID, Name: s.Name, DegreeProgramID: s.DegreeProgramID})
+	}
+	return out, nil
+}
+
+// CreateRecognition vincula dos materias de programas distintos. Cada materia puede tener a lo
+// sumo un reconocimiento por programa, así el vínculo es uno a uno.
+func CreateRecognition(subjectID string, recognizedSubjectID string) (*models.SubjectRecognition, error) {
+	if subjectID == recognizedSubjectID {
+		return nil, ErrInvalidRecognition
+	}
+	var subjects []models.Subject
+	if err := db.Db.Select("id", "degree_program_id").Where("id IN ?", []string{subjectID, recognizedSubjectID}).Find(&subjects).Error; err != nil {
+		return nil, err
+	}
+	if len(subjects) != 2 {
+		return nil, ErrInvalidRecognition
+	}
+	if subjects[0].DegreeProgramID == subjects[1].DegreeProgramID {
+		return nil, ErrInvalidRecognition
+	}
+	programBySubject := map[string]string{subjects[0].ID: subjects[0].DegreeProgramID, subjects[1].ID: subjects[1].DegreeProgramID}
+
+	a, b := recognitionKey(subjectID, recognizedSubjectID)
+	link := models.SubjectRecognition{SubjectID: a, RecognizedSubjectID: b}
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.SubjectRecognition
+		if err := tx.Where("subject_id IN ? OR recognized_subject_id IN ?", []string{a, b}, []string{a, b}).Find(&existing).Error; err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			return tx.Omit("Subject", "RecognizedSubject").Create(&link).Error
+		}
+
+		linkedIDs := make([]string, 0, len(existing)*2)
+		for _, l := range existing {
+			if l.SubjectID == a && l.RecognizedSubjectID == b {
+				return ErrRecognitionExists
+			}
+			linkedIDs = append(linkedIDs, l.SubjectID, l.RecognizedSubjectID)
+		}
+		var linked []models.Subject
+		if err := tx.Select("id", "degree_program_id").Where("id IN ?", linkedIDs).Find(&linked).Error; err != nil {
+			return err
+		}
+		for _, s := range linked {
+			programBySubject[s.ID] = s.DegreeProgramID
+		}
+		// Cada materia puede estar vinculada a una sola materia de cada programa.
+		for _, l := range existing {
+			for _, pair := range [][2]string{{a, b}, {b, a}} {
+				if other, ok := recognitionOther(l, pair[0]); ok && programBySubject[other] == programBySubject[pair[1]] {
+					return ErrRecognitionExists
+				}
+			}
+		}
+		return tx.Omit("Subject", "RecognizedSubject").Create(&link).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func DeleteRecognition(subjectID string, recognizedSubjectID string) error {
+	a, b := recognitionKey(subjectID, recognizedSubjectID)
+	res := db.Db.Where("subject_id = ? AND recognized_subject_id = ?", a, b).Delete(&models.SubjectRecognition{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecognitionNotFound
+	}
+	return nil
+}
+
+// loadRecognizedProgress trae los reconocimientos de las materias del programa y el avance del
+// usuario en las materias vinculadas de otros programas. Sólo cuenta el avance en programas en
+// los que el usuario sigue inscripto.
+func loadRecognizedProgress(userID string, subjects []models.Subject) ([]models.SubjectRecognition, []models.UserSubject, error) {
+	if len(subjects) == 0 {
+		return nil, nil, nil
+	}
+	ids := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		ids = append(ids, s.ID)
+	}
+	var links []models.SubjectRecognition
+	if err := db.Db.Where("subject_id IN ? OR recognized_subject_id IN ?", ids, ids).Find(&links).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(links) == 0 {
+		return nil, nil, nil
+	}
+	linkedIDs := make([]string, 0, len(links)*2)
+	for _, l := range links {
+		linkedIDs = append(linkedIDs, l.SubjectID, l.RecognizedSubjectID)
+	}
+	var linked []models.UserSubject
+	if err := db.Db.Model(&models.UserSubject{}).
+		Select("user_subjects.*").
+		Joins("JOIN subjects ON subjects.id = user_subjects.subject_id").
+		Joins("JOIN user_degree_programs ON user_degree_programs.degree_program_id = subjects.degree_program_id AND user_degree_programs.user_id = user_subjects.user_id").
+		Where("user_subjects.user_id = ? AND user_subjects.subject_id IN ? AND user_subjects.status IN ?", userID, linkedIDs,
+			[]models.SubjectStatus{models.StatusPassed, models.StatusPassedWithDist}).
+		Find(&linked).Error; err != nil {
+		return nil, nil, err
+	}
+	return links, linked, nil
+}
+
+// ApplyRecognitions marca como aprobadas las materias del programa cuya materia vinculada en otro
+// programa está aprobada. No pisa materias ya aprobadas. Devuelve el avance resultante y, para
+// cada materia reconocida, la materia de la que viene.
+func ApplyRecognitions(subjects []models.Subject, userSubjects []models.UserSubject, links []models.SubjectRecognition, linkedProgress []models.UserSubject) ([]models.UserSubject, map[string]string) {
+	recognized := make(map[string]string)
+	if len(links) == 0 || len(linkedProgress) == 0 {
+		return userSubjects, recognized
+	}
+
+	inProgram := make(map[string]struct{}, len(subjects))
+	for _, s := range subjects {
+		inProgram[s.ID] = struct{}{}
+	}
+	passed := make(map[string]models.UserSubject, len(linkedProgress))
+	for _, us := range linkedProgress {
+		if IsPassed(us.Status) {
+			passed[us.SubjectID] = us
+		}
+	}
+
+	out := append([]models.UserSubject(nil), userSubjects...)
+	index := make(map[string]int, len(out))
+	for i, us := range out {
+		index[us.SubjectID] = i
+	}
+
+	sorted := append([]models.SubjectRecognition(nil), links...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].SubjectID != sorted[j].SubjectID {
+			return sorted[i].SubjectID < sorted[j].SubjectID
+		}
+		return sorted[i].RecognizedSubjectID < sorted[j].RecognizedSubjectID
+	})
+	for _, l := range sorted {
+		for _, local := range []string{l.SubjectID, l.RecognizedSubjectID} {
+			if _, ok := inProgram[local]; !ok {
+				continue
+			}
+			other, _ := recognitionOther(l, local)
+			if _, sameProgram := inProgram[other]; sameProgram {
+				continue
+			}
+			source, ok := passed[other]
+			if !ok {
+				continue
+			}
+			i, exists := index[local]
+			if exists && IsPassed(out[i].Status) {
+				continue
+			}
+			row := models.UserSubject{
+				UserID:            source.UserID,
+				SubjectID:         local,
+				Status:            models.StatusPassed,
+				FinalCalification: source.FinalCalification,
+			}
+			if exists {
+				out[i] = row
+			} else {
+				index[local] = len(out)
+				out = append(out, row)
+			}
+			recognized[local] = other
+		}
+	}
+	return out, recognized
+}
+
+// overlayRecognized marca como aprobadas en statuses las materias reconocidas que no lo están,
+// igual que ApplyRecognitions sobre las filas del usuario.
+func overlayRecognized(statuses map[string]models.SubjectStatus, recognized map[string]string) {
+	for subjectID := range recognized {
+		if !IsPassed(statuses[subjectID]) {
+			statuses[subjectID] = models.StatusPassed
+		}
+	}
+}
+
+// dropRecognizedEchoes descarta las filas aprobadas de materias reconocidas que no tienen una
+// aprobación propia guardada: son las filas sintéticas que devuelve el GET y que el cliente
+// reenvía al guardar, y no tienen que quedar como avance real.
+func dropRecognizedEchoes(records []models.UserSubject, existing map[string]*models.UserSubject, recognized map[string]string) []models.UserSubject {
+	out := records[:0]
+	for _, r := range records {
+		if _, ok := recognized[r.SubjectID]; ok && IsPassed(r.Status) {
+			if previous := existing[r.SubjectID]; previous == nil || !IsPassed(previous.Status) {
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// recognitionOther devuelve la materia del otro lado del vínculo si id participa en él.
+func recognitionOther(l models.SubjectRecognition, id string) (string, bool) {
+	switch id {
+	case l.SubjectID:
+		return l.RecognizedSubjectID, true
+	case l.RecognizedSubjectID:
+		return l.SubjectID, true
+	default:
+		return "", false
+	}
+}
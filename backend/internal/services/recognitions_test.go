@@ -0,0 +1,130 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestApplyRecognitions(t *testing.T) {
+	t.Parallel()
+
+	subjects := []models.Subject{{ID: "a1"}, {ID: "a2"}, {ID: "a3"}}
+	userSubjects := []models.UserSubject{
+		{SubjectID: "a1", Status: models.StatusFinalPending},
+		{SubjectID: "a3", Status: models.StatusPassed, FinalCalification: 9},
+	}
+	links := []models.SubjectRecognition{
+		{SubjectID: "a1", RecognizedSubjectID: "b1"},
+		{SubjectID: "a2", RecognizedSubjectID: "b2"},
+		{SubjectID: "a3", RecognizedSubjectID: "b3"},
+		{SubjectID: "a4", RecognizedSubjectID: "b4"},
+	}
+	linked := []models.UserSubject{
+		{SubjectID: "b1", Status: models.StatusPassed, FinalCalification: 7},
+		{SubjectID: "b2", Status: models.StatusFinalPending},
+		{SubjectID: "b3", Status: models.StatusPassed, FinalCalification: 6},
+		{SubjectID: "b4", Status: models.StatusPassed, FinalCalification: 8},
+	}
+
+	got, recognized := ApplyRecognitions(subjects, userSubjects, links, linked)
+
+	if len(recognized) != 1 || recognized["a1"] != "b1" {
+		t.Fatalf("recognized = %v, want only a1 from b1", recognized)
+	}
+	byID := make(map[string]models.UserSubject, len(got))
+	for _, us := range got {
+		byID[us.SubjectID] = us
+	}
+	if len(byID) != 2 {
+		t.Fatalf("user subjects = %+v, want 2 rows", got)
+	}
+	if a1 := byID["a1"]; a1.Status != models.StatusPassed || a1.FinalCalification != 7 {
+		t.Fatalf("a1 = %+v, want passed with 7", a1)
+	}
+	if a3 := byID["a3"]; a3.FinalCalification != 9 {
+		t.Fatalf("a3 = %+v, already passed subject must keep its grade", a3)
+	}
+	if userSubjects[0].Status != models.StatusFinalPending {
+		t.Fatalf("input slice was modified")
+	}
+}
+
+func TestDropRecognizedEchoes(t *testing.T) {
+	t.Parallel()
+
+	recognized := map[string]string{"a1": "b1", "a2": "b2", "a3": "b3"}
+	existing := map[string]*models.UserSubject{
+		"a2": {SubjectID: "a2", Status: models.StatusPassed, FinalCalification: 8},
+		"a3": {SubjectID: "a3", Status: models.StatusInProgress},
+	}
+	records := []models.UserSubject{
+		{SubjectID: "a1", Status: models.StatusPassed},
+		{SubjectID: "a2", Status: models.StatusPassed},
+		{SubjectID: "a3", Status: models.StatusPassed},
+		{SubjectID: "a4", Status: models.StatusPassed},
+	}
+
+	got := dropRecognizedEchoes(records, existing, recognized)
+
+	ids := make([]string, 0, len(got))
+	for _, r := range got {
+		ids = append(ids, r.SubjectID)
+	}
+	if len(ids) != 2 || ids[0] != "a2" || ids[1] != "a4" {
+		t.Fatalf("kept = %v, want [a2 a4]", ids)
+	}
+
+	statuses := map[string]models.SubjectStatus{"a1": models.StatusInProgress, "a2": models.StatusPassedWithDist}
+	overlayRecognized(statuses, recognized)
+	if statuses["a1"] != models.StatusPassed || statuses["a2"] != models.StatusPassedWithDist || statuses["a3"] != models.StatusPassed {
+		t.Fatalf("statuses = %v", statuses)
+	}
+}
+
+// useMockDB reemplaza db.Db por una conexión de sqlmock mientras dura el test. Los tests que la
+// usan no pueden ser paralelos porque db.Db es global.
+func useMockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	gdb, err := gorm.Open(mysql.New(mysql.Config{Conn: conn, SkipInitializeWithVersion: true}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("gorm: %v", err)
+	}
+	previous := db.Db
+	db.Db = gdb
+	t.Cleanup(func() {
+		db.Db = previous
+		conn.Close()
+	})
+	return mock
+}
+
+func TestLoadRecognizedProgress_OnlyCountsEnrolledPrograms(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `subject_recognitions`").
+		WillReturnRows(sqlmock.NewRows([]string{"subject_id", "recognized_subject_id"}).AddRow("a1", "b1"))
+	mock.ExpectQuery("SELECT user_subjects.\\* FROM `user_subjects` JOIN subjects ON subjects.id = user_subjects.subject_id " +
+		"JOIN user_degree_programs ON user_degree_programs.degree_program_id = subjects.degree_program_id AND user_degree_programs.user_id = user_subjects.user_id").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "subject_id", "status"}))
+
+	links, linked, err := loadRecognizedProgress("u1", []models.Subject{{ID: "a1"}})
+	if err != nil {
+		t.Fatalf("loadRecognizedProgress() error = %v", err)
+	}
+	if len(links) != 1 || len(linked) != 0 {
+		t.Fatalf("links = %+v, linked = %+v", links, linked)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
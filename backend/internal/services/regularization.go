@@ -0,0 +1,131 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"sort"
+	"time"
+)
+
+const DefaultRegularizationWarningDays = 60
+
+type RegularizationStatus struct {
+	SubjectID     string    `json:"subject_id"`
+	Name          string    `json:"name"`
+	RegularizedAt time.Time `json:"regularized_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	DaysLeft      int       `json:"days_left"`
+	Expired       bool      `json:"expired"`
+}
+
+type RegularizationReport struct {
+	ValidityMonths *int                   `json:"validity_months"`
+	Expiring       []RegularizationStatus `json:"expiring"`
+	Expired        []RegularizationStatus `json:"expired"`
+}
+
+// TrackRegularization completa RegularizedAt y RegularizationExpired del registro nuevo a partir
+// del anterior (nil si no existía). Una materia que sigue en final_pending conserva su fecha;
+// una que recién llega toma now; cualquier otro estado limpia ambos campos.
+func TrackRegularization(previous *models.UserSubject, next *models.UserSubject, now time.Time) {
+	if next.Status != models.StatusFinalPending {
+		next.RegularizedAt = nil
+		next.RegularizationExpired = false
+		return
+	}
+	if next.RegularizedAt != nil {
+		return
+	}
+	if previous != nil && previous.Status == models.StatusFinalPending && previous.RegularizedAt != nil {
+		next.RegularizedAt = previous.RegularizedAt
+		next.RegularizationExpired = previous.RegularizationExpired
+		return
+	}
+	at := now
+	next.RegularizedAt = &at
+	next.RegularizationExpired = false
+}
+
+// RegularizationExpiresAt es el último día en que la regularización sigue vigente.
+func RegularizationExpiresAt(regularizedAt time.Time, validityMonths int) time.Time {
+	return regularizedAt.AddDate(0, validityMonths, 0)
+}
+
+// EvaluateRegularizations separa las materias con final pendiente cuya regularización vence
+// dentro de warnDays y las que ya vencieron. Sin período de validez no hay nada que informar.
+func EvaluateRegularizations(subjects []models.Subject, userSubjects []models.UserSubject, validityMonths *int, now time.Time, warnDays int) RegularizationReport {
+	report := RegularizationReport{
+		ValidityMonths: validityMonths,
+		Expiring:       make([]RegularizationStatus, 0),
+		Expired:        make([]RegularizationStatus, 0),
+	}
+	if validityMonths == nil || *validityMonths <= 0 {
+		return report
+	}
+
+	names := make(map[string]string, len(subjects))
+	for _, s := range subjects {
+		names[s.ID] = s.Name
+	}
+
+	warnUntil := now.AddDate(0, 0, warnDays)
+	for _, us := range userSubjects {
+		if us.Status != models.StatusFinalPending || us.RegularizedAt == nil {
+			continue
+		}
+		name, ok := names[us.SubjectID]
+		if !ok {
+			continue
+		}
+		expiresAt := RegularizationExpiresAt(*us.RegularizedAt, *validityMonths)
+		status := RegularizationStatus{
+			SubjectID:     us.SubjectID,
+			Name:          name,
+			RegularizedAt: *us.RegularizedAt,
+			ExpiresAt:     expiresAt,
+			DaysLeft:      int(expiresAt.Sub(now).Hours() / 24),
+		}
+		switch {
+		case us.RegularizationExpired || !expiresAt.After(now):
+			status.Expired = true
+			if status.DaysLeft > 0 {
+				status.DaysLeft = 0
+			}
+			report.Expired = append(report.Expired, status)
+		case !expiresAt.After(warnUntil):
+			report.Expiring = append(report.Expiring, status)
+		}
+	}
+
+	byExpiry := func(list []RegularizationStatus) {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].ExpiresAt.Before(list[j].ExpiresAt) })
+	}
+	byExpiry(report.Expiring)
+	byExpiry(report.Expired)
+	return report
+}
+
+// MarkExpiredRegularizations marca como vencidas las regularizaciones que superaron el período
+// de validez de su programa. La materia sigue en final_pending; solo se levanta la marca.
+func MarkExpiredRegularizations(now time.Time) (int64, error) {
+	var programs []models.DegreeProgram
+	if err := db.Db.Select("id", "regularization_months").
+		Where("regularization_months IS NOT NULL AND regularization_months > 0").
+		Find(&programs).Error; err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, program := range programs {
+		cutoff := now.AddDate(0, -*program.RegularizationMonths, 0)
+		tx := db.Db.Model(&models.UserSubject{}).
+			Where("status = ? AND regularization_expired = ? AND regularized_at IS NOT NULL AND regularized_at <= ?", models.StatusFinalPending, false, cutoff).
+			Where("subject_id IN (?)", db.Db.Model(&models.Subject{}).Select("id").Where("degree_program_id = ?", program.ID)).
+			Update("regularization_expired", true)
+		if tx.Error != nil {
+			return total, tx.Error
+		}
+		total += tx.RowsAffected
+	}
+	return total, nil
+}
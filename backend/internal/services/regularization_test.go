@@ -0,0 +1,64 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"testing"
+	"time"
+)
+
+func TestTrackRegularization(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	earlier := now.AddDate(-1, 0, 0)
+
+	next := models.UserSubject{Status: models.StatusFinalPending}
+	TrackRegularization(nil, &next, now)
+	if next.RegularizedAt == nil || !next.RegularizedAt.Equal(now) {
+		t.Fatalf("new final_pending RegularizedAt = %v, want %v", next.RegularizedAt, now)
+	}
+
+	previous := models.UserSubject{Status: models.StatusFinalPending, RegularizedAt: &earlier, RegularizationExpired: true}
+	next = models.UserSubject{Status: models.StatusFinalPending}
+	TrackRegularization(&previous, &next, now)
+	if next.RegularizedAt == nil || !next.RegularizedAt.Equal(earlier) || !next.RegularizationExpired {
+		t.Fatalf("kept final_pending = %v/%v, want %v/true", next.RegularizedAt, next.RegularizationExpired, earlier)
+	}
+
+	next = models.UserSubject{Status: models.StatusPassed, RegularizedAt: &earlier, RegularizationExpired: true}
+	TrackRegularization(&previous, &next, now)
+	if next.RegularizedAt != nil || next.RegularizationExpired {
+		t.Fatalf("passed subject kept regularization data: %v/%v", next.RegularizedAt, next.RegularizationExpired)
+	}
+}
+
+func TestEvaluateRegularizations(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	at := func(y, m, d int) *time.Time {
+		v := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+		return &v
+	}
+	subjects := []models.Subject{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}, {ID: "c", Name: "C"}, {ID: "d", Name: "D"}}
+	userSubjects := []models.UserSubject{
+		{SubjectID: "a", Status: models.StatusFinalPending, RegularizedAt: at(2023, 6, 20)}, // vence 2025-06-20
+		{SubjectID: "b", Status: models.StatusFinalPending, RegularizedAt: at(2023, 1, 1)},  // vencida
+		{SubjectID: "c", Status: models.StatusFinalPending, RegularizedAt: at(2025, 1, 1)},  // vigente
+		{SubjectID: "d", Status: models.StatusPassed},
+	}
+
+	months := 24
+	report := EvaluateRegularizations(subjects, userSubjects, &months, now, 30)
+	if len(report.Expiring) != 1 || report.Expiring[0].SubjectID != "a" || report.Expiring[0].DaysLeft != 19 {
+		t.Fatalf("Expiring = %+v, want only a with 19 days left", report.Expiring)
+	}
+	if len(report.Expired) != 1 || report.Expired[0].SubjectID != "b" || !report.Expired[0].Expired {
+		t.Fatalf("Expired = %+v, want only b", report.Expired)
+	}
+
+	empty := EvaluateRegularizations(subjects, userSubjects, nil, now, 30)
+	if len(empty.Expiring) != 0 || len(empty.Expired) != 0 {
+		t.Fatalf("report without validity = %+v, want empty", empty)
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"errors"
+	"sort"
+)
+
+var ErrRequirementCycle = errors.New("circular dependency between subject requirements")
+
+// RequirementGraph modela las correlativas de un programa. Cada arista va de una materia
+// hacia la materia que exige (subject_id -> requirement_id).
+type RequirementGraph struct {
+	nodes      []string
+	nodeSet    map[string]struct{}
+	requires   map[string][]string
+	requiredBy map[string][]string
+}
+
+// NewRequirementGraph arma el grafo; las aristas que apuntan fuera de subjectIDs se ignoran.
+func NewRequirementGraph(subjectIDs []string, requirements []models.SubjectRequirement) *RequirementGraph {
+	g := &RequirementGraph{
+		nodes:      append([]string(nil), subjectIDs...),
+		nodeSet:    make(map[string]struct{}, len(subjectIDs)),
+		requires:   make(map[string][]string, len(subjectIDs)),
+		requiredBy: make(map[string][]string, len(subjectIDs)),
+	}
+	for _, id := range subjectIDs {
+		g.nodeSet[id] = struct{}{}
+	}
+	for _, r := range requirements {
+		g.AddEdge(r.SubjectID, r.RequirementID)
+	}
+	return g
+}
+
+// AddEdge agrega "subjectID exige requirementID" si ambas materias están en el grafo.
+func (g *RequirementGraph) AddEdge(subjectID string, requirementID string) {
+	if _, ok := g.nodeSet[subjectID]; !ok {
+		return
+	}
+	if _, ok := g.nodeSet[requirementID]; !ok {
+		return
+	}
+	g.requires[subjectID] = append(g.requires[subjectID], requirementID)
+	g.requiredBy[requirementID] = append(g.requiredBy[requirementID], subjectID)
+}
+
+func (g *RequirementGraph) Nodes() []string {
+	return g.nodes
+}
+
+func (g *RequirementGraph) Requirements(id string) []string {
+	return g.requires[id]
+}
+
+func (g *RequirementGraph) Dependents(id string) []string {
+	return g.requiredBy[id]
+}
+
+// FindCycle devuelve un ciclo (primer y último elemento iguales) o nil si el grafo es acíclico.
+func (g *RequirementGraph) FindCycle() []string {
+	// 0 = unvisited, 1 = in stack, 2 = done
+	state := make(map[string]int, len(g.nodes))
+	stack := make([]string, 0, len(g.nodes))
+
+	var dfs func(id string) []string
+	dfs = func(id string) []string {
+		state[id] = 1
+		stack = append(stack, id)
+		for _, dep := range g.requires[id] {
+			if state[dep] == 1 {
+				for i := range stack {
+					if stack[i] == dep {
+						cycle := append([]string(nil), stack[i:]...)
+						return append(cycle, dep)
+					}
+				}
+			}
+			if state[dep] == 0 {
+				if cycle := dfs(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = 2
+		return nil
+	}
+
+	for _, id := range g.nodes {
+		if state[id] == 0 {
+			if cycle := dfs(id); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// TopologicalOrder devuelve las materias con las correlativas siempre antes que quienes las exigen.
+func (g *RequirementGraph) TopologicalOrder() ([]string, error) {
+	pending := make(map[string]int, len(g.nodes))
+	for _, id := range g.nodes {
+		pending[id] = len(g.requires[id])
+	}
+
+	queue := make([]string, 0, len(g.nodes))
+	for _, id := range g.nodes {
+		if pending[id] == 0 {
+			queue = append(queue, id)
+		}
+	}
+
+	order := make([]string, 0, len(g.nodes))
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		order = append(order, id)
+		for _, dependent := range g.requiredBy[id] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	if len(order) != len(g.nodes) {
+		return nil, ErrRequirementCycle
+	}
+	return order, nil
+}
+
+// Heights calcula, para cada materia, la cadena más larga de materias que dependen de ella
+// (incluyéndola). Es la longitud del camino crítico que arranca en esa materia.
+func (g *RequirementGraph) Heights() (map[string]int, error) {
+	order, err := g.TopologicalOrder()
+	if err != nil {
+		return nil, err
+	}
+	heights := make(map[string]int, len(order))
+	for i := len(order) - 1; i >= 0; i-- {
+		id := order[i]
+		best := 0
+		for _, dependent := range g.requiredBy[id] {
+			if heights[dependent] > best {
+				best = heights[dependent]
+			}
+		}
+		heights[id] = best + 1
+	}
+	return heights, nil
+}
+
+// LongestChain devuelve la cadena de correlativas más larga, desde la primera materia a cursar
+// hasta la última.
+func (g *RequirementGraph) LongestChain() ([]string, error) {
+	heights, err := g.Heights()
+	if err != nil {
+		return nil, err
+	}
+
+	start := ""
+	for _, id := range g.nodes {
+		if start == "" || heights[id] > heights[start] {
+			start = id
+		}
+	}
+	if start == "" {
+		return []string{}, nil
+	}
+
+	chain := []string{start}
+	current := start
+	for {
+		next := ""
+		for _, dependent := range g.requiredBy[current] {
+			if heights[dependent] == heights[current]-1 && (next == "" || dependent < next) {
+				next = dependent
+			}
+		}
+		if next == "" {
+			return chain, nil
+		}
+		chain = append(chain, next)
+		current = next
+	}
+}
+
+// TransitiveDependents devuelve todas las materias que, directa o indirectamente, exigen id.
+func (g *RequirementGraph) TransitiveDependents(id string) []string {
+	seen := map[string]struct{}{id: {}}
+	queue := []string{id}
+	result := make([]string, 0)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range g.requiredBy[current] {
+			if _, ok := seen[dependent]; ok {
+				continue
+			}
+			seen[dependent] = struct{}{}
+			result = append(result, dependent)
+			queue = append(queue, dependent)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func edges(pairs ...string) []models.SubjectRequirement {
+	result := make([]models.SubjectRequirement, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		result = append(result, models.SubjectRequirement{SubjectID: pairs[i], RequirementID: pairs[i+1], MinStatus: models.ReqPassed})
+	}
+	return result
+}
+
+func TestRequirementGraph_FindCycle(t *testing.T) {
+	t.Parallel()
+
+	acyclic := NewRequirementGraph([]string{"a", "b", "c"}, edges("b", "a", "c", "b"))
+	if cycle := acyclic.FindCycle(); cycle != nil {
+		t.Fatalf("FindCycle() = %v, want nil", cycle)
+	}
+
+	cyclic := NewRequirementGraph([]string{"a", "b", "c"}, edges("a", "c", "b", "a", "c", "b"))
+	cycle := cyclic.FindCycle()
+	if len(cycle) != 4 || cycle[0] != cycle[len(cycle)-1] {
+		t.Fatalf("FindCycle() = %v, want a closed path of 3 subjects", cycle)
+	}
+	if _, err := cyclic.TopologicalOrder(); !errors.Is(err, ErrRequirementCycle) {
+		t.Fatalf("TopologicalOrder() error = %v, want ErrRequirementCycle", err)
+	}
+}
+
+func TestRequirementGraph_IgnoresUnknownSubjects(t *testing.T) {
+	t.Parallel()
+
+	g := NewRequirementGraph([]string{"a"}, edges("a", "other"))
+	if reqs := g.Requirements("a"); len(reqs) != 0 {
+		t.Fatalf("Requirements(a) = %v, want empty", reqs)
+	}
+}
+
+func TestRequirementGraph_LongestChainAndDependents(t *testing.T) {
+	t.Parallel()
+
+	// a <- b <- d, a <- c, e suelta
+	g := NewRequirementGraph([]string{"a", "b", "c", "d", "e"}, edges("b", "a", "c", "a", "d", "b"))
+
+	chain, err := g.LongestChain()
+	if err != nil {
+		t.Fatalf("LongestChain() error = %v", err)
+	}
+	if want := []string{"a", "b", "d"}; !reflect.DeepEqual(chain, want) {
+		t.Fatalf("LongestChain() = %v, want %v", chain, want)
+	}
+
+	if got, want := g.TransitiveDependents("a"), []string{"b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("TransitiveDependents(a) = %v, want %v", got, want)
+	}
+	if got := g.TransitiveDependents("e"); len(got) != 0 {
+		t.Fatalf("TransitiveDependents(e) = %v, want empty", got)
+	}
+}
@@ -0,0 +1,474 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxRequirementGroupDepth = 4
+
+var (
+	ErrRequirementGroupNotFound = errors.New("requirement group not found")
+	ErrInvalidRequirementGroup  = errors.New("invalid requirement group")
+)
+
+// UnmetGroup describe un grupo de requisitos que todavía no se cumple y cuánto falta.
+type UnmetGroup struct {
+	GroupID  string                      `json:"group_id"`
+	Kind     models.RequirementGroupKind `json:"kind"`
+	Required float64                     `json:"required"`
+	Current  float64                     `json:"current"`
+}
+
+// groupEvaluator resuelve grupos contra los estados de un usuario en un plan.
+type groupEvaluator struct {
+	subjects []models.Subject
+	byID     map[string]models.Subject
+	statuses map[string]models.SubjectStatus
+}
+
+func newGroupEvaluator(subjects []models.Subject, statuses map[string]models.SubjectStatus) *groupEvaluator {
+	byID := make(map[string]models.Subject, len(subjects))
+	for _, s := range subjects {
+		byID[s.ID] = s
+	}
+	return &groupEvaluator{subjects: subjects, byID: byID, statuses: statuses}
+}
+
+// scope devuelve las materias que cuenta el grupo: las explícitas o, si no hay, las del año
+// indicado. Los umbrales sin año cuentan todo el plan. La materia a cursar nunca se cuenta.
+func (e *groupEvaluator) scope(g models.RequirementGroup) []models.Subject {
+	out := make([]models.Subject, 0)
+	if len(g.Subjects) > 0 {
+		for _, item := range g.Subjects {
+			if s, ok := e.byID[item.SubjectID]; ok {
+				out = append(out, s)
+			} else {
+				out = append(out, models.Subject{ID: item.SubjectID})
+			}
+		}
+		return out
+	}
+	isThreshold := g.Kind == models.GroupCredits || g.Kind == models.GroupHours || g.Kind == models.GroupSubjectCount
+	if g.Year == nil && !isThreshold {
+		return out
+	}
+	for _, s := range e.subjects {
+		if s.ID == g.SubjectID {
+			continue
+		}
+		if g.Year != nil && (s.Year == nil || *s.Year != *g.Year) {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func (e *groupEvaluator) satisfies(subjectID string, min models.RequirementMinStatus) bool {
+	status, ok := e.statuses[subjectID]
+	if !ok {
+		status = models.StatusAvailable
+	}
+	return SatisfiesMinStatus(status, min)
+}
+
+// evaluate devuelve si el grupo se cumple junto con el valor alcanzado y el exigido. Con
+// forFinal se exige que todo esté aprobado, igual que las correlativas simples.
+func (e *groupEvaluator) evaluate(g models.RequirementGroup, forFinal bool) (bool, float64, float64) {
+	min := g.MinStatus
+	if min == "" || forFinal {
+		min = models.ReqPassed
+	}
+	scope := e.scope(g)
+
+	switch g.Kind {
+	case models.GroupCredits, models.GroupHours, models.GroupSubjectCount:
+		var current float64
+		for _, s := range scope {
+			if !e.satisfies(s.ID, min) {
+				continue
+			}
+			switch g.Kind {
+			case models.GroupCredits:
+				current += s.Credits
+			case models.GroupHours:
+				current += s.Hours
+			default:
+				current++
+			}
+		}
+		var required float64
+		if g.MinimumValue != nil {
+			required = *g.MinimumValue
+		}
+		return current >= required, current, required
+	default:
+		var met, total float64
+		for _, s := range scope {
+			total++
+			if e.satisfies(s.ID, min) {
+				met++
+			}
+		}
+		for _, child := range g.Children {
+			total++
+			if ok, _, _ := e.evaluate(child, forFinal); ok {
+				met++
+			}
+		}
+		required := total
+		if g.Kind == models.GroupAny {
+			required = 1
+			if g.MinCount != nil {
+				required = float64(*g.MinCount)
+			}
+		}
+		return met >= required, met, required
+	}
+}
+
+// EvaluateGroups revisa los grupos raíz de una materia y devuelve los que no se cumplen.
+func EvaluateGroups(groups []models.RequirementGroup, subjects []models.Subject, statuses map[string]models.SubjectStatus, forFinal bool) []UnmetGroup {
+	e := newGroupEvaluator(subjects, statuses)
+	unmet := make([]UnmetGroup, 0)
+	for _, g := range groups {
+		ok, current, required := e.evaluate(g, forFinal)
+		if !ok {
+			unmet = append(unmet, UnmetGroup{GroupID: g.ID, Kind: g.Kind, Required: required, Current: current})
+		}
+	}
+	return unmet
+}
+
+// BuildGroupTree arma el árbol a partir de las filas planas y devuelve solo los grupos raíz.
+func BuildGroupTree(flat []models.RequirementGroup) []models.RequirementGroup {
+	children := make(map[string][]models.RequirementGroup)
+	roots := make([]models.RequirementGroup, 0)
+	for _, g := range flat {
+		if g.ParentID == nil {
+			roots = append(roots, g)
+			continue
+		}
+		children[*g.ParentID] = append(children[*g.ParentID], g)
+	}
+	var attach func(g *models.RequirementGroup, depth int)
+	attach = func(g *models.RequirementGroup, depth int) {
+		g.Children = append([]models.RequirementGroup(nil), children[g.ID]...)
+		if depth >= maxRequirementGroupDepth {
+			g.Children = nil
+			return
+		}
+		for i := range g.Children {
+			attach(&g.Children[i], depth+1)
+		}
+		sortGroups(g.Children)
+	}
+	for i := range roots {
+		attach(&roots[i], 1)
+	}
+	sortGroups(roots)
+	return roots
+}
+
+func sortGroups(groups []models.RequirementGroup) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		if !groups[i].CreatedAt.Equal(groups[j].CreatedAt) {
+			return groups[i].CreatedAt.Before(groups[j].CreatedAt)
+		}
+		return groups[i].ID < groups[j].ID
+	})
+}
+
+// GroupsBySubject agrupa los grupos raíz por la materia a la que habilitan.
+func GroupsBySubject(roots []models.RequirementGroup) map[string][]models.RequirementGroup {
+	out := make(map[string][]models.RequirementGroup)
+	for _, g := range roots {
+		out[g.SubjectID] = append(out[g.SubjectID], g)
+	}
+	return out
+}
+
+// GroupRequirementEdges expresa como correlativas las materias nombradas explícitamente en
+// los grupos, para detectar ciclos con el mismo grafo que subject_requirements.
+func GroupRequirementEdges(roots []models.RequirementGroup) []models.SubjectRequirement {
+	edges := make([]models.SubjectRequirement, 0)
+	var walk func(subjectID string, g models.RequirementGroup)
+	walk = func(subjectID string, g models.RequirementGroup) {
+		for _, item := range g.Subjects {
+			edges = append(edges, models.SubjectRequirement{SubjectID: subjectID, RequirementID: item.SubjectID, MinStatus: g.MinStatus})
+		}
+		for _, child := range g.Children {
+			walk(subjectID, child)
+		}
+	}
+	for _, g := range roots {
+		walk(g.SubjectID, g)
+	}
+	return edges
+}
+
+// ValidateRequirementGroup revisa la forma del árbol: tipo, mínimos y profundidad.
+func ValidateRequirementGroup(g models.RequirementGroup) error {
+	return validateGroup(g, 1)
+}
+
+func validateGroup(g models.RequirementGroup, depth int) error {
+	if depth > maxRequirementGroupDepth {
+		return fmt.Errorf("%w: more than %d nested levels", ErrInvalidRequirementGroup, maxRequirementGroupDepth)
+	}
+	if g.MinStatus != "" && g.MinStatus != models.ReqPassed && g.MinStatus != models.ReqFinalPending {
+		return fmt.Errorf("%w: invalid min_status %q", ErrInvalidRequirementGroup, g.MinStatus)
+	}
+	seen := make(map[string]struct{}, len(g.Subjects))
+	for _, item := range g.Subjects {
+		if _, dup := seen[item.SubjectID]; dup {
+			return fmt.Errorf("%w: duplicate subject %q", ErrInvalidRequirementGroup, item.SubjectID)
+		}
+		seen[item.SubjectID] = struct{}{}
+	}
+
+	switch g.Kind {
+	case models.GroupAll, models.GroupAny:
+		if g.MinimumValue != nil {
+			return fmt.Errorf("%w: minimum_value only applies to thresholds", ErrInvalidRequirementGroup)
+		}
+		items := len(g.Subjects) + len(g.Children)
+		if items == 0 && g.Year == nil {
+			return fmt.Errorf("%w: %s group needs subjects, children or a year", ErrInvalidRequirementGroup, g.Kind)
+		}
+		if g.MinCount != nil {
+			if g.Kind != models.GroupAny || *g.MinCount < 1 {
+				return fmt.Errorf("%w: min_count must be positive and only applies to any", ErrInvalidRequirementGroup)
+			}
+			if g.Year == nil && *g.MinCount > items {
+				return fmt.Errorf("%w: min_count is greater than the number of items", ErrInvalidRequirementGroup)
+			}
+		}
+	case models.GroupCredits, models.GroupHours, models.GroupSubjectCount:
+		if g.MinimumValue == nil || *g.MinimumValue <= 0 {
+			return fmt.Errorf("%w: %s group needs a positive minimum_value", ErrInvalidRequirementGroup, g.Kind)
+		}
+		if g.MinCount != nil || len(g.Children) > 0 {
+			return fmt.Errorf("%w: thresholds cannot have min_count or children", ErrInvalidRequirementGroup)
+		}
+	default:
+		return fmt.Errorf("%w: invalid kind %q", ErrInvalidRequirementGroup, g.Kind)
+	}
+
+	for _, child := range g.Children {
+		if err := validateGroup(child, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadRequirementGroups trae los grupos de las materias indicadas ya armados como árbol.
+func LoadRequirementGroups(subjectIDs []string) ([]models.RequirementGroup, error) {
+	if len(subjectIDs) == 0 {
+		return nil, nil
+	}
+	var flat []models.RequirementGroup
+	if err := db.Db.Preload("Subjects").Where("subject_id IN ?", subjectIDs).Find(&flat).Error; err != nil {
+		return nil, err
+	}
+	return BuildGroupTree(flat), nil
+}
+
+func GetRequirementGroup(subjectID string, groupID string) (*models.RequirementGroup, error) {
+	var group models.RequirementGroup
+	if err := db.Db.Where("id = ? AND subject_id = ? AND parent_id IS NULL", groupID, subjectID).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRequirementGroupNotFound
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// SaveRequirementGroup guarda un grupo raíz completo para subject; con replaceID reemplaza el
+// árbol existente y la raíz conserva ese id. Las materias nombradas tienen que ser del mismo plan, no pueden formar
+// ciclos con las correlativas ni con otros grupos, ni chocar con un co-requisito.
+func SaveRequirementGroup(subject models.Subject, group *models.RequirementGroup, replaceID string) error {
+	if err := ValidateRequirementGroup(*group); err != nil {
+		return err
+	}
+
+	subjects, requirements, err := LoadProgramSubjects(subject.DegreeProgramID, subject.PlanVersionID)
+	if err != nil {
+		return err
+	}
+	inPlan := make(map[string]struct{}, len(subjects))
+	ids := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		inPlan[s.ID] = struct{}{}
+		ids = append(ids, s.ID)
+	}
+	if replaceID != "" {
+		group.ID = replaceID
+	}
+	assignGroupIDs(group, subject.ID, nil)
+	for _, edge := range GroupRequirementEdges([]models.RequirementGroup{*group}) {
+		if _, ok := inPlan[edge.RequirementID]; !ok || edge.RequirementID == subject.ID {
+			return fmt.Errorf("%w: subject %q is not in the same plan", ErrInvalidRequirementGroup, edge.RequirementID)
+		}
+	}
+
+	existing, err := LoadRequirementGroups(ids)
+	if err != nil {
+		return err
+	}
+	others := make([]models.RequirementGroup, 0, len(existing))
+	for _, g := range existing {
+		if g.ID != replaceID {
+			others = append(others, g)
+		}
+	}
+	edges := append(requirements, GroupRequirementEdges(append(others, *group))...)
+	if cycle := NewRequirementGraph(ids, edges).FindCycle(); cycle != nil {
+		return ErrRequirementCycle
+	}
+
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		if replaceID != "" {
+			if err := deleteGroupTree(tx, replaceID); err != nil {
+				return err
+			}
+		}
+		if err := createGroupTree(tx, *group); err != nil {
+			return err
+		}
+		return CheckCorequisiteConflicts(tx, subject.DegreeProgramID, subject.PlanVersionID)
+	})
+}
+
+func DeleteRequirementGroup(subjectID string, groupID string) error {
+	if _, err := GetRequirementGroup(subjectID, groupID); err != nil {
+		return err
+	}
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		return deleteGroupTree(tx, groupID)
+	})
+}
+
+// CreateRequirementGroupTx crea un grupo ya validado dentro de una transacción existente.
+func CreateRequirementGroupTx(tx *gorm.DB, subjectID string, group *models.RequirementGroup) error {
+	assignGroupIDs(group, subjectID, nil)
+	return createGroupTree(tx, *group)
+}
+
+func assignGroupIDs(g *models.RequirementGroup, subjectID string, parentID *string) {
+	if g.ID == "" {
+		g.ID = uuid.NewString()
+	}
+	g.SubjectID = subjectID
+	g.ParentID = parentID
+	if g.MinStatus == "" {
+		g.MinStatus = models.ReqPassed
+	}
+	for i := range g.Subjects {
+		g.Subjects[i].GroupID = g.ID
+	}
+	for i := range g.Children {
+		id := g.ID
+		assignGroupIDs(&g.Children[i], subjectID, &id)
+	}
+}
+
+func createGroupTree(tx *gorm.DB, g models.RequirementGroup) error {
+	if err := tx.Omit("Subject", "Subjects", "Children").Create(&g).Error; err != nil {
+		return err
+	}
+	if len(g.Subjects) > 0 {
+		if err := tx.Omit("Subject").Create(&g.Subjects).Error; err != nil {
+			return err
+		}
+	}
+	for _, child := range g.Children {
+		if err := createGroupTree(tx, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteGroupTree borra el grupo, sus subgrupos y las materias de cada uno.
+func deleteGroupTree(tx *gorm.DB, groupID string) error {
+	ids := []string{groupID}
+	for frontier := ids; len(frontier) > 0; {
+		var next []string
+		if err := tx.Model(&models.RequirementGroup{}).Where("parent_id IN ?", frontier).Pluck("id", &next).Error; err != nil {
+			return err
+		}
+		ids = append(ids, next...)
+		frontier = next
+	}
+	if err := tx.Where("group_id IN ?", ids).Delete(&models.RequirementGroupSubject{}).Error; err != nil {
+		return err
+	}
+	for i := len(ids) - 1; i >= 0; i-- {
+		if err := tx.Where("id = ?", ids[i]).Delete(&models.RequirementGroup{}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyRequirementGroups copia los grupos de las materias copiadas usando los IDs nuevos.
+// Las materias que no se copiaron se descartan del grupo, y los grupos all/any que quedan
+// vacíos se descartan también: si no, se darían por cumplidos.
+func copyRequirementGroups(tx *gorm.DB, subjectIDs map[string]string) error {
+	oldIDs := make([]string, 0, len(subjectIDs))
+	for oldID := range subjectIDs {
+		oldIDs = append(oldIDs, oldID)
+	}
+	var flat []models.RequirementGroup
+	if err := tx.Preload("Subjects").Where("subject_id IN ?", oldIDs).Find(&flat).Error; err != nil {
+		return err
+	}
+	for _, root := range BuildGroupTree(flat) {
+		copied, ok := remapRequirementGroup(root, subjectIDs)
+		if !ok {
+			continue
+		}
+		assignGroupIDs(&copied, subjectIDs[root.SubjectID], nil)
+		if err := createGroupTree(tx, copied); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remapRequirementGroup copia g con los IDs nuevos de subjectIDs. Devuelve false si el grupo es
+// all/any y perdió en la copia todas las materias y subgrupos que tenía; los grupos por año no
+// nombran materias y se copian siempre.
+func remapRequirementGroup(g models.RequirementGroup, subjectIDs map[string]string) (models.RequirementGroup, bool) {
+	copied := g
+	copied.ID = ""
+	copied.CreatedAt, copied.UpdatedAt = g.CreatedAt, g.UpdatedAt
+	copied.Subjects = make([]models.RequirementGroupSubject, 0, len(g.Subjects))
+	for _, item := range g.Subjects {
+		if newID, ok := subjectIDs[item.SubjectID]; ok {
+			copied.Subjects = append(copied.Subjects, models.RequirementGroupSubject{SubjectID: newID})
+		}
+	}
+	copied.Children = make([]models.RequirementGroup, 0, len(g.Children))
+	for _, child := range g.Children {
+		if remapped, ok := remapRequirementGroup(child, subjectIDs); ok {
+			copied.Children = append(copied.Children, remapped)
+		}
+	}
+	hadItems := len(g.Subjects) > 0 || len(g.Children) > 0
+	lostItems := len(copied.Subjects) == 0 && len(copied.Children) == 0
+	if (g.Kind == models.GroupAll || g.Kind == models.GroupAny) && g.Year == nil && hadItems && lostItems {
+		return copied, false
+	}
+	return copied, true
+}
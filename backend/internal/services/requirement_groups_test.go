@@ -0,0 +1,247 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func groupSubjects(ids ...string) []models.RequirementGroupSubject {
+	out := make([]models.RequirementGroupSubject, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, models.RequirementGroupSubject{SubjectID: id})
+	}
+	return out
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestEvaluateGroups(t *testing.T) {
+	t.Parallel()
+
+	subjects := []models.Subject{
+		{ID: "a", Year: intPtr(1), Credits: 6, Hours: 96},
+		{ID: "b", Year: intPtr(1), Credits: 6, Hours: 64},
+		{ID: "c", Year: intPtr(2), Credits: 8, Hours: 128},
+		{ID: "d", Year: intPtr(2), Credits: 8, Hours: 128},
+		{ID: "target", Year: intPtr(3)},
+	}
+	statuses := map[string]models.SubjectStatus{
+		"a": models.StatusPassed,
+		"b": models.StatusFinalPending,
+		"c": models.StatusFinalPending,
+		"d": models.StatusFinalPending,
+	}
+
+	tests := []struct {
+		name        string
+		group       models.RequirementGroup
+		wantMet     bool
+		wantFinal   bool
+		wantCurrent float64
+	}{
+		{
+			name:        "any of two",
+			group:       models.RequirementGroup{Kind: models.GroupAny, Subjects: groupSubjects("a", "c")},
+			wantMet:     true,
+			wantFinal:   true,
+			wantCurrent: 1,
+		},
+		{
+			name:        "two of three regularized",
+			group:       models.RequirementGroup{Kind: models.GroupAny, MinStatus: models.ReqFinalPending, MinCount: intPtr(2), Subjects: groupSubjects("a", "b", "c")},
+			wantMet:     true,
+			wantFinal:   false,
+			wantCurrent: 3,
+		},
+		{
+			name:        "all of year 2 regularized",
+			group:       models.RequirementGroup{SubjectID: "target", Kind: models.GroupAll, MinStatus: models.ReqFinalPending, Year: intPtr(2)},
+			wantMet:     true,
+			wantFinal:   false,
+			wantCurrent: 2,
+		},
+		{
+			name:        "credits threshold",
+			group:       models.RequirementGroup{SubjectID: "target", Kind: models.GroupCredits, MinimumValue: floatPtr(10)},
+			wantMet:     false,
+			wantFinal:   false,
+			wantCurrent: 6,
+		},
+		{
+			name:        "hours threshold regularized in year 1",
+			group:       models.RequirementGroup{SubjectID: "target", Kind: models.GroupHours, MinStatus: models.ReqFinalPending, MinimumValue: floatPtr(160), Year: intPtr(1)},
+			wantMet:     true,
+			wantFinal:   false,
+			wantCurrent: 160,
+		},
+		{
+			name: "all with nested any",
+			group: models.RequirementGroup{Kind: models.GroupAll, Subjects: groupSubjects("a"), Children: []models.RequirementGroup{
+				{Kind: models.GroupAny, Subjects: groupSubjects("c", "d")},
+			}},
+			wantMet:     false,
+			wantFinal:   false,
+			wantCurrent: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			unmet := EvaluateGroups([]models.RequirementGroup{tt.group}, subjects, statuses, false)
+			if got := len(unmet) == 0; got != tt.wantMet {
+				t.Fatalf("met = %v, want %v (%+v)", got, tt.wantMet, unmet)
+			}
+			if !tt.wantMet && unmet[0].Current != tt.wantCurrent {
+				t.Fatalf("current = %v, want %v", unmet[0].Current, tt.wantCurrent)
+			}
+			unmetFinal := EvaluateGroups([]models.RequirementGroup{tt.group}, subjects, statuses, true)
+			if got := len(unmetFinal) == 0; got != tt.wantFinal {
+				t.Fatalf("met for final = %v, want %v", got, tt.wantFinal)
+			}
+		})
+	}
+}
+
+func TestEvaluateEligibilityWithGroups(t *testing.T) {
+	t.Parallel()
+
+	subjects := []models.Subject{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	groups := []models.RequirementGroup{{ID: "g", SubjectID: "c", Kind: models.GroupAny, Subjects: groupSubjects("a", "b")}}
+	statuses := map[string]models.SubjectStatus{"b": models.StatusPassed}
+
+	result := EvaluateEligibilityWithGroups(subjects, nil, groups, statuses)
+	if !result["c"].CanEnroll {
+		t.Fatalf("c should be enrollable with one of the group passed: %+v", result["c"])
+	}
+
+	delete(statuses, "b")
+	result = EvaluateEligibilityWithGroups(subjects, nil, groups, statuses)
+	if result["c"].CanEnroll || len(result["c"].UnmetGroups) != 1 || result["c"].UnmetGroups[0].GroupID != "g" {
+		t.Fatalf("c should be blocked by group g: %+v", result["c"])
+	}
+}
+
+func TestValidateRequirementGroup(t *testing.T) {
+	t.Parallel()
+
+	nested := models.RequirementGroup{Kind: models.GroupAll, Subjects: groupSubjects("a")}
+	for i := 0; i < maxRequirementGroupDepth; i++ {
+		nested = models.RequirementGroup{Kind: models.GroupAll, Children: []models.RequirementGroup{nested}}
+	}
+
+	tests := []struct {
+		name    string
+		group   models.RequirementGroup
+		wantErr bool
+	}{
+		{name: "valid any", group: models.RequirementGroup{Kind: models.GroupAny, MinCount: intPtr(2), Subjects: groupSubjects("a", "b", "c")}},
+		{name: "valid year-wide all", group: models.RequirementGroup{Kind: models.GroupAll, Year: intPtr(2)}},
+		{name: "valid credits", group: models.RequirementGroup{Kind: models.GroupCredits, MinimumValue: floatPtr(120)}},
+		{name: "unknown kind", group: models.RequirementGroup{Kind: "some"}, wantErr: true},
+		{name: "empty all", group: models.RequirementGroup{Kind: models.GroupAll}, wantErr: true},
+		{name: "min count too high", group: models.RequirementGroup{Kind: models.GroupAny, MinCount: intPtr(3), Subjects: groupSubjects("a", "b")}, wantErr: true},
+		{name: "min count on all", group: models.RequirementGroup{Kind: models.GroupAll, MinCount: intPtr(1), Subjects: groupSubjects("a")}, wantErr: true},
+		{name: "threshold without minimum", group: models.RequirementGroup{Kind: models.GroupHours}, wantErr: true},
+		{name: "duplicate subject", group: models.RequirementGroup{Kind: models.GroupAll, Subjects: groupSubjects("a", "a")}, wantErr: true},
+		{name: "too deep", group: nested, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := ValidateRequirementGroup(tt.group)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidRequirementGroup) {
+				t.Fatalf("err = %v, want ErrInvalidRequirementGroup", err)
+			}
+		})
+	}
+}
+
+func TestBuildGroupTree(t *testing.T) {
+	t.Parallel()
+
+	root := "root"
+	flat := []models.RequirementGroup{
+		{ID: "child", SubjectID: "s", ParentID: &root, Kind: models.GroupAny},
+		{ID: "root", SubjectID: "s", Kind: models.GroupAll},
+	}
+	tree := BuildGroupTree(flat)
+	if len(tree) != 1 || tree[0].ID != "root" || len(tree[0].Children) != 1 || tree[0].Children[0].ID != "child" {
+		t.Fatalf("tree = %+v, want root with one child", tree)
+	}
+	edges := GroupRequirementEdges([]models.RequirementGroup{{SubjectID: "s", Subjects: groupSubjects("a"), Children: []models.RequirementGroup{{Subjects: groupSubjects("b")}}}})
+	if len(edges) != 2 || edges[0].RequirementID != "a" || edges[1].RequirementID != "b" || edges[1].SubjectID != "s" {
+		t.Fatalf("edges = %+v, want s<-a and s<-b", edges)
+	}
+}
+
+func TestRemapRequirementGroup_DropsEmptyGroups(t *testing.T) {
+	t.Parallel()
+
+	ids := map[string]string{"a": "a2"}
+	root := models.RequirementGroup{
+		Kind:     models.GroupAll,
+		Subjects: groupSubjects("a", "x"),
+		Children: []models.RequirementGroup{{Kind: models.GroupAny, Subjects: groupSubjects("x", "y")}},
+	}
+	copied, ok := remapRequirementGroup(root, ids)
+	if !ok || len(copied.Subjects) != 1 || copied.Subjects[0].SubjectID != "a2" || len(copied.Children) != 0 {
+		t.Fatalf("copied = %+v, want only a2 and the empty child dropped", copied)
+	}
+
+	if _, ok := remapRequirementGroup(models.RequirementGroup{Kind: models.GroupAny, Subjects: groupSubjects("x")}, ids); ok {
+		t.Fatal("an any group left without subjects should be dropped")
+	}
+	if _, ok := remapRequirementGroup(models.RequirementGroup{Kind: models.GroupCredits, MinimumValue: floatPtr(10)}, ids); !ok {
+		t.Fatal("threshold groups do not depend on their subjects and should be kept")
+	}
+	yearWide := models.RequirementGroup{Kind: models.GroupAll, Year: intPtr(1)}
+	if copied, ok := remapRequirementGroup(yearWide, ids); !ok || copied.Year == nil || *copied.Year != 1 {
+		t.Fatalf("copied = %+v, %v, want the year-wide group kept", copied, ok)
+	}
+	yearWithChild := models.RequirementGroup{
+		Kind:     models.GroupAny,
+		Year:     intPtr(2),
+		Children: []models.RequirementGroup{{Kind: models.GroupAll, Subjects: groupSubjects("x")}},
+	}
+	if copied, ok := remapRequirementGroup(yearWithChild, ids); !ok || len(copied.Children) != 0 {
+		t.Fatalf("copied = %+v, %v, want the year-wide group kept without the empty child", copied, ok)
+	}
+}
+
+func TestSaveRequirementGroup_ReplaceKeepsRootID(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `subjects`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "degree_program_id"}).AddRow("s1", "p1").AddRow("s2", "p1"))
+	mock.ExpectQuery("SELECT \\* FROM `subject_requirements`").WillReturnRows(sqlmock.NewRows([]string{"subject_id"}))
+	mock.ExpectQuery("SELECT \\* FROM `requirement_groups`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subject_id", "kind", "year"}).AddRow("g1", "s2", models.GroupAll, 1))
+	mock.ExpectQuery("SELECT \\* FROM `requirement_group_subjects`").WillReturnRows(sqlmock.NewRows([]string{"group_id"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `id` FROM `requirement_groups` WHERE parent_id IN").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec("DELETE FROM `requirement_group_subjects`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM `requirement_groups`").WithArgs("g1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `requirement_groups`").
+		WithArgs("g1", "s2", nil, models.GroupAll, models.ReqPassed, nil, nil, 2, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT `id` FROM `subjects`").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("s1").AddRow("s2"))
+	mock.ExpectQuery("SELECT \\* FROM `subject_corequisites`").WillReturnRows(sqlmock.NewRows([]string{"subject_id"}))
+	mock.ExpectCommit()
+
+	group := models.RequirementGroup{Kind: models.GroupAll, Year: intPtr(2)}
+	if err := SaveRequirementGroup(models.Subject{ID: "s2", DegreeProgramID: "p1"}, &group, "g1"); err != nil {
+		t.Fatalf("SaveRequirementGroup() error = %v", err)
+	}
+	if group.ID != "g1" {
+		t.Fatalf("group.ID = %q, want g1", group.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
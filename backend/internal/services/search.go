@@ -0,0 +1,238 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type SearchHitType string
+
+const (
+	SearchUniversity SearchHitType = "university"
+	SearchProgram    SearchHitType = "program"
+	SearchSubject    SearchHitType = "subject"
+)
+
+// SearchHit es un resultado de búsqueda. MatchedField indica qué campo coincidió (name,
+// location, tag o code); University y Program dan contexto a programas y materias.
+type SearchHit struct {
+	Type         SearchHitType `json:"type"`
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Score        float64       `json:"score"`
+	MatchedField string        `json:"matched_field"`
+	UniversityID string        `json:"university_id,omitempty"`
+	University   string        `json:"university,omitempty"`
+	ProgramID    string        `json:"program_id,omitempty"`
+	Program      string        `json:"program,omitempty"`
+}
+
+// SearchIndex es lo que se recorre en una búsqueda: las universidades con sus tags y sólo los
+// programas que el usuario puede ver, con las materias de su plan base y de sus versiones publicadas.
+type SearchIndex struct {
+	Universities []models.University
+	Programs     []models.DegreeProgram
+	Subjects     []models.Subject
+}
+
+var searchTypeOrder = map[SearchHitType]int{SearchUniversity: 0, SearchProgram: 1, SearchSubject: 2}
+
+// Peso de cada campo: coincidir en el nombre vale más que en un tag o en la ubicación.
+var searchFieldWeights = map[string]float64{"name": 1, "code": 0.9, "tag": 0.8, "location": 0.6}
+
+// LoadSearchPrograms trae los datos de los programas que hacen falta para buscar y para
+// decidir la visibilidad, sin sus relaciones.
+func LoadSearchPrograms() ([]models.DegreeProgram, error) {
+	var programs []models.DegreeProgram
+	if err := db.Db.Select("id", "name", "university_id", "approval_status", "public_requested").Find(&programs).Error; err != nil {
+		return nil, err
+	}
+	return programs, nil
+}
+
+// LoadSearchIndex completa el índice con las universidades y las materias de los programas
+// visibles (plan base y versiones publicadas). Las materias se cargan sólo si se buscan y ya
+// filtradas en SQL: cada palabra de la consulta tiene que aparecer en el nombre o en el código
+// (la intercalación de MySQL ignora acentos y mayúsculas); el puntaje fino lo calcula Search.
+// Una materia que se repite igual en varias versiones del mismo programa aparece una sola vez.
+func LoadSearchIndex(visible []models.DegreeProgram, query string, withSubjects bool) (*SearchIndex, error) {
+	index := &SearchIndex{Programs: visible}
+	if err := db.Db.Select("id", "name", "location").Preload("FocusTags").Find(&index.Universities).Error; err != nil {
+		return nil, err
+	}
+	tokens := searchTokens(query)
+	if !withSubjects || len(visible) == 0 || len(tokens) == 0 {
+		return index, nil
+	}
+	programIDs := make([]string, 0, len(visible))
+	for _, p := range visible {
+		programIDs = append(programIDs, p.ID)
+	}
+	published := db.Db.Model(&models.PlanVersion{}).Select("id").Where("status = ?", models.PlanVersionPublished)
+	tx := db.Db.Select("id", "name", "code", "degree_program_id", "plan_version_id").
+		Where("degree_program_id IN ?", programIDs).
+		Where("plan_version_id IS NULL OR plan_version_id IN (?)", published)
+	for _, token := range tokens {
+		pattern := "%" + token + "%"
+		tx = tx.Where("name LIKE ? OR code LIKE ?", pattern, pattern)
+	}
+	var subjects []models.Subject
+	if err := tx.Find(&subjects).Error; err != nil {
+		return nil, err
+	}
+	index.Subjects = dedupeSearchSubjects(subjects)
+	return index, nil
+}
+
+// dedupeSearchSubjects deja una materia por programa, nombre y código, prefiriendo la del plan base.
+func dedupeSearchSubjects(subjects []models.Subject) []models.Subject {
+	sort.SliceStable(subjects, func(i, j int) bool {
+		return subjects[i].PlanVersionID == nil && subjects[j].PlanVersionID != nil
+	})
+	seen := make(map[string]struct{}, len(subjects))
+	out := make([]models.Subject, 0, len(subjects))
+	for _, s := range subjects {
+		key := s.DegreeProgramID + "\x00" + FoldAccents(s.Name) + "\x00" + s.Code
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, s)
+	}
+	return out
+}
+
+// searchTokens separa el texto en palabras sin acentos ni mayúsculas.
+func searchTokens(text string) []string {
+	return strings.FieldsFunc(FoldAccents(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchScore puntúa qué tan bien el campo coincide con la consulta (0 = no coincide): igual,
+// empieza igual, todas las palabras como comienzo de palabra o todas en cualquier lugar. Dentro
+// de cada nivel suma más cuanto más del campo cubre la consulta.
+func searchScore(query []string, field string) float64 {
+	words := searchTokens(field)
+	if len(query) == 0 || len(words) == 0 {
+		return 0
+	}
+	q, f := strings.Join(query, " "), strings.Join(words, " ")
+
+	var base float64
+	switch {
+	case f == q:
+		base = 100
+	case strings.HasPrefix(f, q):
+		base = 80
+	default:
+		allPrefix, allContained := true, true
+		for _, token := range query {
+			prefix := false
+			for _, w := range words {
+				if strings.HasPrefix(w, token) {
+					prefix = true
+					break
+				}
+			}
+			allPrefix = allPrefix && prefix
+			allContained = allContained && strings.Contains(f, token)
+		}
+		switch {
+		case allPrefix:
+			base = 60
+		case allContained:
+			base = 40
+		default:
+			return 0
+		}
+	}
+	coverage := float64(len(q)) / float64(max(len(f), len(q)))
+	return base + 10*coverage
+}
+
+// bestSearchField devuelve el campo con mejor puntaje ya ponderado.
+func bestSearchField(query []string, fields map[string][]string) (float64, string) {
+	best, matched := 0.0, ""
+	for _, name := range []string{"name", "code", "tag", "location"} {
+		for _, value := range fields[name] {
+			if score := searchScore(query, value) * searchFieldWeights[name]; score > best {
+				best, matched = score, name
+			}
+		}
+	}
+	return math.Round(best*100) / 100, matched
+}
+
+// Search busca la consulta en el índice y devuelve los resultados ordenados por puntaje; a igual
+// puntaje van primero universidades, después programas y después materias. types vacío busca todo.
+func Search(index *SearchIndex, query string, types map[SearchHitType]bool) []SearchHit {
+	tokens := searchTokens(query)
+	hits := make([]SearchHit, 0)
+	if len(tokens) == 0 {
+		return hits
+	}
+	wanted := func(t SearchHitType) bool { return len(types) == 0 || types[t] }
+
+	universities := make(map[string]string, len(index.Universities))
+	for _, u := range index.Universities {
+		universities[u.ID] = u.Name
+		if !wanted(SearchUniversity) {
+			continue
+		}
+		tags := make([]string, 0, len(u.FocusTags))
+		for _, tag := range u.FocusTags {
+			tags = append(tags, tag.Tag)
+		}
+		score, field := bestSearchField(tokens, map[string][]string{"name": {u.Name}, "location": {u.Location}, "tag": tags})
+		if score > 0 {
+			hits = append(hits, SearchHit{Type: SearchUniversity, ID: u.ID, Name: u.Name, Score: score, MatchedField: field})
+		}
+	}
+
+	programs := make(map[string]models.DegreeProgram, len(index.Programs))
+	for _, p := range index.Programs {
+		programs[p.ID] = p
+		if !wanted(SearchProgram) {
+			continue
+		}
+		score, field := bestSearchField(tokens, map[string][]string{"name": {p.Name}})
+		if score > 0 {
+			hits = append(hits, SearchHit{Type: SearchProgram, ID: p.ID, Name: p.Name, Score: score, MatchedField: field,
+				UniversityID: p.UniversityID, University: universities[p.UniversityID]})
+		}
+	}
+
+	if wanted(SearchSubject) {
+		for _, s := range index.Subjects {
+			program, ok := programs[s.DegreeProgramID]
+			if !ok {
+				continue
+			}
+			score, field := bestSearchField(tokens, map[string][]string{"name": {s.Name}, "code": {s.Code}})
+			if score > 0 {
+				hits = append(hits, SearchHit{Type: SearchSubject, ID: s.ID, Name: s.Name, Score: score, MatchedField: field,
+					UniversityID: program.UniversityID, University: universities[program.UniversityID], ProgramID: program.ID, Program: program.Name})
+			}
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Type != b.Type {
+			return searchTypeOrder[a.Type] < searchTypeOrder[b.Type]
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	return hits
+}
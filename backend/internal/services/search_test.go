@@ -0,0 +1,126 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func searchFixture() *SearchIndex {
+	return &SearchIndex{
+		Universities: []models.University{
+			{ID: "utn", Name: "Universidad Tecnológica Nacional", Location: "Córdoba",
+				FocusTags: []models.UniversityTag{{UniversityID: "utn", Tag: "Ingeniería"}}},
+			{ID: "unc", Name: "Universidad Nacional de Córdoba", Location: "Córdoba"},
+		},
+		Programs: []models.DegreeProgram{
+			{ID: "isi", Name: "Ingeniería en Sistemas de Información", UniversityID: "utn"},
+			{ID: "lic", Name: "Licenciatura en Física", UniversityID: "unc"},
+		},
+		Subjects: []models.Subject{
+			{ID: "f1", Name: "Física I", Code: "FIS1", DegreeProgramID: "isi"},
+			{ID: "fm", Name: "Física Moderna", DegreeProgramID: "lic"},
+			{ID: "geo", Name: "Geofísica", DegreeProgramID: "lic"},
+			{ID: "hidden", Name: "Física", DegreeProgramID: "not-visible"},
+		},
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		types map[SearchHitType]bool
+		want  []string
+	}{
+		{"tag exacto antes que prefijo, sin acentos", "ingenieria", nil, []string{"utn", "isi"}},
+		{"con acentos y mayúsculas", "INGENIERÍA", nil, []string{"utn", "isi"}},
+		{"prefijo antes que contenido", "fisica", nil, []string{"f1", "fm", "lic", "geo"}},
+		{"palabras en cualquier orden", "fisica licenciatura", nil, []string{"lic"}},
+		{"ubicación", "cordoba", nil, []string{"unc", "utn"}},
+		{"código de materia", "fis1", nil, []string{"f1"}},
+		{"filtro por tipo", "fisica", map[SearchHitType]bool{SearchProgram: true}, []string{"lic"}},
+		{"sin resultados", "química", nil, []string{}},
+		{"sólo separadores", "--", nil, []string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			hits := Search(searchFixture(), tc.query, tc.types)
+			got := make([]string, 0, len(hits))
+			for _, h := range hits {
+				got = append(got, h.ID)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("hits = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("hits = %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSearch_HitContext(t *testing.T) {
+	t.Parallel()
+
+	hits := Search(searchFixture(), "fisica i", map[SearchHitType]bool{SearchSubject: true})
+	if len(hits) == 0 {
+		t.Fatal("expected hits")
+	}
+	h := hits[0]
+	if h.ID != "f1" || h.Type != SearchSubject || h.MatchedField != "name" || h.Score != 110 {
+		t.Fatalf("hit = %+v", h)
+	}
+	if h.ProgramID != "isi" || h.Program != "Ingeniería en Sistemas de Información" || h.UniversityID != "utn" || h.University != "Universidad Tecnológica Nacional" {
+		t.Fatalf("hit context = %+v", h)
+	}
+}
+
+func TestDedupeSearchSubjects_PrefersBasePlan(t *testing.T) {
+	t.Parallel()
+
+	v2 := "v2"
+	got := dedupeSearchSubjects([]models.Subject{
+		{ID: "f1-v2", Name: "Física I", Code: "FIS1", DegreeProgramID: "isi", PlanVersionID: &v2},
+		{ID: "f1", Name: "Fisica I", Code: "FIS1", DegreeProgramID: "isi"},
+		{ID: "f2-v2", Name: "Física II", Code: "FIS2", DegreeProgramID: "isi", PlanVersionID: &v2},
+		{ID: "f1-lic", Name: "Física I", Code: "FIS1", DegreeProgramID: "lic"},
+	})
+	ids := make([]string, 0, len(got))
+	for _, s := range got {
+		ids = append(ids, s.ID)
+	}
+	if len(ids) != 3 || ids[0] != "f1" || ids[1] != "f1-lic" || ids[2] != "f2-v2" {
+		t.Fatalf("ids = %v, want [f1 f1-lic f2-v2]", ids)
+	}
+}
+
+func TestLoadSearchIndex_FiltersSubjectsInSQL(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT `id`,`name`,`location` FROM `universities`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "location"}))
+	mock.ExpectQuery("SELECT `id`,`name`,`code`,`degree_program_id`,`plan_version_id` FROM `subjects` "+
+		"WHERE degree_program_id IN \\(\\?\\) AND \\(plan_version_id IS NULL OR plan_version_id IN \\(SELECT `id` FROM `plan_versions` WHERE status = \\?\\)\\) "+
+		"AND \\(name LIKE \\? OR code LIKE \\?\\) AND \\(name LIKE \\? OR code LIKE \\?\\)").
+		WithArgs("isi", models.PlanVersionPublished, "%fisica%", "%fisica%", "%1%", "%1%").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "code", "degree_program_id", "plan_version_id"}).
+			AddRow("f1", "Física I", "FIS1", "isi", nil))
+
+	index, err := LoadSearchIndex([]models.DegreeProgram{{ID: "isi"}}, "Física 1", true)
+	if err != nil {
+		t.Fatalf("LoadSearchIndex() error = %v", err)
+	}
+	if len(index.Subjects) != 1 || index.Subjects[0].ID != "f1" {
+		t.Fatalf("subjects = %+v, want f1", index.Subjects)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"fmt"
+	"strings"
+)
+
+const (
+	maxSeedCodeLen = 64
+	maxSeedNameLen = 191
+)
+
+var validSeedTerms = map[models.SubjectTerm]struct{}{
+	models.TermAnnual:    {},
+	models.TermSemester:  {},
+	models.TermQuarterly: {},
+	models.TermBimonthly: {},
+}
+
+// SeedError es el error de ValidateSeed. SubjectCodes son las materias involucradas (vacío si el
+// problema no es de una materia, como en los pools y reglas de electivas).
+type SeedError struct {
+	SubjectCodes []string
+	Message      string
+}
+
+func (e *SeedError) Error() string {
+	return e.Message
+}
+
+func seedSubjectError(codes []string, format string, args ...any) *SeedError {
+	return &SeedError{SubjectCodes: codes, Message: fmt.Sprintf(format, args...)}
+}
+
+// ValidateSeed normaliza y valida las materias del seed: códigos únicos, referencias a códigos
+// existentes, grupos bien formados y sin ciclos. El error es un *SeedError y su mensaje se puede
+// mostrar tal cual al usuario. No revisa el bloque degreeProgram, que depende de si el seed crea
+// o actualiza un programa.
+func ValidateSeed(payload *models.JsonToDegreeProgram) error {
+	codeMap := make(map[string]struct{}, len(payload.Subjects))
+	for i := range payload.Subjects {
+		payload.Subjects[i].Code = strings.TrimSpace(payload.Subjects[i].Code)
+		payload.Subjects[i].Name = strings.TrimSpace(payload.Subjects[i].Name)
+		s := payload.Subjects[i]
+
+		if s.Code == "" {
+			return seedSubjectError(nil, "subject at index %d is missing a code", i)
+		}
+		if len(s.Code) > maxSeedCodeLen {
+			return seedSubjectError([]string{s.Code}, "subject code %q is longer than %d characters", s.Code, maxSeedCodeLen)
+		}
+		if s.Name == "" {
+			return seedSubjectError([]string{s.Code}, "subject %q is missing a name", s.Code)
+		}
+		if _, valid := validSeedTerms[s.Term]; !valid {
+			return seedSubjectError([]string{s.Code}, "subject %q has invalid term %q", s.Code, s.Term)
+		}
+		if s.Credits < 0 || s.Hours < 0 {
+			return seedSubjectError([]string{s.Code}, "subject %q cannot have negative credits or hours", s.Code)
+		}
+		if _, exists := codeMap[s.Code]; exists {
+			return seedSubjectError([]string{s.Code}, "duplicate subject code %q", s.Code)
+		}
+		codeMap[s.Code] = struct{}{}
+	}
+
+	for _, s := range payload.Subjects {
+		seen := make(map[string]struct{}, len(s.Requirements))
+		for _, req := range s.Requirements {
+			if _, exists := codeMap[req.SubjectCode]; !exists {
+				return seedSubjectError([]string{s.Code}, "subject %q references unknown requirement code %q", s.Code, req.SubjectCode)
+			}
+			if req.Type != models.SeedRequirementApproved && req.Type != models.SeedRequirementRegularize && req.Type != models.SeedRequirementCorequisite {
+				return seedSubjectError([]string{s.Code}, "subject %q has invalid requirement type %q", s.Code, req.Type)
+			}
+			if _, dup := seen[req.SubjectCode]; dup {
+				return seedSubjectError([]string{s.Code}, "subject %q lists requirement %q more than once", s.Code, req.SubjectCode)
+			}
+			seen[req.SubjectCode] = struct{}{}
+		}
+		for _, group := range s.RequirementGroups {
+			if err := validateSeedRequirementGroup(group, codeMap); err != nil {
+				return seedSubjectError([]string{s.Code}, "subject %q: %s", s.Code, err.Error())
+			}
+		}
+	}
+
+	if err := validateSeedElectives(payload, codeMap); err != nil {
+		return &SeedError{Message: err.Error()}
+	}
+	if err := detectSeedCycles(payload.Subjects); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateSeedElectives revisa que los pools tengan código único y sólo materias del seed, y que
+// cada regla apunte a un pool existente con un rango de años y un mínimo válidos.
+func validateSeedElectives(payload *models.JsonToDegreeProgram, subjectCodes map[string]struct{}) error {
+	poolCodes := make(map[string]struct{}, len(payload.ElectivePools))
+	for i := range payload.ElectivePools {
+		payload.ElectivePools[i].Code = strings.TrimSpace(payload.ElectivePools[i].Code)
+		payload.ElectivePools[i].Name = strings.TrimSpace(payload.ElectivePools[i].Name)
+		payload.ElectivePools[i].Description = strings.TrimSpace(payload.ElectivePools[i].Description)
+		pool := payload.ElectivePools[i]
+
+		if pool.Code == "" {
+			return fmt.Errorf("elective pool at index %d is missing a code", i)
+		}
+		if _, exists := poolCodes[pool.Code]; exists {
+			return fmt.Errorf("duplicate elective pool code %q", pool.Code)
+		}
+		poolCodes[pool.Code] = struct{}{}
+		if pool.Name == "" || len(pool.Name) > maxSeedNameLen {
+			return fmt.Errorf("elective pool %q needs a name of at most %d characters", pool.Code, maxSeedNameLen)
+		}
+		if len(pool.Description) > maxSeedNameLen {
+			return fmt.Errorf("elective pool %q has a description longer than %d characters", pool.Code, maxSeedNameLen)
+		}
+		inPool := make(map[string]struct{}, len(pool.SubjectCodes))
+		for _, code := range pool.SubjectCodes {
+			if _, exists := subjectCodes[code]; !exists {
+				return fmt.Errorf("elective pool %q references unknown subject code %q", pool.Code, code)
+			}
+			if _, dup := inPool[code]; dup {
+				return fmt.Errorf("elective pool %q lists subject %q more than once", pool.Code, code)
+			}
+			inPool[code] = struct{}{}
+		}
+	}
+
+	for i, rule := range payload.ElectiveRules {
+		if _, exists := poolCodes[rule.PoolCode]; !exists {
+			return fmt.Errorf("elective rule at index %d references unknown pool code %q", i, rule.PoolCode)
+		}
+		switch rule.RequirementType {
+		case models.RequirementHours, models.RequirementCredits, models.RequirementSubjectCount:
+		default:
+			return fmt.Errorf("elective rule at index %d has invalid requirement type %q", i, rule.RequirementType)
+		}
+		if rule.AppliesFromYear <= 0 {
+			return fmt.Errorf("elective rule at index %d needs appliesFromYear greater than 0", i)
+		}
+		if rule.AppliesToYear != nil && *rule.AppliesToYear < rule.AppliesFromYear {
+			return fmt.Errorf("elective rule at index %d has appliesToYear before appliesFromYear", i)
+		}
+		if rule.MinimumValue <= 0 {
+			return fmt.Errorf("elective rule at index %d needs a minimumValue greater than 0", i)
+		}
+	}
+	return nil
+}
+
+// SeedMinStatus traduce el tipo de correlativa del seed al estado mínimo guardado.
+func SeedMinStatus(t models.SeedRequirementType) models.RequirementMinStatus {
+	if t == models.SeedRequirementRegularize {
+		return models.ReqFinalPending
+	}
+	return models.ReqPassed
+}
+
+func detectSeedCycles(subjects []models.SeedSubject) *SeedError {
+	codes := make([]string, 0, len(subjects))
+	requirements := make([]models.SubjectRequirement, 0)
+	corequisites := make([]models.SubjectCorequisite, 0)
+	groups := make([]models.RequirementGroup, 0)
+	for _, s := range subjects {
+		codes = append(codes, s.Code)
+		for _, r := range s.Requirements {
+			// Los co-requisitos pueden ser mutuos: no forman parte del grafo de correlativas.
+			if r.Type == models.SeedRequirementCorequisite {
+				corequisites = append(corequisites, models.SubjectCorequisite{SubjectID: s.Code, CorequisiteID: r.SubjectCode})
+				continue
+			}
+			requirements = append(requirements, models.SubjectRequirement{SubjectID: s.Code, RequirementID: r.SubjectCode})
+		}
+		for _, g := range s.RequirementGroups {
+			group := SeedGroupToModel(g, nil)
+			group.SubjectID = s.Code
+			groups = append(groups, group)
+		}
+	}
+	requirements = append(requirements, GroupRequirementEdges(groups)...)
+
+	if cycle := NewRequirementGraph(codes, requirements).FindCycle(); cycle != nil {
+		return seedSubjectError(cycle, "circular dependency detected involving subject %q", cycle[0])
+	}
+	if co, conflict := FindCorequisiteConflict(codes, requirements, corequisites); conflict {
+		return seedSubjectError([]string{co.SubjectID, co.CorequisiteID}, "subject %q cannot have %q as both corequisite and prerequisite", co.SubjectID, co.CorequisiteID)
+	}
+	return nil
+}
+
+// SeedGroupToModel traduce un grupo del seed; con ids nil las materias quedan con su código.
+func SeedGroupToModel(g models.SeedRequirementGroup, ids map[string]string) models.RequirementGroup {
+	group := models.RequirementGroup{
+		Kind:         g.Kind,
+		MinStatus:    models.ReqPassed,
+		MinCount:     g.MinCount,
+		MinimumValue: g.MinimumValue,
+		Year:         g.Year,
+	}
+	if g.Type == models.SeedRequirementRegularize {
+		group.MinStatus = models.ReqFinalPending
+	}
+	for _, code := range g.SubjectCodes {
+		id := code
+		if ids != nil {
+			id = ids[code]
+		}
+		group.Subjects = append(group.Subjects, models.RequirementGroupSubject{SubjectID: id})
+	}
+	for _, child := range g.Groups {
+		group.Children = append(group.Children, SeedGroupToModel(child, ids))
+	}
+	return group
+}
+
+func validateSeedRequirementGroup(g models.SeedRequirementGroup, codes map[string]struct{}) error {
+	if g.Type != "" && g.Type != models.SeedRequirementApproved && g.Type != models.SeedRequirementRegularize {
+		return fmt.Errorf("invalid requirement group type %q", g.Type)
+	}
+	for _, code := range g.SubjectCodes {
+		if _, ok := codes[code]; !ok {
+			return fmt.Errorf("requirement group references unknown subject code %q", code)
+		}
+	}
+	for _, child := range g.Groups {
+		if err := validateSeedRequirementGroup(child, codes); err != nil {
+			return err
+		}
+	}
+	return ValidateRequirementGroup(SeedGroupToModel(g, nil))
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func seedSubject(code string, reqs ...models.SeedRequirement) models.SeedSubject {
+	return models.SeedSubject{Code: code, Name: "Materia " + code, SubjectYear: 1, Term: models.TermAnnual, Requirements: reqs}
+}
+
+func TestValidateSeed(t *testing.T) {
+	t.Parallel()
+
+	approved := func(code string) models.SeedRequirement {
+		return models.SeedRequirement{SubjectCode: code, Type: models.SeedRequirementApproved}
+	}
+	tests := []struct {
+		name     string
+		subjects []models.SeedSubject
+		wantErr  string
+	}{
+		{name: "valid", subjects: []models.SeedSubject{seedSubject("A"), seedSubject("B", approved("A"))}},
+		{name: "missing code", subjects: []models.SeedSubject{seedSubject("  ")}, wantErr: "missing a code"},
+		{name: "code too long", subjects: []models.SeedSubject{seedSubject(strings.Repeat("X", maxSeedCodeLen+1))}, wantErr: "longer than"},
+		{name: "duplicate code", subjects: []models.SeedSubject{seedSubject("A"), seedSubject("A")}, wantErr: "duplicate subject code"},
+		{name: "unknown requirement", subjects: []models.SeedSubject{seedSubject("A", approved("Z"))}, wantErr: "unknown requirement code"},
+		{name: "repeated requirement", subjects: []models.SeedSubject{seedSubject("A"), seedSubject("B", approved("A"), approved("A"))}, wantErr: "more than once"},
+		{name: "cycle", subjects: []models.SeedSubject{seedSubject("A", approved("B")), seedSubject("B", approved("A"))}, wantErr: "circular dependency"},
+		{name: "mutual corequisites", subjects: []models.SeedSubject{
+			seedSubject("A", models.SeedRequirement{SubjectCode: "B", Type: models.SeedRequirementCorequisite}),
+			seedSubject("B", models.SeedRequirement{SubjectCode: "A", Type: models.SeedRequirementCorequisite}),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := ValidateSeed(&models.JsonToDegreeProgram{Subjects: tt.subjects})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSeed_ErrorNamesSubjects(t *testing.T) {
+	t.Parallel()
+
+	approved := func(code string) models.SeedRequirement {
+		return models.SeedRequirement{SubjectCode: code, Type: models.SeedRequirementApproved}
+	}
+	err := ValidateSeed(&models.JsonToDegreeProgram{Subjects: []models.SeedSubject{
+		seedSubject("A"), seedSubject("B", approved("C")), seedSubject("C", approved("B")),
+	}})
+	var seedErr *SeedError
+	if !errors.As(err, &seedErr) {
+		t.Fatalf("err = %v, want *SeedError", err)
+	}
+	got := append([]string(nil), seedErr.SubjectCodes...)
+	sort.Strings(got)
+	if len(got) < 2 || got[0] != "B" || got[len(got)-1] != "C" {
+		t.Fatalf("subject codes = %v, want the cycle B-C", seedErr.SubjectCodes)
+	}
+}
+
+func TestSubjectCodes_KeepsPersistedCodes(t *testing.T) {
+	t.Parallel()
+
+	codes := SubjectCodes([]models.Subject{
+		{ID: "a", Name: "Física", Year: intPtr(1)},
+		{ID: "b", Name: "Otra", Code: "FISICA", Year: intPtr(2)},
+	})
+	if codes["b"] != "FISICA" || codes["a"] != "FISICA-2" {
+		t.Fatalf("codes = %v, want persisted FISICA kept and generated one suffixed", codes)
+	}
+}
+
+func TestBuildSeedDiff(t *testing.T) {
+	t.Parallel()
+
+	regularized := models.SeedRequirement{SubjectCode: "A", Type: models.SeedRequirementRegularize}
+	approved := models.SeedRequirement{SubjectCode: "A", Type: models.SeedRequirementApproved}
+	current := []models.SeedSubject{seedSubject("A"), seedSubject("B", approved), seedSubject("C"), seedSubject("D")}
+	incoming := []models.SeedSubject{seedSubject("A"), seedSubject("B", regularized), seedSubject("C"), seedSubject("E", approved)}
+	incoming[2].Credits = 6
+	ids := map[string]string{"A": "id-a", "B": "id-b", "C": "id-c", "D": "id-d"}
+
+	diff := BuildSeedDiff(current, incoming, ids)
+	if diff.Unchanged != 1 {
+		t.Fatalf("unchanged = %d, want 1", diff.Unchanged)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Code != "E" || len(diff.Added[0].RequirementsAdded) != 1 {
+		t.Fatalf("added = %+v, want E with its requirement", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].SubjectID != "id-d" {
+		t.Fatalf("removed = %+v, want D", diff.Removed)
+	}
+	if len(diff.Updated) != 2 {
+		t.Fatalf("updated = %+v, want B and C", diff.Updated)
+	}
+	b, c := diff.Updated[0], diff.Updated[1]
+	if len(b.RequirementsAdded) != 1 || b.RequirementsAdded[0] != regularized || len(b.RequirementsRemoved) != 1 || b.RequirementsRemoved[0] != approved || len(b.Changes) != 0 {
+		t.Fatalf("B = %+v, want requirement switched to regularized", b)
+	}
+	if len(c.Changes) != 1 || c.Changes[0].Field != "credits" || c.SubjectID != "id-c" {
+		t.Fatalf("C = %+v, want credits change", c)
+	}
+}
+
+func TestBuildSeedElectivesDiff(t *testing.T) {
+	t.Parallel()
+
+	two := 2
+	rule := func(pool string, from int, to *int, min float64) models.SeedElectiveRule {
+		return models.SeedElectiveRule{PoolCode: pool, AppliesFromYear: from, AppliesToYear: to, RequirementType: models.RequirementCredits, MinimumValue: min}
+	}
+	current := models.JsonToDegreeProgram{
+		ElectivePools: []models.SeedElectivePool{
+			{Code: "HUMANIDADES", Name: "Humanidades", SubjectCodes: []string{"A", "B"}},
+			{Code: "OPTATIVAS", Name: "Optativas", SubjectCodes: []string{"C"}},
+			{Code: "VIEJO", Name: "Viejo", SubjectCodes: []string{"D"}},
+		},
+		ElectiveRules: []models.SeedElectiveRule{rule("HUMANIDADES", 1, &two, 4), rule("OPTATIVAS", 3, nil, 8)},
+	}
+	incoming := models.JsonToDegreeProgram{
+		ElectivePools: []models.SeedElectivePool{
+			{Code: "HUMANIDADES", Name: "Humanidades", SubjectCodes: []string{"B", "E"}},
+			{Code: "OPTATIVAS", Name: "Optativas", SubjectCodes: []string{"C"}},
+			{Code: "NUEVO", Name: "Nuevo", SubjectCodes: []string{"D"}},
+		},
+		ElectiveRules: []models.SeedElectiveRule{rule("OPTATIVAS", 3, nil, 8), rule("HUMANIDADES", 1, &two, 6)},
+	}
+	poolIDs := map[string]string{"HUMANIDADES": "pool-h", "OPTATIVAS": "pool-o", "VIEJO": "pool-v"}
+
+	var diff SeedDiff
+	BuildSeedElectivesDiff(&diff, current, incoming, poolIDs)
+	if len(diff.PoolsAdded) != 1 || diff.PoolsAdded[0].Code != "NUEVO" || len(diff.PoolsAdded[0].SubjectsAdded) != 1 {
+		t.Fatalf("pools added = %+v, want NUEVO with D", diff.PoolsAdded)
+	}
+	if len(diff.PoolsRemoved) != 1 || diff.PoolsRemoved[0].PoolID != "pool-v" {
+		t.Fatalf("pools removed = %+v, want VIEJO", diff.PoolsRemoved)
+	}
+	if len(diff.PoolsUpdated) != 1 {
+		t.Fatalf("pools updated = %+v, want only HUMANIDADES", diff.PoolsUpdated)
+	}
+	h := diff.PoolsUpdated[0]
+	if h.PoolID != "pool-h" || len(h.SubjectsAdded) != 1 || h.SubjectsAdded[0] != "E" || len(h.SubjectsRemoved) != 1 || h.SubjectsRemoved[0] != "A" {
+		t.Fatalf("HUMANIDADES = %+v, want E added and A removed", h)
+	}
+	if len(diff.RulesAdded) != 1 || diff.RulesAdded[0].MinimumValue != 6 || len(diff.RulesRemoved) != 1 || diff.RulesRemoved[0].MinimumValue != 4 {
+		t.Fatalf("rules added = %+v removed = %+v, want the HUMANIDADES minimum switched to 6", diff.RulesAdded, diff.RulesRemoved)
+	}
+
+	var same SeedDiff
+	BuildSeedElectivesDiff(&same, current, current, poolIDs)
+	if len(same.PoolsAdded)+len(same.PoolsUpdated)+len(same.PoolsRemoved)+len(same.RulesAdded)+len(same.RulesRemoved) != 0 {
+		t.Fatalf("diff of identical electives = %+v, want empty", same)
+	}
+}
+
+func TestSameSeedGroups_IgnoresCodeOrder(t *testing.T) {
+	t.Parallel()
+
+	a := []models.SeedRequirementGroup{{Kind: models.GroupAny, Type: models.SeedRequirementApproved, SubjectCodes: []string{"B", "A"}}}
+	b := []models.SeedRequirementGroup{{Kind: models.GroupAny, SubjectCodes: []string{"A", "B"}}}
+	if !sameSeedGroups(a, b) {
+		t.Fatal("groups with the same codes in another order should be equal")
+	}
+	b[0].Kind = models.GroupAll
+	if sameSeedGroups(a, b) {
+		t.Fatal("groups with different kind should differ")
+	}
+}
+
+func TestValidateSeed_Electives(t *testing.T) {
+	t.Parallel()
+
+	valid := func() models.JsonToDegreeProgram {
+		return models.JsonToDegreeProgram{
+			Subjects:      []models.SeedSubject{seedSubject("A"), seedSubject("E1"), seedSubject("E2")},
+			ElectivePools: []models.SeedElectivePool{{Code: " P ", Name: "Electivas", SubjectCodes: []string{"E1", "E2"}}},
+			ElectiveRules: []models.SeedElectiveRule{{PoolCode: "P", AppliesFromYear: 3, RequirementType: models.RequirementCredits, MinimumValue: 8}},
+		}
+	}
+	tests := []struct {
+		name    string
+		mutate  func(p *models.JsonToDegreeProgram)
+		wantErr string
+	}{
+		{name: "valid", mutate: func(p *models.JsonToDegreeProgram) {}},
+		{name: "negative credits", mutate: func(p *models.JsonToDegreeProgram) { p.Subjects[0].Credits = -1 }, wantErr: "negative credits"},
+		{name: "pool without code", mutate: func(p *models.JsonToDegreeProgram) { p.ElectivePools[0].Code = "" }, wantErr: "missing a code"},
+		{name: "duplicate pool", mutate: func(p *models.JsonToDegreeProgram) {
+			p.ElectivePools = append(p.ElectivePools, models.SeedElectivePool{Code: "P", Name: "Otra"})
+		}, wantErr: "duplicate elective pool code"},
+		{name: "unknown pool subject", mutate: func(p *models.JsonToDegreeProgram) { p.ElectivePools[0].SubjectCodes = []string{"Z"} }, wantErr: "unknown subject code"},
+		{name: "repeated pool subject", mutate: func(p *models.JsonToDegreeProgram) { p.ElectivePools[0].SubjectCodes = []string{"E1", "E1"} }, wantErr: "more than once"},
+		{name: "unknown rule pool", mutate: func(p *models.JsonToDegreeProgram) { p.ElectiveRules[0].PoolCode = "X" }, wantErr: "unknown pool code"},
+		{name: "invalid rule type", mutate: func(p *models.JsonToDegreeProgram) { p.ElectiveRules[0].RequirementType = "points" }, wantErr: "invalid requirement type"},
+		{name: "inverted years", mutate: func(p *models.JsonToDegreeProgram) { p.ElectiveRules[0].AppliesToYear = intPtr(2) }, wantErr: "before appliesFromYear"},
+		{name: "zero minimum", mutate: func(p *models.JsonToDegreeProgram) { p.ElectiveRules[0].MinimumValue = 0 }, wantErr: "minimumValue"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			payload := valid()
+			tt.mutate(&payload)
+			err := ValidateSeed(&payload)
+			if tt.wantErr == "" {
+				if err != nil || payload.ElectivePools[0].Code != "P" {
+					t.Fatalf("err = %v, pool code = %q", err, payload.ElectivePools[0].Code)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
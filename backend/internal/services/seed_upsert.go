@@ -0,0 +1,551 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrSeedRemovesProgress = errors.New("seed removes subjects with user progress")
+
+type SeedFieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// SeedSubjectDiff describe qué le pasa a una materia al reimportar el seed.
+type SeedSubjectDiff struct {
+	Code                string                   `json:"code"`
+	Name                string                   `json:"name"`
+	SubjectID           string                   `json:"subject_id,omitempty"`
+	Changes             []SeedFieldChange        `json:"changes,omitempty"`
+	RequirementsAdded   []models.SeedRequirement `json:"requirements_added,omitempty"`
+	RequirementsRemoved []models.SeedRequirement `json:"requirements_removed,omitempty"`
+	GroupsChanged       bool                     `json:"groups_changed,omitempty"`
+	// UsersWithProgress cuenta, en las materias a borrar, los usuarios que tienen avance cargado.
+	UsersWithProgress int64 `json:"users_with_progress,omitempty"`
+}
+
+// SeedPoolDiff describe qué le pasa a un pool de electivas al reimportar el seed.
+type SeedPoolDiff struct {
+	Code            string            `json:"code"`
+	Name            string            `json:"name"`
+	PoolID          string            `json:"pool_id,omitempty"`
+	Changes         []SeedFieldChange `json:"changes,omitempty"`
+	SubjectsAdded   []string          `json:"subjects_added,omitempty"`
+	SubjectsRemoved []string          `json:"subjects_removed,omitempty"`
+}
+
+type SeedDiff struct {
+	ProgramID     string            `json:"program_id"`
+	PlanVersionID *string           `json:"plan_version_id,omitempty"`
+	DryRun        bool              `json:"dry_run"`
+	Added         []SeedSubjectDiff `json:"added"`
+	Updated       []SeedSubjectDiff `json:"updated"`
+	Removed       []SeedSubjectDiff `json:"removed"`
+	Unchanged     int               `json:"unchanged"`
+	// CodesAssigned son las materias existentes que todavía no tenían código guardado.
+	CodesAssigned int `json:"codes_assigned"`
+
+	PoolsAdded   []SeedPoolDiff            `json:"pools_added"`
+	PoolsUpdated []SeedPoolDiff            `json:"pools_updated"`
+	PoolsRemoved []SeedPoolDiff            `json:"pools_removed"`
+	RulesAdded   []models.SeedElectiveRule `json:"rules_added"`
+	RulesRemoved []models.SeedElectiveRule `json:"rules_removed"`
+}
+
+// BuildSeedDiff compara las materias actuales (en formato seed) con las del seed entrante,
+// emparejándolas por código. ids traduce los códigos actuales a ids de materia.
+func BuildSeedDiff(current []models.SeedSubject, incoming []models.SeedSubject, ids map[string]string) SeedDiff {
+	diff := SeedDiff{Added: []SeedSubjectDiff{}, Updated: []SeedSubjectDiff{}, Removed: []SeedSubjectDiff{}}
+
+	currentByCode := make(map[string]models.SeedSubject, len(current))
+	for _, s := range current {
+		currentByCode[s.Code] = s
+	}
+	incomingCodes := make(map[string]struct{}, len(incoming))
+	for _, in := range incoming {
+		incomingCodes[in.Code] = struct{}{}
+		cur, exists := currentByCode[in.Code]
+		if !exists {
+			diff.Added = append(diff.Added, SeedSubjectDiff{
+				Code:              in.Code,
+				Name:              in.Name,
+				RequirementsAdded: in.Requirements,
+				GroupsChanged:     len(in.RequirementGroups) > 0,
+			})
+			continue
+		}
+
+		entry := SeedSubjectDiff{Code: in.Code, Name: in.Name, SubjectID: ids[in.Code]}
+		addChange := func(field string, from, to any) {
+			entry.Changes = append(entry.Changes, SeedFieldChange{Field: field, From: from, To: to})
+		}
+		if cur.Name != in.Name {
+			addChange("name", cur.Name, in.Name)
+		}
+		if cur.SubjectYear != in.SubjectYear {
+			addChange("subjectYear", cur.SubjectYear, in.SubjectYear)
+		}
+		if cur.Term != in.Term {
+			addChange("term", cur.Term, in.Term)
+		}
+		if cur.IsElective != in.IsElective {
+			addChange("is_elective", cur.IsElective, in.IsElective)
+		}
+		if cur.Credits != in.Credits {
+			addChange("credits", cur.Credits, in.Credits)
+		}
+		if cur.Hours != in.Hours {
+			addChange("hours", cur.Hours, in.Hours)
+		}
+		entry.RequirementsAdded, entry.RequirementsRemoved = diffSeedRequirements(cur.Requirements, in.Requirements)
+		entry.GroupsChanged = !sameSeedGroups(cur.RequirementGroups, in.RequirementGroups)
+
+		if len(entry.Changes) == 0 && len(entry.RequirementsAdded) == 0 && len(entry.RequirementsRemoved) == 0 && !entry.GroupsChanged {
+			diff.Unchanged++
+			continue
+		}
+		diff.Updated = append(diff.Updated, entry)
+	}
+
+	for _, cur := range current {
+		if _, kept := incomingCodes[cur.Code]; !kept {
+			diff.Removed = append(diff.Removed, SeedSubjectDiff{Code: cur.Code, Name: cur.Name, SubjectID: ids[cur.Code]})
+		}
+	}
+	return diff
+}
+
+// BuildSeedElectivesDiff compara los pools (por código) y las reglas de electivas actuales con
+// los del seed entrante y completa esa parte de diff. poolIDs traduce los códigos de pool a ids.
+func BuildSeedElectivesDiff(diff *SeedDiff, current models.JsonToDegreeProgram, incoming models.JsonToDegreeProgram, poolIDs map[string]string) {
+	diff.PoolsAdded, diff.PoolsUpdated, diff.PoolsRemoved = []SeedPoolDiff{}, []SeedPoolDiff{}, []SeedPoolDiff{}
+
+	currentByCode := make(map[string]models.SeedElectivePool, len(current.ElectivePools))
+	for _, p := range current.ElectivePools {
+		currentByCode[p.Code] = p
+	}
+	incomingCodes := make(map[string]struct{}, len(incoming.ElectivePools))
+	for _, in := range incoming.ElectivePools {
+		incomingCodes[in.Code] = struct{}{}
+		cur, exists := currentByCode[in.Code]
+		if !exists {
+			diff.PoolsAdded = append(diff.PoolsAdded, SeedPoolDiff{Code: in.Code, Name: in.Name, SubjectsAdded: in.SubjectCodes})
+			continue
+		}
+
+		entry := SeedPoolDiff{Code: in.Code, Name: in.Name, PoolID: poolIDs[in.Code]}
+		if cur.Name != in.Name {
+			entry.Changes = append(entry.Changes, SeedFieldChange{Field: "name", From: cur.Name, To: in.Name})
+		}
+		if cur.Description != in.Description {
+			entry.Changes = append(entry.Changes, SeedFieldChange{Field: "description", From: cur.Description, To: in.Description})
+		}
+		entry.SubjectsAdded, entry.SubjectsRemoved = diffCodes(cur.SubjectCodes, in.SubjectCodes)
+		if len(entry.Changes) > 0 || len(entry.SubjectsAdded) > 0 || len(entry.SubjectsRemoved) > 0 {
+			diff.PoolsUpdated = append(diff.PoolsUpdated, entry)
+		}
+	}
+	for _, cur := range current.ElectivePools {
+		if _, kept := incomingCodes[cur.Code]; !kept {
+			diff.PoolsRemoved = append(diff.PoolsRemoved, SeedPoolDiff{Code: cur.Code, Name: cur.Name, PoolID: poolIDs[cur.Code]})
+		}
+	}
+
+	// Las reglas no tienen código: se comparan por contenido y, si algo cambia, se reemplazan todas.
+	diff.RulesAdded, diff.RulesRemoved = []models.SeedElectiveRule{}, []models.SeedElectiveRule{}
+	currentRules := make(map[string]int, len(current.ElectiveRules))
+	for _, r := range current.ElectiveRules {
+		currentRules[seedRuleKey(r)]++
+	}
+	incomingRules := make(map[string]int, len(incoming.ElectiveRules))
+	for _, r := range incoming.ElectiveRules {
+		incomingRules[seedRuleKey(r)]++
+	}
+	for _, r := range incoming.ElectiveRules {
+		key := seedRuleKey(r)
+		if currentRules[key] > 0 {
+			currentRules[key]--
+			continue
+		}
+		diff.RulesAdded = append(diff.RulesAdded, r)
+	}
+	for _, r := range current.ElectiveRules {
+		key := seedRuleKey(r)
+		if incomingRules[key] > 0 {
+			incomingRules[key]--
+			continue
+		}
+		diff.RulesRemoved = append(diff.RulesRemoved, r)
+	}
+}
+
+func seedRuleKey(r models.SeedElectiveRule) string {
+	to := "-"
+	if r.AppliesToYear != nil {
+		to = fmt.Sprint(*r.AppliesToYear)
+	}
+	return fmt.Sprintf("%s|%d|%s|%s|%g", r.PoolCode, r.AppliesFromYear, to, r.RequirementType, r.MinimumValue)
+}
+
+func diffCodes(current []string, incoming []string) (added []string, removed []string) {
+	inCurrent := make(map[string]struct{}, len(current))
+	for _, c := range current {
+		inCurrent[c] = struct{}{}
+	}
+	inIncoming := make(map[string]struct{}, len(incoming))
+	for _, c := range incoming {
+		inIncoming[c] = struct{}{}
+		if _, ok := inCurrent[c]; !ok {
+			added = append(added, c)
+		}
+	}
+	for _, c := range current {
+		if _, ok := inIncoming[c]; !ok {
+			removed = append(removed, c)
+		}
+	}
+	return added, removed
+}
+
+func diffSeedRequirements(current []models.SeedRequirement, incoming []models.SeedRequirement) (added []models.SeedRequirement, removed []models.SeedRequirement) {
+	inCurrent := make(map[models.SeedRequirement]struct{}, len(current))
+	for _, r := range current {
+		inCurrent[r] = struct{}{}
+	}
+	inIncoming := make(map[models.SeedRequirement]struct{}, len(incoming))
+	for _, r := range incoming {
+		inIncoming[r] = struct{}{}
+		if _, ok := inCurrent[r]; !ok {
+			added = append(added, r)
+		}
+	}
+	for _, r := range current {
+		if _, ok := inIncoming[r]; !ok {
+			removed = append(removed, r)
+		}
+	}
+	return added, removed
+}
+
+// sameSeedGroups compara los grupos sin importar el orden de los códigos dentro de cada uno.
+func sameSeedGroups(a []models.SeedRequirementGroup, b []models.SeedRequirementGroup) bool {
+	left, errA := json.Marshal(normalizeSeedGroups(a))
+	right, errB := json.Marshal(normalizeSeedGroups(b))
+	return errA == nil && errB == nil && string(left) == string(right)
+}
+
+func normalizeSeedGroups(groups []models.SeedRequirementGroup) []models.SeedRequirementGroup {
+	out := make([]models.SeedRequirementGroup, 0, len(groups))
+	for _, g := range groups {
+		if g.Type == models.SeedRequirementApproved {
+			g.Type = ""
+		}
+		g.SubjectCodes = append([]string(nil), g.SubjectCodes...)
+		sort.Strings(g.SubjectCodes)
+		g.Groups = normalizeSeedGroups(g.Groups)
+		out = append(out, g)
+	}
+	return out
+}
+
+// UpsertSeed reimporta el seed sobre un programa existente: agrega, actualiza y borra materias
+// emparejándolas por código, y reemplaza correlativas, co-requisitos y grupos sólo de las materias
+// que cambiaron. Las materias que siguen conservan su id, y con él el avance de los usuarios.
+// Borrar materias con avance requiere force. Los pools de electivas se emparejan por código como
+// las materias (conservan su id) y las reglas se reemplazan si cambian; el payload describe el plan
+// completo, así que los pools que no vienen se borran. Tiene que venir validado con ValidateSeed.
+func UpsertSeed(program models.DegreeProgram, versionID *string, payload models.JsonToDegreeProgram, dryRun bool, force bool) (*SeedDiff, error) {
+	plan, err := LoadProgramPlan(program.ID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	codes := SubjectCodes(plan.Subjects)
+	current := BuildProgramExport(program, plan)
+	ids := make(map[string]string, len(codes))
+	for id, code := range codes {
+		ids[code] = id
+	}
+
+	poolIDs := make(map[string]string, len(plan.Pools))
+	for id, code := range ElectivePoolCodes(plan.Pools) {
+		poolIDs[code] = id
+	}
+
+	diff := BuildSeedDiff(current.Subjects, payload.Subjects, ids)
+	BuildSeedElectivesDiff(&diff, current, payload, poolIDs)
+	diff.ProgramID = program.ID
+	diff.PlanVersionID = versionID
+	diff.DryRun = dryRun
+	incomingCodes := make(map[string]struct{}, len(payload.Subjects))
+	for _, s := range payload.Subjects {
+		incomingCodes[s.Code] = struct{}{}
+	}
+	for _, s := range plan.Subjects {
+		if _, kept := incomingCodes[codes[s.ID]]; kept && s.Code == "" {
+			diff.CodesAssigned++
+		}
+	}
+
+	if len(diff.Removed) > 0 {
+		removedIDs := make([]string, 0, len(diff.Removed))
+		for _, r := range diff.Removed {
+			removedIDs = append(removedIDs, r.SubjectID)
+		}
+		var counts []struct {
+			SubjectID string
+			Users     int64
+		}
+		if err := db.Db.Model(&models.UserSubject{}).
+			Select("subject_id, COUNT(*) AS users").
+			Where("subject_id IN ? AND status <> ?", removedIDs, models.StatusAvailable).
+			Group("subject_id").
+			Scan(&counts).Error; err != nil {
+			return nil, err
+		}
+		bySubject := make(map[string]int64, len(counts))
+		for _, c := range counts {
+			bySubject[c.SubjectID] = c.Users
+		}
+		for i := range diff.Removed {
+			diff.Removed[i].UsersWithProgress = bySubject[diff.Removed[i].SubjectID]
+		}
+	}
+
+	if dryRun {
+		return &diff, nil
+	}
+	if !force {
+		for _, r := range diff.Removed {
+			if r.UsersWithProgress > 0 {
+				return &diff, ErrSeedRemovesProgress
+			}
+		}
+	}
+
+	err = db.Db.Transaction(func(tx *gorm.DB) error {
+		return applySeedDiff(tx, program, versionID, plan, codes, ids, poolIDs, payload, diff)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
+
+func applySeedDiff(tx *gorm.DB, program models.DegreeProgram, versionID *string, plan *ProgramPlan, codes map[string]string, ids map[string]string, poolIDs map[string]string, payload models.JsonToDegreeProgram, diff SeedDiff) error {
+	for _, s := range plan.Subjects {
+		if s.Code != codes[s.ID] {
+			if err := tx.Model(&models.Subject{}).Where("id = ?", s.ID).Update("code", codes[s.ID]).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, removed := range diff.Removed {
+		if err := DeleteSubjectTx(tx, models.Subject{ID: removed.SubjectID}); err != nil {
+			return err
+		}
+		delete(ids, removed.Code)
+	}
+
+	incoming := make(map[string]models.SeedSubject, len(payload.Subjects))
+	for _, s := range payload.Subjects {
+		incoming[s.Code] = s
+	}
+	for _, added := range diff.Added {
+		in := incoming[added.Code]
+		year := in.SubjectYear
+		subject := models.Subject{
+			ID:              uuid.NewString(),
+			Name:            in.Name,
+			Code:            in.Code,
+			Year:            &year,
+			Term:            string(in.Term),
+			Credits:         in.Credits,
+			Hours:           in.Hours,
+			IsElective:      in.IsElective,
+			DegreeProgramID: program.ID,
+			PlanVersionID:   versionID,
+		}
+		if err := tx.Create(&subject).Error; err != nil {
+			return err
+		}
+		ids[in.Code] = subject.ID
+	}
+
+	for _, updated := range diff.Updated {
+		if len(updated.Changes) == 0 {
+			continue
+		}
+		in := incoming[updated.Code]
+		updates := map[string]interface{}{
+			"name":         in.Name,
+			"subject_year": in.SubjectYear,
+			"term":         string(in.Term),
+			"is_elective":  in.IsElective,
+			"credits":      in.Credits,
+			"hours":        in.Hours,
+		}
+		if err := tx.Model(&models.Subject{}).Where("id = ?", updated.SubjectID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	touched := make([]SeedSubjectDiff, 0, len(diff.Added)+len(diff.Updated))
+	touched = append(append(touched, diff.Added...), diff.Updated...)
+	for _, entry := range touched {
+		in := incoming[entry.Code]
+		subjectID := ids[entry.Code]
+		if len(entry.RequirementsAdded) > 0 || len(entry.RequirementsRemoved) > 0 {
+			if err := tx.Where("subject_id = ?", subjectID).Delete(&models.SubjectRequirement{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("subject_id = ?", subjectID).Delete(&models.SubjectCorequisite{}).Error; err != nil {
+				return err
+			}
+			for _, req := range in.Requirements {
+				if req.Type == models.SeedRequirementCorequisite {
+					co := models.SubjectCorequisite{SubjectID: subjectID, CorequisiteID: ids[req.SubjectCode]}
+					if err := tx.Omit("Subject", "Corequisite").Create(&co).Error; err != nil {
+						return err
+					}
+					continue
+				}
+				row := models.SubjectRequirement{SubjectID: subjectID, RequirementID: ids[req.SubjectCode], MinStatus: SeedMinStatus(req.Type)}
+				if err := tx.Create(&row).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if entry.GroupsChanged {
+			groupIDs := tx.Model(&models.RequirementGroup{}).Select("id").Where("subject_id = ?", subjectID)
+			if err := tx.Where("group_id IN (?)", groupIDs).Delete(&models.RequirementGroupSubject{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("subject_id = ?", subjectID).Delete(&models.RequirementGroup{}).Error; err != nil {
+				return err
+			}
+			for _, seedGroup := range in.RequirementGroups {
+				group := SeedGroupToModel(seedGroup, ids)
+				if err := CreateRequirementGroupTx(tx, subjectID, &group); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := applySeedElectivesDiff(tx, program, versionID, ids, poolIDs, payload, diff); err != nil {
+		return err
+	}
+
+	if err := CheckRequirementCycles(tx, program.ID, versionID); err != nil {
+		return err
+	}
+	return CheckCorequisiteConflicts(tx, program.ID, versionID)
+}
+
+// applySeedElectivesDiff aplica los cambios de pools y reglas. Corre después de crear las materias
+// nuevas, así ids ya tiene todos los códigos del payload.
+func applySeedElectivesDiff(tx *gorm.DB, program models.DegreeProgram, versionID *string, ids map[string]string, poolIDs map[string]string, payload models.JsonToDegreeProgram, diff SeedDiff) error {
+	rulesChanged := len(diff.RulesAdded) > 0 || len(diff.RulesRemoved) > 0
+	if rulesChanged || len(diff.PoolsRemoved) > 0 {
+		if err := tx.Where("degree_program_id = ?", program.ID).Scopes(ScopePlanVersion("plan_version_id", versionID)).Delete(&models.ElectiveRule{}).Error; err != nil {
+			return err
+		}
+		rulesChanged = true
+	}
+
+	for _, removed := range diff.PoolsRemoved {
+		if err := tx.Where("elective_pool_id = ?", removed.PoolID).Delete(&models.ElectivePoolSubject{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", removed.PoolID).Delete(&models.ElectivePool{}).Error; err != nil {
+			return err
+		}
+		delete(poolIDs, removed.Code)
+	}
+
+	incoming := make(map[string]models.SeedElectivePool, len(payload.ElectivePools))
+	for _, p := range payload.ElectivePools {
+		incoming[p.Code] = p
+	}
+	for _, added := range diff.PoolsAdded {
+		pool := models.ElectivePool{
+			ID:              uuid.NewString(),
+			DegreeProgramID: program.ID,
+			Name:            added.Name,
+			Description:     incoming[added.Code].Description,
+			PlanVersionID:   versionID,
+		}
+		if err := tx.Omit("DegreeProgram", "Subjects").Create(&pool).Error; err != nil {
+			return err
+		}
+		poolIDs[added.Code] = pool.ID
+		if err := createSeedPoolMembers(tx, pool.ID, added.SubjectsAdded, ids); err != nil {
+			return err
+		}
+	}
+	for _, updated := range diff.PoolsUpdated {
+		in := incoming[updated.Code]
+		if len(updated.Changes) > 0 {
+			if err := tx.Model(&models.ElectivePool{}).Where("id = ?", updated.PoolID).
+				Updates(map[string]interface{}{"name": in.Name, "description": in.Description}).Error; err != nil {
+				return err
+			}
+		}
+		if len(updated.SubjectsRemoved) > 0 {
+			removedIDs := make([]string, 0, len(updated.SubjectsRemoved))
+			for _, code := range updated.SubjectsRemoved {
+				if id, ok := ids[code]; ok {
+					removedIDs = append(removedIDs, id)
+				}
+			}
+			if len(removedIDs) > 0 {
+				if err := tx.Where("elective_pool_id = ? AND subject_id IN ?", updated.PoolID, removedIDs).Delete(&models.ElectivePoolSubject{}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if err := createSeedPoolMembers(tx, updated.PoolID, updated.SubjectsAdded, ids); err != nil {
+			return err
+		}
+	}
+
+	if !rulesChanged {
+		return nil
+	}
+	for _, seedRule := range payload.ElectiveRules {
+		rule := models.ElectiveRule{
+			ID:              uuid.NewString(),
+			DegreeProgramID: program.ID,
+			PoolID:          poolIDs[seedRule.PoolCode],
+			AppliesFromYear: seedRule.AppliesFromYear,
+			AppliesToYear:   seedRule.AppliesToYear,
+			RequirementType: seedRule.RequirementType,
+			MinimumValue:    seedRule.MinimumValue,
+			PlanVersionID:   versionID,
+		}
+		if err := tx.Omit("DegreeProgram", "Pool").Create(&rule).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createSeedPoolMembers(tx *gorm.DB, poolID string, subjectCodes []string, ids map[string]string) error {
+	for _, code := range subjectCodes {
+		member := models.ElectivePoolSubject{ElectivePoolID: poolID, SubjectID: ids[code]}
+		if err := tx.Omit("Pool", "Subject").Create(&member).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,520 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidImportFile = errors.New("invalid import file")
+
+const (
+	maxImportColumns = 64
+	// maxXLSXPartSize acota cuánto se descomprime de cada parte del .xlsx.
+	maxXLSXPartSize = 32 << 20
+)
+
+// ImportRow es una fila de la planilla ya traducida a materia del seed. Columns son las columnas
+// que trae la planilla; en una materia existente las que faltan conservan el valor actual. Nil
+// equivale a todas las columnas.
+type ImportRow struct {
+	Line    int
+	Subject models.SeedSubject
+	Columns map[string]bool
+}
+
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+type SubjectImportResult struct {
+	Rows   int              `json:"rows"`
+	Errors []ImportRowError `json:"errors"`
+	Diff   *SeedDiff        `json:"diff,omitempty"`
+}
+
+// Encabezados aceptados por columna, ya sin acentos y en minúsculas.
+var importColumns = map[string][]string{
+	"code":          {"code", "codigo", "cod"},
+	"name":          {"name", "nombre", "materia"},
+	"year":          {"year", "ano", "anio", "subjectyear"},
+	"term":          {"term", "periodo", "cursada", "duracion"},
+	"credits":       {"credits", "creditos"},
+	"hours":         {"hours", "horas", "carga horaria"},
+	"elective":      {"elective", "is_elective", "electiva", "optativa"},
+	"prerequisites": {"prerequisites", "requirements", "correlativas", "requisitos"},
+}
+
+var importTerms = map[string]models.SubjectTerm{
+	"annual": models.TermAnnual, "anual": models.TermAnnual,
+	"semester": models.TermSemester, "cuatrimestral": models.TermSemester, "semestral": models.TermSemester,
+	"quarterly": models.TermQuarterly, "trimestral": models.TermQuarterly,
+	"bimonthly": models.TermBimonthly, "bimestral": models.TermBimonthly,
+}
+
+var importRequirementTypes = map[string]models.SeedRequirementType{
+	"": models.SeedRequirementApproved, "approved": models.SeedRequirementApproved, "aprobada": models.SeedRequirementApproved,
+	"regularized": models.SeedRequirementRegularize, "regularizada": models.SeedRequirementRegularize, "regular": models.SeedRequirementRegularize,
+	"corequisite": models.SeedRequirementCorequisite, "correquisito": models.SeedRequirementCorequisite,
+}
+
+// ParseSubjectsCSV lee la planilla en CSV. El separador puede ser coma o punto y coma (Excel en
+// español exporta con ;). Devuelve los errores por fila junto con las filas válidas.
+func ParseSubjectsCSV(r io.Reader) ([]ImportRow, []ImportRowError, error) {
+	records, lines, err := readCSVRecords(r, []rune{';'}, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseSubjectTable(records, lines)
+}
+
+// readCSVRecords lee todo el CSV y devuelve cada registro con su número de línea. Elige como
+// separador el que más aparece en la primera línea entre la coma y los de separators.
+func readCSVRecords(r io.Reader, separators []rune, lazyQuotes bool) ([][]string, []int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	header, _, _ := strings.Cut(text, "\n")
+
+	reader := csv.NewReader(strings.NewReader(text))
+	for _, sep := range separators {
+		if strings.Count(header, string(sep)) > strings.Count(header, string(reader.Comma)) {
+			reader.Comma = sep
+		}
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = lazyQuotes
+
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", ErrInvalidImportFile, err.Error())
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return records, lines, nil
+}
+
+// ParseSubjectsXLSX lee la primera hoja de un .xlsx con archive/zip y encoding/xml.
+func ParseSubjectsXLSX(r io.ReaderAt, size int64) ([]ImportRow, []ImportRowError, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: not an xlsx file", ErrInvalidImportFile)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxText `xml:"si"`
+		}
+		if err := decodeZipXML(f, &sst); err != nil {
+			return nil, nil, err
+		}
+		for _, item := range sst.Items {
+			shared = append(shared, item.String())
+		}
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sheet struct {
+		Rows []struct {
+			Number int `xml:"r,attr"`
+			Cells  []struct {
+				Ref    string   `xml:"r,attr"`
+				Type   string   `xml:"t,attr"`
+				Value  string   `xml:"v"`
+				Inline xlsxText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodeZipXML(files[sheetPath], &sheet); err != nil {
+		return nil, nil, err
+	}
+
+	var records [][]string
+	var lines []int
+	for i, row := range sheet.Rows {
+		record := make([]string, 0, len(row.Cells))
+		for j, cell := range row.Cells {
+			column := j
+			if cell.Ref != "" {
+				column = xlsxColumnIndex(cell.Ref)
+			}
+			if column < 0 || column >= maxImportColumns {
+				continue
+			}
+			for len(record) <= column {
+				record = append(record, "")
+			}
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(strings.TrimSpace(cell.Value))
+				if err != nil || index < 0 || index >= len(shared) {
+					return nil, nil, fmt.Errorf("%w: invalid shared string in cell %s", ErrInvalidImportFile, cell.Ref)
+				}
+				value = shared[index]
+			case "inlineStr":
+				value = cell.Inline.String()
+			case "b":
+				value = map[string]string{"1": "true", "0": "false"}[cell.Value]
+			}
+			record[column] = value
+		}
+		line := row.Number
+		if line == 0 {
+			line = i + 1
+		}
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return parseSubjectTable(records, lines)
+}
+
+// xlsxText es un texto de celda: simple (<t>) o con formato (<r><t>).
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
+
+func decodeZipXML(f *zip.File, v any) error {
+	if f == nil {
+		return fmt.Errorf("%w: missing worksheet", ErrInvalidImportFile)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidImportFile, err.Error())
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(io.LimitReader(rc, maxXLSXPartSize)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s is not valid xml", ErrInvalidImportFile, f.Name)
+	}
+	return nil
+}
+
+// firstSheetPath busca en workbook.xml la primera hoja y su archivo en las relaciones.
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipXML(files["xl/workbook.xml"], &workbook); err != nil || len(workbook.Sheets) == 0 {
+		if _, ok := files["xl/worksheets/sheet1.xml"]; ok {
+			return "xl/worksheets/sheet1.xml", nil
+		}
+		return "", fmt.Errorf("%w: the workbook has no sheets", ErrInvalidImportFile)
+	}
+	var rels struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Items {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = "xl/" + target
+		}
+		return target, nil
+	}
+	return "", fmt.Errorf("%w: first sheet not found", ErrInvalidImportFile)
+}
+
+// xlsxColumnIndex pasa la referencia de celda (ej. "AB12") al índice de columna desde 0.
+func xlsxColumnIndex(ref string) int {
+	index := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+	}
+	return index - 1
+}
+
+// parseSubjectTable usa la primera fila como encabezado y traduce el resto a materias. Las filas
+// vacías se ignoran; cada problema de una fila se informa con su número de línea.
+func parseSubjectTable(records [][]string, lines []int) ([]ImportRow, []ImportRowError, error) {
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("%w: the file is empty", ErrInvalidImportFile)
+	}
+	columns := make(map[string]int)
+	for i, raw := range records[0] {
+		header := strings.TrimSpace(FoldAccents(raw))
+		for field, aliases := range importColumns {
+			for _, alias := range aliases {
+				if header == alias {
+					if _, dup := columns[field]; dup {
+						return nil, nil, fmt.Errorf("%w: column %q appears more than once", ErrInvalidImportFile, field)
+					}
+					columns[field] = i
+				}
+			}
+		}
+	}
+	for _, required := range []string{"code", "name"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("%w: missing column %q", ErrInvalidImportFile, required)
+		}
+	}
+
+	present := make(map[string]bool, len(columns))
+	for field := range columns {
+		present[field] = true
+	}
+
+	rows := make([]ImportRow, 0, len(records)-1)
+	rowErrors := make([]ImportRowError, 0)
+	for i := 1; i < len(records); i++ {
+		record := records[i]
+		cell := func(field string) string {
+			index, ok := columns[field]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		line := lines[i]
+		subject := models.SeedSubject{Code: cell("code"), Name: cell("name"), Term: models.TermAnnual, Requirements: []models.SeedRequirement{}}
+		fail := func(format string, args ...any) {
+			rowErrors = append(rowErrors, ImportRowError{Line: line, Code: subject.Code, Message: fmt.Sprintf(format, args...)})
+		}
+		before := len(rowErrors)
+
+		if subject.Code == "" {
+			fail("missing code")
+		} else if len(subject.Code) > maxSeedCodeLen {
+			fail("code is longer than %d characters", maxSeedCodeLen)
+		}
+		if subject.Name == "" {
+			fail("missing name")
+		} else if len(subject.Name) > maxSeedNameLen {
+			fail("name is longer than %d characters", maxSeedNameLen)
+		}
+		if raw := cell("year"); raw != "" {
+			year, err := strconv.Atoi(raw)
+			if err != nil || year < 0 {
+				fail("invalid year %q", raw)
+			}
+			subject.SubjectYear = year
+		}
+		if raw := cell("term"); raw != "" {
+			term, ok := importTerms[FoldAccents(raw)]
+			if !ok {
+				fail("invalid term %q", raw)
+			}
+			subject.Term = term
+		}
+		for _, field := range []string{"credits", "hours"} {
+			raw := strings.Replace(cell(field), ",", ".", 1)
+			if raw == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil || value < 0 {
+				fail("invalid %s %q", field, cell(field))
+				continue
+			}
+			if field == "credits" {
+				subject.Credits = value
+			} else {
+				subject.Hours = value
+			}
+		}
+		switch FoldAccents(cell("elective")) {
+		case "", "0", "false", "no", "n":
+		case "1", "true", "yes", "si", "s", "x":
+			subject.IsElective = true
+		default:
+			fail("invalid elective flag %q", cell("elective"))
+		}
+		for _, item := range strings.FieldsFunc(cell("prerequisites"), func(r rune) bool { return r == ';' || r == '|' || r == '\n' }) {
+			code, kind, _ := strings.Cut(strings.TrimSpace(item), ":")
+			code = strings.TrimSpace(code)
+			if code == "" {
+				continue
+			}
+			reqType, ok := importRequirementTypes[FoldAccents(strings.TrimSpace(kind))]
+			if !ok {
+				fail("invalid requirement type %q for %q", kind, code)
+				continue
+			}
+			subject.Requirements = append(subject.Requirements, models.SeedRequirement{SubjectCode: code, Type: reqType})
+		}
+
+		if len(rowErrors) == before {
+			rows = append(rows, ImportRow{Line: line, Subject: subject, Columns: present})
+		}
+	}
+	return rows, rowErrors, nil
+}
+
+// MergeImportRows aplica las filas sobre las materias actuales del plan: en las filas con un código
+// existente sólo se pisan las columnas que trae la planilla (los grupos siempre se conservan) y el
+// resto se agregan. Las materias que no aparecen en la planilla quedan como están. Valida con las
+// mismas reglas que el seed e informa cada problema en la línea de la fila correspondiente; si el
+// problema no es de ninguna fila importada devuelve un error ErrInvalidImportFile para todo el archivo.
+func MergeImportRows(current []models.SeedSubject, rows []ImportRow) ([]models.SeedSubject, []ImportRowError, error) {
+	rowErrors := make([]ImportRowError, 0)
+	lineByCode := make(map[string]int, len(rows))
+	for _, row := range rows {
+		if first, dup := lineByCode[row.Subject.Code]; dup {
+			rowErrors = append(rowErrors, ImportRowError{Line: row.Line, Code: row.Subject.Code, Message: fmt.Sprintf("duplicate code, already used on line %d", first)})
+			continue
+		}
+		lineByCode[row.Subject.Code] = row.Line
+	}
+	if len(rowErrors) > 0 {
+		return nil, rowErrors, nil
+	}
+
+	merged := make([]models.SeedSubject, 0, len(current)+len(rows))
+	byCode := make(map[string]int, len(current)+len(rows))
+	for _, s := range current {
+		byCode[s.Code] = len(merged)
+		merged = append(merged, s)
+	}
+	for _, row := range rows {
+		if i, exists := byCode[row.Subject.Code]; exists {
+			merged[i] = mergeImportRow(merged[i], row)
+			continue
+		}
+		byCode[row.Subject.Code] = len(merged)
+		merged = append(merged, row.Subject)
+	}
+
+	for _, row := range rows {
+		for _, req := range row.Subject.Requirements {
+			if _, ok := byCode[req.SubjectCode]; !ok {
+				rowErrors = append(rowErrors, ImportRowError{Line: row.Line, Code: row.Subject.Code, Message: fmt.Sprintf("unknown prerequisite code %q", req.SubjectCode)})
+			}
+		}
+	}
+	if len(rowErrors) > 0 {
+		return nil, rowErrors, nil
+	}
+
+	payload := models.JsonToDegreeProgram{Subjects: merged}
+	if err := ValidateSeed(&payload); err != nil {
+		var seedErr *SeedError
+		if errors.As(err, &seedErr) {
+			line, code := 0, ""
+			for _, c := range seedErr.SubjectCodes {
+				if l, ok := lineByCode[c]; ok && (line == 0 || l < line) {
+					line, code = l, c
+				}
+			}
+			if line > 0 {
+				return nil, []ImportRowError{{Line: line, Code: code, Message: err.Error()}}, nil
+			}
+		}
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidImportFile, err.Error())
+	}
+	return payload.Subjects, nil, nil
+}
+
+// mergeImportRow pisa en la materia actual sólo las columnas que trae la fila.
+func mergeImportRow(current models.SeedSubject, row ImportRow) models.SeedSubject {
+	has := func(field string) bool {
+		return row.Columns == nil || row.Columns[field]
+	}
+	merged := current
+	merged.Name = row.Subject.Name
+	if has("year") {
+		merged.SubjectYear = row.Subject.SubjectYear
+	}
+	if has("term") {
+		merged.Term = row.Subject.Term
+	}
+	if has("credits") {
+		merged.Credits = row.Subject.Credits
+	}
+	if has("hours") {
+		merged.Hours = row.Subject.Hours
+	}
+	if has("elective") {
+		merged.IsElective = row.Subject.IsElective
+	}
+	if has("prerequisites") {
+		merged.Requirements = row.Subject.Requirements
+	}
+	return merged
+}
+
+// ImportSubjects valida las filas contra el plan y, si no hay errores ni es dryRun, las guarda
+// con UpsertSeed: nunca borra materias, deja los pools y reglas de electivas como están y no toca
+// el avance de los usuarios.
+func ImportSubjects(program models.DegreeProgram, versionID *string, rows []ImportRow, dryRun bool) (*SubjectImportResult, error) {
+	plan, err := LoadProgramPlan(program.ID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	current := BuildProgramExport(program, plan)
+
+	result := &SubjectImportResult{Rows: len(rows), Errors: []ImportRowError{}}
+	merged, rowErrors, err := MergeImportRows(current.Subjects, rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(rowErrors) > 0 {
+		result.Errors = rowErrors
+		return result, nil
+	}
+
+	payload := models.JsonToDegreeProgram{Subjects: merged, ElectivePools: current.ElectivePools, ElectiveRules: current.ElectiveRules}
+	diff, err := UpsertSeed(program, versionID, payload, dryRun, false)
+	if err != nil {
+		return nil, err
+	}
+	result.Diff = diff
+	return result, nil
+}
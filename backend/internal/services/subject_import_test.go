@@ -0,0 +1,179 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseSubjectsCSV(t *testing.T) {
+	t.Parallel()
+
+	input := "\ufeffCódigo;Nombre;Año;Cursada;Créditos;Horas;Electiva;Correlativas\n" +
+		"MAT1;Análisis I;1;anual;8;128;no;\n" +
+		"\n" +
+		"MAT2;Análisis II;2;cuatrimestral;6,5;96;;MAT1 | FIS1:regularizada\n" +
+		"BAD;Sin año;dos;mensual;;;quizás;\n"
+
+	rows, rowErrors, err := ParseSubjectsCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %+v, want 2", rows)
+	}
+	mat2 := rows[1]
+	if mat2.Line != 4 || mat2.Subject.Term != models.TermSemester || mat2.Subject.Credits != 6.5 || mat2.Subject.SubjectYear != 2 {
+		t.Fatalf("MAT2 = %+v", mat2)
+	}
+	if len(mat2.Subject.Requirements) != 2 ||
+		mat2.Subject.Requirements[0] != (models.SeedRequirement{SubjectCode: "MAT1", Type: models.SeedRequirementApproved}) ||
+		mat2.Subject.Requirements[1] != (models.SeedRequirement{SubjectCode: "FIS1", Type: models.SeedRequirementRegularize}) {
+		t.Fatalf("MAT2 requirements = %+v", mat2.Subject.Requirements)
+	}
+	if len(rowErrors) != 3 {
+		t.Fatalf("rowErrors = %+v, want year, term and elective errors", rowErrors)
+	}
+	for _, e := range rowErrors {
+		if e.Line != 5 || e.Code != "BAD" {
+			t.Fatalf("rowError = %+v, want line 5 for BAD", e)
+		}
+	}
+}
+
+func TestParseSubjectsCSV_MissingColumn(t *testing.T) {
+	t.Parallel()
+
+	if _, _, err := ParseSubjectsCSV(strings.NewReader("code,year\nA,1\n")); err == nil {
+		t.Fatal("expected an error for a file without name column")
+	}
+}
+
+func TestParseSubjectsXLSX(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Plan" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Target="worksheets/plan.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>code</t></si><si><t>name</t></si><si><r><t>Álgebra </t></r><r><t>I</t></r></si></sst>`,
+		"xl/worksheets/plan.xml": `<worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="D1" t="inlineStr"><is><t>electiva</t></is></c></row>` +
+			`<row r="3"><c r="A3" t="inlineStr"><is><t>ALG</t></is></c><c r="B3" t="s"><v>2</v></c><c r="D3" t="b"><v>1</v></c></row>` +
+			`</sheetData></worksheet>`,
+	}
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, rowErrors, err := ParseSubjectsXLSX(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("err = %v, rowErrors = %+v", err, rowErrors)
+	}
+	if len(rows) != 1 || rows[0].Line != 3 || rows[0].Subject.Code != "ALG" || rows[0].Subject.Name != "Álgebra I" || !rows[0].Subject.IsElective {
+		t.Fatalf("rows = %+v", rows)
+	}
+}
+
+func TestMergeImportRows(t *testing.T) {
+	t.Parallel()
+
+	approved := func(code string) models.SeedRequirement {
+		return models.SeedRequirement{SubjectCode: code, Type: models.SeedRequirementApproved}
+	}
+	existing := seedSubject("A")
+	existing.RequirementGroups = []models.SeedRequirementGroup{{Kind: models.GroupCredits, MinimumValue: floatPtr(10)}}
+	current := []models.SeedSubject{existing, seedSubject("B", approved("A"))}
+
+	t.Run("replaces and adds", func(t *testing.T) {
+		t.Parallel()
+		renamed := seedSubject("A")
+		renamed.Name = "Nuevo nombre"
+		merged, rowErrors, err := MergeImportRows(current, []ImportRow{{Line: 2, Subject: renamed}, {Line: 3, Subject: seedSubject("C", approved("B"))}})
+		if err != nil || len(rowErrors) != 0 || len(merged) != 3 {
+			t.Fatalf("merged = %+v, errors = %+v, err = %v", merged, rowErrors, err)
+		}
+		if merged[0].Name != "Nuevo nombre" || len(merged[0].RequirementGroups) != 1 {
+			t.Fatalf("A = %+v, want renamed with its groups kept", merged[0])
+		}
+	})
+
+	tests := []struct {
+		name     string
+		rows     []ImportRow
+		wantLine int
+	}{
+		{name: "duplicate code", rows: []ImportRow{{Line: 2, Subject: seedSubject("C")}, {Line: 4, Subject: seedSubject("C")}}, wantLine: 4},
+		{name: "unknown prerequisite", rows: []ImportRow{{Line: 7, Subject: seedSubject("C", approved("Z"))}}, wantLine: 7},
+		{name: "cycle with existing subject", rows: []ImportRow{{Line: 9, Subject: seedSubject("A", approved("B"))}}, wantLine: 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, rowErrors, err := MergeImportRows(current, tt.rows)
+			if err != nil || len(rowErrors) != 1 || rowErrors[0].Line != tt.wantLine {
+				t.Fatalf("rowErrors = %+v, err = %v, want one on line %d", rowErrors, err, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestMergeImportRows_PartialColumnsKeepOtherFields(t *testing.T) {
+	t.Parallel()
+
+	existing := seedSubject("B", models.SeedRequirement{SubjectCode: "A", Type: models.SeedRequirementRegularize})
+	existing.SubjectYear = 2
+	existing.Term = models.TermSemester
+	existing.Credits = 6
+	existing.Hours = 96
+	existing.IsElective = true
+	current := []models.SeedSubject{seedSubject("A"), existing}
+
+	rows, rowErrors, err := ParseSubjectsCSV(strings.NewReader("code,name\nB,Análisis II\n"))
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("err = %v, rowErrors = %+v", err, rowErrors)
+	}
+	merged, rowErrors, err := MergeImportRows(current, rows)
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("err = %v, rowErrors = %+v", err, rowErrors)
+	}
+	b := merged[1]
+	if b.Name != "Análisis II" {
+		t.Fatalf("name = %q, want the imported one", b.Name)
+	}
+	if b.SubjectYear != 2 || b.Term != models.TermSemester || b.Credits != 6 || b.Hours != 96 || !b.IsElective {
+		t.Fatalf("B = %+v, want year, term, credits, hours and elective unchanged", b)
+	}
+	if len(b.Requirements) != 1 || b.Requirements[0] != existing.Requirements[0] {
+		t.Fatalf("requirements = %+v, want %+v", b.Requirements, existing.Requirements)
+	}
+}
+
+func TestMergeImportRows_ErrorOutsideImportedRowsIsFileLevel(t *testing.T) {
+	t.Parallel()
+
+	// El plan actual ya tiene un ciclo entre A y B; la fila importada es otra materia.
+	current := []models.SeedSubject{
+		seedSubject("A", models.SeedRequirement{SubjectCode: "B", Type: models.SeedRequirementApproved}),
+		seedSubject("B", models.SeedRequirement{SubjectCode: "A", Type: models.SeedRequirementApproved}),
+	}
+	_, rowErrors, err := MergeImportRows(current, []ImportRow{{Line: 2, Subject: seedSubject("C")}})
+	if !errors.Is(err, ErrInvalidImportFile) || len(rowErrors) != 0 {
+		t.Fatalf("err = %v, rowErrors = %+v, want a file-level ErrInvalidImportFile", err, rowErrors)
+	}
+}
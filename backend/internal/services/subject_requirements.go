@@ -0,0 +1,145 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRequirementNotFound  = errors.New("requirement not found")
+	ErrInvalidRequirement   = errors.New("invalid requirement")
+	ErrDuplicateRequirement = errors.New("requirement already exists")
+)
+
+// ValidRequirementMinStatus indica si el estado mínimo se puede usar en una correlativa.
+// in_progress queda reservado para los co-requisitos.
+func ValidRequirementMinStatus(status models.RequirementMinStatus) bool {
+	return status == models.ReqPassed || status == models.ReqFinalPending
+}
+
+// CheckRequirementCycles carga el plan con tx y devuelve ErrRequirementCycle (con el ciclo en el
+// mensaje) si las correlativas, junto con las materias nombradas en los grupos, forman un ciclo.
+func CheckRequirementCycles(tx *gorm.DB, programID string, versionID *string) error {
+	var subjectIDs []string
+	if err := tx.Model(&models.Subject{}).Where("degree_program_id = ?", programID).
+		Scopes(ScopePlanVersion("plan_version_id", versionID)).Pluck("id", &subjectIDs).Error; err != nil {
+		return err
+	}
+	if len(subjectIDs) == 0 {
+		return nil
+	}
+	var requirements []models.SubjectRequirement
+	if err := tx.Where("subject_id IN ?", subjectIDs).Find(&requirements).Error; err != nil {
+		return err
+	}
+	var groups []models.RequirementGroup
+	if err := tx.Preload("Subjects").Where("subject_id IN ?", subjectIDs).Find(&groups).Error; err != nil {
+		return err
+	}
+
+	edges := append(requirements, GroupRequirementEdges(BuildGroupTree(groups))...)
+	if cycle := NewRequirementGraph(subjectIDs, edges).FindCycle(); cycle != nil {
+		return fmt.Errorf("%w: %s", ErrRequirementCycle, strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+func ListSubjectRequirements(subjectID string) ([]models.SubjectRequirement, error) {
+	requirements := make([]models.SubjectRequirement, 0)
+	if err := db.Db.Where("subject_id = ?", subjectID).Order("created_at ASC").Find(&requirements).Error; err != nil {
+		return nil, err
+	}
+	return requirements, nil
+}
+
+func getSubjectRequirement(tx *gorm.DB, subjectID string, requirementID string) (*models.SubjectRequirement, error) {
+	var requirement models.SubjectRequirement
+	if err := tx.Where("subject_id = ? AND requirement_id = ?", subjectID, requirementID).First(&requirement).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRequirementNotFound
+		}
+		return nil, err
+	}
+	return &requirement, nil
+}
+
+// AddSubjectRequirement agrega una correlativa a subject. Tiene que ser otra materia del mismo
+// plan y no puede generar ciclos ni chocar con un co-requisito.
+func AddSubjectRequirement(subject models.Subject, requirementID string, minStatus models.RequirementMinStatus) (*models.SubjectRequirement, error) {
+	if !ValidRequirementMinStatus(minStatus) {
+		return nil, fmt.Errorf("%w: min status must be passed or final_pending", ErrInvalidRequirement)
+	}
+	if requirementID == subject.ID {
+		return nil, fmt.Errorf("%w: a subject cannot require itself", ErrInvalidRequirement)
+	}
+
+	row := models.SubjectRequirement{SubjectID: subject.ID, RequirementID: requirementID, MinStatus: minStatus}
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		var required models.Subject
+		if err := tx.Where("id = ? AND degree_program_id = ?", requirementID, subject.DegreeProgramID).Scopes(ScopePlanVersion("plan_version_id", subject.PlanVersionID)).First(&required).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: subject %q is not in the same plan", ErrInvalidRequirement, requirementID)
+			}
+			return err
+		}
+		if _, err := getSubjectRequirement(tx, subject.ID, requirementID); err == nil {
+			return ErrDuplicateRequirement
+		} else if !errors.Is(err, ErrRequirementNotFound) {
+			return err
+		}
+
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+		if err := CheckRequirementCycles(tx, subject.DegreeProgramID, subject.PlanVersionID); err != nil {
+			return err
+		}
+		return CheckCorequisiteConflicts(tx, subject.DegreeProgramID, subject.PlanVersionID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// UpdateSubjectRequirement cambia el estado mínimo de una correlativa existente.
+func UpdateSubjectRequirement(subject models.Subject, requirementID string, minStatus models.RequirementMinStatus) (*models.SubjectRequirement, error) {
+	if !ValidRequirementMinStatus(minStatus) {
+		return nil, fmt.Errorf("%w: min status must be passed or final_pending", ErrInvalidRequirement)
+	}
+
+	var row *models.SubjectRequirement
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		row, err = getSubjectRequirement(tx, subject.ID, requirementID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.SubjectRequirement{}).
+			Where("subject_id = ? AND requirement_id = ?", subject.ID, requirementID).
+			Update("min_status", minStatus).Error; err != nil {
+			return err
+		}
+		row.MinStatus = minStatus
+		return CheckRequirementCycles(tx, subject.DegreeProgramID, subject.PlanVersionID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// DeleteSubjectRequirement quita una correlativa. Sacar una arista no puede generar ciclos.
+func DeleteSubjectRequirement(subjectID string, requirementID string) error {
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		if _, err := getSubjectRequirement(tx, subjectID, requirementID); err != nil {
+			return err
+		}
+		return tx.Where("subject_id = ? AND requirement_id = ?", subjectID, requirementID).Delete(&models.SubjectRequirement{}).Error
+	})
+}
@@ -0,0 +1,21 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"testing"
+)
+
+func TestValidRequirementMinStatus(t *testing.T) {
+	t.Parallel()
+
+	for status, want := range map[models.RequirementMinStatus]bool{
+		models.ReqPassed:       true,
+		models.ReqFinalPending: true,
+		models.ReqInProgress:   false,
+		"approved":             false,
+	} {
+		if got := ValidRequirementMinStatus(status); got != want {
+			t.Fatalf("ValidRequirementMinStatus(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -3,12 +3,17 @@ package services
 import (
 	"acadifyapp/internal/db"
 	"acadifyapp/internal/models"
+
+	"gorm.io/gorm"
 )
 
-func GetAllSubjectsFromProgram(id string) (models.DegreeProgram, error) {
+// GetAllSubjectsFromProgram carga el programa con las materias de una versión del plan (nil es
+// el plan base) y sus correlativas.
+func GetAllSubjectsFromProgram(id string, versionID *string) (models.DegreeProgram, error) {
 	var degreeProgram models.DegreeProgram
 
 	if err := db.Db.
+		Preload("Subjects", ScopePlanVersion("plan_version_id", versionID)).
 		Preload("Subjects.Requirements").
 		Where("id = ?", id).
 		First(&degreeProgram).Error; err != nil {
@@ -16,3 +21,29 @@ func GetAllSubjectsFromProgram(id string) (models.DegreeProgram, error) {
 	}
 	return degreeProgram, nil
 }
+
+// DeleteSubjectTx borra la materia con sus correlativas, co-requisitos, grupos, pools y el avance
+// de los usuarios en ella.
+func DeleteSubjectTx(tx *gorm.DB, subject models.Subject) error {
+	id := subject.ID
+	if err := tx.Where("subject_id = ? OR requirement_id = ?", id, id).Delete(&models.SubjectRequirement{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("subject_id = ? OR corequisite_id = ?", id, id).Delete(&models.SubjectCorequisite{}).Error; err != nil {
+		return err
+	}
+	groupIDs := tx.Model(&models.RequirementGroup{}).Select("id").Where("subject_id = ?", id)
+	if err := tx.Where("group_id IN (?) OR subject_id = ?", groupIDs, id).Delete(&models.RequirementGroupSubject{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("subject_id = ?", id).Delete(&models.RequirementGroup{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("subject_id = ?", id).Delete(&models.ElectivePoolSubject{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("subject_id = ?", id).Delete(&models.UserSubject{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&subject).Error
+}
@@ -0,0 +1,526 @@
+package services
+
+import (
+	"acadifyapp/internal/db"
+	"acadifyapp/internal/models"
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// TranscriptMinConfidence es el puntaje mínimo para proponer una materia.
+	TranscriptMinConfidence = 0.6
+	// TranscriptConfirmConfidence es el puntaje desde el que la propuesta viene confirmada.
+	TranscriptConfirmConfidence = 0.9
+	maxTranscriptAlternatives   = 3
+)
+
+// TranscriptEntry es una fila de la historia académica ya interpretada. Status queda vacío
+// para desaprobados, ausentes y filas que no cambian el estado de la materia.
+type TranscriptEntry struct {
+	Line   int                  `json:"line"`
+	Name   string               `json:"name"`
+	Code   string               `json:"code,omitempty"`
+	Date   *time.Time           `json:"date,omitempty"`
+	Grade  *float64             `json:"grade,omitempty"`
+	Result string               `json:"result,omitempty"`
+	Status models.SubjectStatus `json:"status,omitempty"`
+}
+
+type TranscriptCandidate struct {
+	SubjectID  string  `json:"subject_id"`
+	Name       string  `json:"name"`
+	Confidence float64 `json:"confidence"`
+}
+
+// TranscriptMatch es la materia del plan que mejor coincide con una fila; sin SubjectID la
+// fila no se pudo emparejar y Alternatives sugiere las más parecidas.
+type TranscriptMatch struct {
+	TranscriptEntry
+	SubjectID    string                `json:"subject_id,omitempty"`
+	SubjectName  string                `json:"subject_name,omitempty"`
+	Confidence   float64               `json:"confidence"`
+	Alternatives []TranscriptCandidate `json:"alternatives"`
+}
+
+// TranscriptProposal es el estado propuesto para una materia a partir de todas sus filas.
+// El cliente lo revisa y manda los confirmados en el formato de POST /me/subjects. Date es la
+// fecha de la fila elegida (la del examen si quedó aprobada); se manda como exam_date para que
+// la aprobación quede registrada como intento de examen.
+type TranscriptProposal struct {
+	SubjectID         string               `json:"subject_id"`
+	Name              string               `json:"name"`
+	Status            models.SubjectStatus `json:"status"`
+	CurrentStatus     models.SubjectStatus `json:"current_status,omitempty"`
+	FinalCalification *float64             `json:"final_calification,omitempty"`
+	RegularizedAt     *time.Time           `json:"regularized_at,omitempty"`
+	Date              *time.Time           `json:"date,omitempty"`
+	Confidence        float64              `json:"confidence"`
+	Confirmed         bool                 `json:"confirmed"`
+	Lines             []int                `json:"lines"`
+}
+
+type TranscriptMatchTable struct {
+	Matches   []TranscriptMatch    `json:"matches"`
+	Proposals []TranscriptProposal `json:"proposals"`
+}
+
+var transcriptColumns = map[string][]string{
+	"name":   {"materia", "actividad", "asignatura", "nombre", "actividad academica", "name", "subject"},
+	"code":   {"codigo", "cod", "code"},
+	"date":   {"fecha", "fecha de examen", "date"},
+	"grade":  {"nota", "calificacion", "nota final", "grade"},
+	"result": {"resultado", "condicion", "estado", "result", "status"},
+}
+
+var (
+	transcriptDateRe  = regexp.MustCompile(`\b(\d{1,2}/\d{1,2}/\d{4}|\d{4}-\d{2}-\d{2}|\d{1,2}-\d{1,2}-\d{4})\b`)
+	transcriptGradeRe = regexp.MustCompile(`(?:^|\s)(10|\d)(?:[.,](\d{1,2}))?(?:\s*\([^)]*\))?(?:\s|$)`)
+	// Al final del nombre sólo se toma como nota un número con decimales o escrito en letras
+	// ("7,50", "7 (siete)"): "Física 2" es un nombre, no una nota.
+	transcriptEndGradeRe = regexp.MustCompile(`\s(10|\d)(?:[.,](\d{1,2})|\s*\([^)0-9]+\))\s*$`)
+	transcriptCodeRe     = regexp.MustCompile(`\(\s*([A-Za-z0-9.\-]{1,20})\s*\)\s*$`)
+)
+
+// Palabras del resultado, en minúsculas. Las de desaprobado van primero porque "desaprobado"
+// también contiene "aprob".
+var transcriptResults = []struct {
+	keyword string
+	status  models.SubjectStatus
+}{
+	{"desaprob", ""},
+	{"reprob", ""},
+	{"insuficiente", ""},
+	{"ausente", ""},
+	{"libre", ""},
+	{"promoc", models.StatusPassed},
+	{"aprob", models.StatusPassed},
+	{"equivalencia", models.StatusPassed},
+	{"regular", models.StatusFinalPending},
+	{"cursando", models.StatusInProgress},
+}
+
+// ParseTranscriptCSV lee una historia académica exportada como planilla. Sólo exige la columna
+// de la materia y alguna de nota o resultado; las celdas que no se entienden quedan vacías.
+// Los sistemas de gestión suelen exportar con tabulador y comillas sin escapar, así que se aceptan.
+func ParseTranscriptCSV(r io.Reader) ([]TranscriptEntry, error) {
+	records, lines, err := readCSVRecords(r, []rune{';', '\t'}, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidImportFile)
+	}
+	columns := make(map[string]int)
+	for i, raw := range records[0] {
+		header := strings.TrimSpace(FoldAccents(raw))
+		for field, aliases := range transcriptColumns {
+			for _, alias := range aliases {
+				if _, seen := columns[field]; header == alias && !seen {
+					columns[field] = i
+				}
+			}
+		}
+	}
+	_, hasGrade := columns["grade"]
+	_, hasResult := columns["result"]
+	if _, ok := columns["name"]; !ok || (!hasGrade && !hasResult) {
+		return nil, fmt.Errorf("%w: the transcript needs a subject column and a grade or result column", ErrInvalidImportFile)
+	}
+
+	entries := make([]TranscriptEntry, 0, len(records)-1)
+	for i := 1; i < len(records); i++ {
+		record := records[i]
+		cell := func(field string) string {
+			index, ok := columns[field]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+		entry := TranscriptEntry{Line: lines[i], Code: cell("code"), Result: cell("result")}
+		entry.Name, entry.Code = splitTranscriptName(cell("name"), entry.Code)
+		if entry.Name == "" {
+			continue
+		}
+		entry.Date = parseTranscriptDate(cell("date"))
+		if m := transcriptGradeRe.FindStringSubmatch(" " + cell("grade") + " "); m != nil {
+			entry.Grade = transcriptGrade(m)
+		}
+		entry.Status = transcriptStatus(entry.Result, entry.Grade)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ParseTranscriptText lee el texto de una historia académica (por ejemplo, el PDF de SIU Guaraní
+// pasado a texto). Cada línea con fecha o resultado es una fila: la materia es lo que está antes
+// de la fecha y la nota, el primer número entre 0 y 10 que la sigue.
+func ParseTranscriptText(r io.Reader) ([]TranscriptEntry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	entries := make([]TranscriptEntry, 0)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if text == "" {
+			continue
+		}
+		if entry, ok := parseTranscriptLine(text); ok {
+			entry.Line = line
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidImportFile, err.Error())
+	}
+	return entries, nil
+}
+
+func parseTranscriptLine(text string) (TranscriptEntry, bool) {
+	var entry TranscriptEntry
+	name, rest := text, ""
+	loc := transcriptDateRe.FindStringIndex(text)
+	if loc != nil {
+		name, rest = text[:loc[0]], text[loc[1]:]
+		entry.Date = parseTranscriptDate(text[loc[0]:loc[1]])
+	}
+	if at := transcriptResultIndex(rest); at >= 0 {
+		entry.Result = strings.Fields(rest[at:])[0]
+	} else if loc == nil {
+		at := transcriptResultIndex(text)
+		if at < 0 {
+			return entry, false
+		}
+		name, rest = text[:at], text[at:]
+		entry.Result = strings.Fields(rest)[0]
+	}
+
+	if m := transcriptGradeRe.FindStringSubmatch(rest); m != nil {
+		entry.Grade = transcriptGrade(m)
+	} else if m := transcriptEndGradeRe.FindStringSubmatch(name); m != nil {
+		entry.Grade = transcriptGrade(m)
+		name = transcriptEndGradeRe.ReplaceAllString(name, "")
+	}
+
+	entry.Name, entry.Code = splitTranscriptName(name, "")
+	if entry.Name == "" {
+		return entry, false
+	}
+	entry.Status = transcriptStatus(entry.Result, entry.Grade)
+	return entry, true
+}
+
+// transcriptResultIndex es la posición de la primera palabra de resultado, o -1. Sólo pasa a
+// minúsculas las letras ASCII para que las posiciones sigan valiendo sobre el texto original.
+func transcriptResultIndex(text string) int {
+	lower := strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, text)
+	at := -1
+	for _, r := range transcriptResults {
+		if i := strings.Index(lower, r.keyword); i >= 0 && (at < 0 || i < at) {
+			at = i
+		}
+	}
+	return at
+}
+
+// splitTranscriptName limpia el nombre y separa el código entre paréntesis del final, si lo hay.
+func splitTranscriptName(raw string, code string) (string, string) {
+	name := strings.Trim(strings.TrimSpace(raw), "-|:;,.")
+	if m := transcriptCodeRe.FindStringSubmatchIndex(name); m != nil {
+		if code == "" {
+			code = name[m[2]:m[3]]
+		}
+		name = name[:m[0]]
+	}
+	name = strings.Join(strings.Fields(strings.Trim(strings.TrimSpace(name), "-|:;,")), " ")
+	hasLetter := false
+	for _, r := range name {
+		if unicode.IsLetter(r) {
+			hasLetter = true
+			break
+		}
+	}
+	if !hasLetter || len(name) > maxSeedNameLen {
+		return "", code
+	}
+	return name, strings.TrimSpace(code)
+}
+
+func parseTranscriptDate(raw string) *time.Time {
+	raw = strings.TrimSpace(raw)
+	for _, layout := range []string{"2/1/2006", "2006-01-02", "2-1-2006"} {
+		if date, err := time.Parse(layout, raw); err == nil {
+			return &date
+		}
+	}
+	return nil
+}
+
+// transcriptGrade arma la nota a partir de la parte entera y los decimales capturados.
+func transcriptGrade(m []string) *float64 {
+	raw := m[1]
+	if len(m) > 2 && m[2] != "" {
+		raw += "." + m[2]
+	}
+	grade, err := strconv.ParseFloat(raw, 64)
+	if err != nil || grade < 0 || grade > 10 {
+		return nil
+	}
+	return &grade
+}
+
+// transcriptStatus traduce el resultado de la fila a un estado. Sin resultado, una nota de 4 o
+// más se toma como aprobada (la escala de las universidades argentinas).
+func transcriptStatus(result string, grade *float64) models.SubjectStatus {
+	lower := strings.ToLower(result)
+	if strings.TrimSpace(lower) != "" {
+		for _, r := range transcriptResults {
+			if strings.Contains(lower, r.keyword) {
+				return r.status
+			}
+		}
+	}
+	if grade != nil && *grade >= 4 {
+		return models.StatusPassed
+	}
+	return ""
+}
+
+var transcriptRomanNumerals = map[string]string{
+	"i": "1", "ii": "2", "iii": "3", "iv": "4", "v": "5", "vi": "6", "vii": "7", "viii": "8", "ix": "9", "x": "10",
+}
+
+var transcriptStopwords = map[string]struct{}{
+	"de": {}, "del": {}, "la": {}, "las": {}, "el": {}, "los": {}, "y": {}, "e": {}, "a": {}, "en": {}, "para": {},
+}
+
+// normalizeSubjectName deja sólo las palabras significativas del nombre, sin acentos y con los
+// números romanos pasados a arábigos: "Análisis Matemático II" → "analisis matematico 2".
+func normalizeSubjectName(name string) []string {
+	fields := strings.FieldsFunc(FoldAccents(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if _, skip := transcriptStopwords[f]; skip {
+			continue
+		}
+		if digit, ok := transcriptRomanNumerals[f]; ok {
+			f = digit
+		}
+		tokens = append(tokens, f)
+	}
+	return tokens
+}
+
+// NameSimilarity compara dos nombres de materia y devuelve un puntaje entre 0 y 1: el mejor
+// entre palabras en común y distancia de edición. Si los números no coinciden ("Física I" contra
+// "Física II") el puntaje se reduce a la mitad.
+func NameSimilarity(a string, b string) float64 {
+	ta, tb := normalizeSubjectName(a), normalizeSubjectName(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	ja, jb := strings.Join(ta, " "), strings.Join(tb, " ")
+	if ja == jb {
+		return 1
+	}
+
+	counts := make(map[string]int, len(ta))
+	for _, t := range ta {
+		counts[t]++
+	}
+	common := 0
+	for _, t := range tb {
+		if counts[t] > 0 {
+			counts[t]--
+			common++
+		}
+	}
+	score := 2 * float64(common) / float64(len(ta)+len(tb))
+	ra, rb := []rune(ja), []rune(jb)
+	if edit := 1 - float64(levenshtein(ra, rb))/float64(max(len(ra), len(rb))); edit > score {
+		score = edit
+	}
+	if numberTokens(ta) != numberTokens(tb) {
+		score /= 2
+	}
+	return math.Round(score*100) / 100
+}
+
+func numberTokens(tokens []string) string {
+	numbers := make([]string, 0)
+	for _, t := range tokens {
+		if _, err := strconv.Atoi(t); err == nil {
+			numbers = append(numbers, t)
+		}
+	}
+	sort.Strings(numbers)
+	return strings.Join(numbers, " ")
+}
+
+func levenshtein(a []rune, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// transcriptStatusRank ordena los estados de menor a mayor avance para quedarse con el mejor.
+var transcriptStatusRank = map[models.SubjectStatus]int{
+	models.StatusInProgress:     1,
+	models.StatusFinalPending:   2,
+	models.StatusPassed:         3,
+	models.StatusPassedWithDist: 3,
+}
+
+// MatchTranscript empareja cada fila con la materia del plan más parecida (por código si
+// coincide con el guardado, si no por nombre) y arma una propuesta por materia con el mejor
+// estado alcanzado: la nota de la aprobación o la fecha de la regularización.
+func MatchTranscript(entries []TranscriptEntry, subjects []models.Subject, userSubjects []models.UserSubject) TranscriptMatchTable {
+	sorted := append([]models.Subject(nil), subjects...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	current := make(map[string]models.SubjectStatus, len(userSubjects))
+	for _, us := range userSubjects {
+		current[us.SubjectID] = us.Status
+	}
+
+	table := TranscriptMatchTable{Matches: make([]TranscriptMatch, 0, len(entries)), Proposals: make([]TranscriptProposal, 0)}
+	proposals := make(map[string]*TranscriptProposal)
+	order := make([]string, 0)
+	for _, entry := range entries {
+		candidates := make([]TranscriptCandidate, 0, len(sorted))
+		for _, s := range sorted {
+			confidence := NameSimilarity(entry.Name, s.Name)
+			if entry.Code != "" && s.Code != "" && strings.EqualFold(entry.Code, s.Code) {
+				confidence = 1
+			}
+			if confidence > 0 {
+				candidates = append(candidates, TranscriptCandidate{SubjectID: s.ID, Name: s.Name, Confidence: confidence})
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Confidence > candidates[j].Confidence })
+
+		match := TranscriptMatch{TranscriptEntry: entry, Alternatives: []TranscriptCandidate{}}
+		if len(candidates) > 0 && candidates[0].Confidence >= TranscriptMinConfidence {
+			best := candidates[0]
+			match.SubjectID, match.SubjectName, match.Confidence = best.SubjectID, best.Name, best.Confidence
+			candidates = candidates[1:]
+		}
+		for _, alt := range candidates {
+			if len(match.Alternatives) == maxTranscriptAlternatives || alt.Confidence < TranscriptMinConfidence/2 {
+				break
+			}
+			match.Alternatives = append(match.Alternatives, alt)
+		}
+		table.Matches = append(table.Matches, match)
+
+		if match.SubjectID == "" {
+			continue
+		}
+		proposal, ok := proposals[match.SubjectID]
+		if !ok {
+			proposal = &TranscriptProposal{SubjectID: match.SubjectID, Name: match.SubjectName, CurrentStatus: current[match.SubjectID], Lines: []int{}}
+			proposals[match.SubjectID] = proposal
+			order = append(order, match.SubjectID)
+		}
+		proposal.Lines = append(proposal.Lines, entry.Line)
+		if entry.Status == "" || !betterTranscriptEntry(entry, proposal) {
+			continue
+		}
+		proposal.Status = entry.Status
+		proposal.Confidence = match.Confidence
+		proposal.Date = entry.Date
+		proposal.FinalCalification, proposal.RegularizedAt = nil, nil
+		if entry.Status == models.StatusPassed {
+			proposal.FinalCalification = entry.Grade
+		}
+		if entry.Status == models.StatusFinalPending {
+			proposal.RegularizedAt = entry.Date
+		}
+	}
+
+	for _, id := range order {
+		proposal := proposals[id]
+		if proposal.Status == "" {
+			continue
+		}
+		// Una propuesta que baja el estado actual (p. ej. aprobada → regular) nunca se confirma sola.
+		proposal.Confirmed = proposal.Confidence >= TranscriptConfirmConfidence && !lowersTranscriptStatus(proposal.CurrentStatus, proposal.Status)
+		table.Proposals = append(table.Proposals, *proposal)
+	}
+	return table
+}
+
+// betterTranscriptEntry indica si la fila mejora la propuesta: más avance o, a igual avance,
+// una fecha posterior (la última regularización o el último final aprobado).
+func betterTranscriptEntry(entry TranscriptEntry, proposal *TranscriptProposal) bool {
+	if proposal.Status == "" {
+		return true
+	}
+	next, prev := transcriptStatusRank[entry.Status], transcriptStatusRank[proposal.Status]
+	if next != prev {
+		return next > prev
+	}
+	return entry.Date != nil && (proposal.Date == nil || entry.Date.After(*proposal.Date))
+}
+
+// lowersTranscriptStatus indica si pasar de current a proposed pierde avance. Aprobada con
+// distinción → aprobada también cuenta, porque se perdería la distinción.
+func lowersTranscriptStatus(current models.SubjectStatus, proposed models.SubjectStatus) bool {
+	if current == models.StatusPassedWithDist && proposed == models.StatusPassed {
+		return true
+	}
+	return transcriptStatusRank[proposed] < transcriptStatusRank[current]
+}
+
+// MatchUserTranscript empareja la historia académica con las materias de la versión del plan
+// del usuario y agrega su estado actual a cada propuesta.
+func MatchUserTranscript(userID string, programID string, entries []TranscriptEntry) (*TranscriptMatchTable, error) {
+	versionID, err := UserPlanVersionID(userID, programID)
+	if err != nil {
+		return nil, err
+	}
+	subjects, _, err := LoadProgramSubjects(programID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	var userSubjects []models.UserSubject
+	if len(subjects) > 0 {
+		subjectIDs := make([]string, 0, len(subjects))
+		for _, s := range subjects {
+			subjectIDs = append(subjectIDs, s.ID)
+		}
+		if err := db.Db.Where("user_id = ? AND subject_id IN ?", userID, subjectIDs).Find(&userSubjects).Error; err != nil {
+			return nil, err
+		}
+	}
+	table := MatchTranscript(entries, subjects, userSubjects)
+	return &table, nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTranscriptText(t *testing.T) {
+	t.Parallel()
+
+	input := strings.Join([]string{
+		"Historia Académica - Ingeniería en Sistemas",
+		"Actividad Fecha Tipo Nota Resultado",
+		"Análisis Matemático I (0001) 12/07/2019 Examen 2 (dos) Desaprobado",
+		"Análisis Matemático I (0001) 10/12/2019 Examen 8 (ocho) Aprobado",
+		"Física 2 15/11/2020 Regular",
+		"Química General 7,50 Promocionado",
+		"Página 1 de 3",
+	}, "\n")
+
+	entries, err := ParseTranscriptText(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("entries = %+v, want 4", entries)
+	}
+
+	failed, passed, regular, promoted := entries[0], entries[1], entries[2], entries[3]
+	if failed.Status != "" || failed.Grade == nil || *failed.Grade != 2 {
+		t.Fatalf("failed = %+v", failed)
+	}
+	if passed.Line != 4 || passed.Name != "Análisis Matemático I" || passed.Code != "0001" || passed.Status != models.StatusPassed || *passed.Grade != 8 {
+		t.Fatalf("passed = %+v", passed)
+	}
+	if passed.Date == nil || !passed.Date.Equal(time.Date(2019, 12, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("passed date = %v", passed.Date)
+	}
+	if regular.Name != "Física 2" || regular.Status != models.StatusFinalPending || regular.Grade != nil {
+		t.Fatalf("regular = %+v", regular)
+	}
+	if promoted.Name != "Química General" || promoted.Status != models.StatusPassed || *promoted.Grade != 7.5 {
+		t.Fatalf("promoted = %+v", promoted)
+	}
+}
+
+func TestParseTranscriptCSV(t *testing.T) {
+	t.Parallel()
+
+	input := "Materia;Fecha;Nota;Condición\n" +
+		"Álgebra y Geometría Analítica;05/03/2021;9;Aprobado\n" +
+		";;;\n" +
+		"Sistemas Operativos;20/07/2022;;Regular\n" +
+		"Inglés I;01/08/2022;6;\n"
+
+	entries, err := ParseTranscriptCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	want := []struct {
+		line   int
+		name   string
+		status models.SubjectStatus
+	}{
+		{2, "Álgebra y Geometría Analítica", models.StatusPassed},
+		{4, "Sistemas Operativos", models.StatusFinalPending},
+		{5, "Inglés I", models.StatusPassed},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %+v", entries)
+	}
+	for i, w := range want {
+		if entries[i].Line != w.line || entries[i].Name != w.name || entries[i].Status != w.status {
+			t.Fatalf("entry %d = %+v, want %+v", i, entries[i], w)
+		}
+	}
+
+	if _, err := ParseTranscriptCSV(strings.NewReader("Materia;Fecha\nA;01/01/2020\n")); err == nil {
+		t.Fatal("expected an error without grade or result columns")
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b     string
+		min, max float64
+	}{
+		{"Análisis Matemático II", "ANALISIS MATEMATICO 2", 1, 1},
+		{"Algebra y Geometria Analitica", "Álgebra y Geometría Analítica", 1, 1},
+		{"Sistemas Operativos", "Sistemas Operativo", 0.9, 0.99},
+		{"Análisis Matemático I", "Análisis Matemático II", 0, 0.5},
+		{"Física I", "Química General", 0, 0.4},
+		{"", "Física", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			t.Parallel()
+			if got := NameSimilarity(tt.a, tt.b); got < tt.min || got > tt.max {
+				t.Fatalf("NameSimilarity = %v, want between %v and %v", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestMatchTranscript(t *testing.T) {
+	t.Parallel()
+
+	date := func(y int, m time.Month, d int) *time.Time {
+		v := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return &v
+	}
+	subjects := []models.Subject{
+		{ID: "am1", Name: "Análisis Matemático I"},
+		{ID: "am2", Name: "Análisis Matemático II"},
+		{ID: "so", Name: "Sistemas Operativos", Code: "SO"},
+	}
+	entries := []TranscriptEntry{
+		{Line: 1, Name: "Analisis Matematico I", Date: date(2019, 7, 1), Grade: floatPtr(6), Status: models.StatusPassed},
+		{Line: 2, Name: "Análisis Matemático 2", Date: date(2020, 7, 1), Status: models.StatusFinalPending},
+		{Line: 3, Name: "Análisis Matemático 2", Date: date(2020, 12, 1), Grade: floatPtr(2)},
+		{Line: 4, Name: "Sist. Operativos", Code: "so", Date: date(2021, 7, 1), Status: models.StatusFinalPending},
+		{Line: 5, Name: "Sist Operativos", Date: date(2021, 12, 1), Grade: floatPtr(9), Status: models.StatusPassed},
+		{Line: 6, Name: "Taller de Tesis", Status: models.StatusPassed},
+	}
+	userSubjects := []models.UserSubject{{SubjectID: "am1", Status: models.StatusInProgress}}
+
+	table := MatchTranscript(entries, subjects, userSubjects)
+	if len(table.Matches) != len(entries) {
+		t.Fatalf("matches = %+v", table.Matches)
+	}
+	if got := table.Matches[3]; got.SubjectID != "so" || got.Confidence != 1 {
+		t.Fatalf("code match = %+v", got)
+	}
+	if got := table.Matches[5]; got.SubjectID != "" {
+		t.Fatalf("unmatched row = %+v, want no subject", got)
+	}
+
+	if len(table.Proposals) != 3 {
+		t.Fatalf("proposals = %+v", table.Proposals)
+	}
+	am1, am2, so := table.Proposals[0], table.Proposals[1], table.Proposals[2]
+	if am1.SubjectID != "am1" || am1.Status != models.StatusPassed || *am1.FinalCalification != 6 || am1.CurrentStatus != models.StatusInProgress || !am1.Confirmed {
+		t.Fatalf("am1 = %+v", am1)
+	}
+	if am2.SubjectID != "am2" || am2.Status != models.StatusFinalPending || !am2.RegularizedAt.Equal(*date(2020, 7, 1)) || len(am2.Lines) != 2 {
+		t.Fatalf("am2 = %+v", am2)
+	}
+	if so.SubjectID != "so" || so.Status != models.StatusPassed || *so.FinalCalification != 9 || so.RegularizedAt != nil || so.Confirmed {
+		t.Fatalf("so = %+v, want passed from the fuzzy row and not auto-confirmed", so)
+	}
+}
+
+func TestMatchTranscript_NeverConfirmsLowerStatus(t *testing.T) {
+	t.Parallel()
+
+	subjects := []models.Subject{{ID: "so", Name: "Sistemas Operativos", Code: "SO"}, {ID: "ds", Name: "Diseño de Sistemas", Code: "DS"}}
+	entries := []TranscriptEntry{
+		{Line: 1, Name: "Sistemas Operativos", Code: "SO", Status: models.StatusFinalPending},
+		{Line: 2, Name: "Diseño de Sistemas", Code: "DS", Grade: floatPtr(9), Status: models.StatusPassed},
+	}
+	userSubjects := []models.UserSubject{
+		{SubjectID: "so", Status: models.StatusPassed},
+		{SubjectID: "ds", Status: models.StatusPassedWithDist},
+	}
+
+	table := MatchTranscript(entries, subjects, userSubjects)
+	if len(table.Proposals) != 2 {
+		t.Fatalf("proposals = %+v", table.Proposals)
+	}
+	for _, p := range table.Proposals {
+		if p.Confidence != 1 || p.Confirmed {
+			t.Fatalf("%s = %+v, want a code match that is not auto-confirmed", p.SubjectID, p)
+		}
+	}
+}
+
+func TestMatchTranscript_KeepsLatestPassedEntry(t *testing.T) {
+	t.Parallel()
+
+	date := func(y int, m time.Month, d int) *time.Time {
+		v := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return &v
+	}
+	subjects := []models.Subject{{ID: "so", Name: "Sistemas Operativos", Code: "SO"}}
+	entries := []TranscriptEntry{
+		{Line: 1, Name: "Sistemas Operativos", Code: "SO", Date: date(2022, 3, 1), Grade: floatPtr(8), Status: models.StatusPassed},
+		{Line: 2, Name: "Sistemas Operativos", Code: "SO", Date: date(2021, 12, 1), Grade: floatPtr(4), Status: models.StatusPassed},
+	}
+
+	table := MatchTranscript(entries, subjects, nil)
+	if len(table.Proposals) != 1 {
+		t.Fatalf("proposals = %+v", table.Proposals)
+	}
+	so := table.Proposals[0]
+	if *so.FinalCalification != 8 || so.Date == nil || !so.Date.Equal(*date(2022, 3, 1)) {
+		t.Fatalf("so = %+v, want the 2022 exam with grade 8", so)
+	}
+}
@@ -3,8 +3,68 @@ package services
 import (
 	"acadifyapp/internal/db"
 	"acadifyapp/internal/models"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSubjectNotInProgram  = errors.New("subject not in program")
+	ErrInvalidSubjectStatus = errors.New("invalid subject status")
+	ErrDuplicateUserSubject = errors.New("duplicate subject in payload")
+	ErrInvalidCalification  = errors.New("final_calification must be between 0 and 10")
 )
 
+var allowedUserSubjectStatus = map[models.SubjectStatus]struct{}{
+	models.StatusAvailable:      {},
+	models.StatusInProgress:     {},
+	models.StatusFinalPending:   {},
+	models.StatusPassed:         {},
+	models.StatusPassedWithDist: {},
+}
+
+// SubjectProgress es el estado que el usuario declara para una materia. Sin FinalCalification se
+// conserva la nota guardada; sin RegularizedAt se aplica TrackRegularization. ExamDate, en una
+// materia aprobada con nota, registra la aprobación como intento de examen (así la carga desde la
+// historia académica queda igual que si se hubiera cargado el final).
+type SubjectProgress struct {
+	SubjectID         string
+	Status            models.SubjectStatus
+	FinalCalification *float64
+	RegularizedAt     *time.Time
+	ExamDate          *time.Time
+}
+
+// RequirementViolationsError se devuelve en modo strict cuando los estados no respetan las correlativas.
+type RequirementViolationsError struct {
+	Violations []RequirementViolation
+}
+
+func (e *RequirementViolationsError) Error() string {
+	return "requirement violations"
+}
+
+var validationModeRank = map[models.RequirementValidationMode]int{
+	models.ValidationOff:    0,
+	models.ValidationWarn:   1,
+	models.ValidationStrict: 2,
+}
+
+// StricterValidationMode devuelve el más estricto entre el modo del programa y el pedido. Un
+// programa sin modo configurado valida en warn.
+func StricterValidationMode(program models.RequirementValidationMode, requested models.RequirementValidationMode) models.RequirementValidationMode {
+	if program == "" {
+		program = models.ValidationWarn
+	}
+	if requested != "" && validationModeRank[requested] > validationModeRank[program] {
+		return requested
+	}
+	return program
+}
+
 func GetAllUserSubjects(userId string, programId string) ([]models.UserSubject, error) {
 	var userSubjects []models.UserSubject
 
@@ -22,3 +82,191 @@ func GetAllUserSubjects(userId string, programId string) ([]models.UserSubject,
 
 	return userSubjects, nil
 }
+
+// SaveUserSubjects guarda los estados del usuario en las materias de su versión del plan.
+// Con replace, las materias que no vienen en items se borran (es lo que hace POST /me/subjects);
+// sin replace, se conservan y cuentan para validar correlativas. mode sólo puede endurecer el modo
+// del programa (vacío lo usa tal cual); en strict las violaciones devuelven
+// *RequirementViolationsError y no se guarda nada.
+func SaveUserSubjects(userID string, programID string, items []SubjectProgress, mode models.RequirementValidationMode, replace bool) ([]RequirementViolation, error) {
+	versionID, err := UserPlanVersionID(userID, programID)
+	if err != nil {
+		return nil, err
+	}
+	subjects, links, err := LoadProgramSubjects(programID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	subjectIDs := make([]string, 0, len(subjects))
+	subjectIDSet := make(map[string]struct{}, len(subjects))
+	for _, s := range subjects {
+		subjectIDs = append(subjectIDs, s.ID)
+		subjectIDSet[s.ID] = struct{}{}
+	}
+
+	records := make([]models.UserSubject, 0, len(items))
+	payloadIDs := make([]string, 0, len(items))
+	payloadIDSet := make(map[string]struct{}, len(items))
+	missingCalification := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		if _, ok := subjectIDSet[item.SubjectID]; !ok {
+			return nil, ErrSubjectNotInProgram
+		}
+		if _, ok := allowedUserSubjectStatus[item.Status]; !ok {
+			return nil, ErrInvalidSubjectStatus
+		}
+		if _, exists := payloadIDSet[item.SubjectID]; exists {
+			return nil, ErrDuplicateUserSubject
+		}
+		if item.FinalCalification != nil && (*item.FinalCalification < 0 || *item.FinalCalification > 10) {
+			return nil, ErrInvalidCalification
+		}
+		payloadIDs = append(payloadIDs, item.SubjectID)
+		payloadIDSet[item.SubjectID] = struct{}{}
+
+		record := models.UserSubject{UserID: userID, SubjectID: item.SubjectID, Status: item.Status}
+		if item.Status == models.StatusFinalPending {
+			record.RegularizedAt = item.RegularizedAt
+		}
+		if item.FinalCalification != nil {
+			record.FinalCalification = *item.FinalCalification
+		} else {
+			missingCalification[item.SubjectID] = struct{}{}
+		}
+		records = append(records, record)
+	}
+
+	recognitionLinks, linkedProgress, err := loadRecognizedProgress(userID, subjects)
+	if err != nil {
+		return nil, err
+	}
+	_, recognized := ApplyRecognitions(subjects, nil, recognitionLinks, linkedProgress)
+
+	newStatuses := make(map[string]models.SubjectStatus, len(records))
+	if len(subjectIDs) > 0 {
+		var existingRows []models.UserSubject
+		if err := db.Db.Where("user_id = ? AND subject_id IN ?", userID, subjectIDs).Find(&existingRows).Error; err != nil {
+			return nil, err
+		}
+		existingBySubjectID := make(map[string]*models.UserSubject, len(existingRows))
+		for i := range existingRows {
+			existingBySubjectID[existingRows[i].SubjectID] = &existingRows[i]
+			if !replace {
+				newStatuses[existingRows[i].SubjectID] = existingRows[i].Status
+			}
+		}
+		now := time.Now().UTC()
+		for i := range records {
+			previous := existingBySubjectID[records[i].SubjectID]
+			if _, missing := missingCalification[records[i].SubjectID]; missing && previous != nil {
+				records[i].FinalCalification = previous.FinalCalification
+			}
+			// Si el estado que llega es el que salió de los intentos, se conserva lo cargado a mano
+			// para poder volver a eso; cualquier otro estado pasa a ser manual.
+			if previous != nil && previous.ManualStatus != "" && records[i].Status == previous.Status {
+				records[i].ManualStatus = previous.ManualStatus
+				records[i].ManualCalification = previous.ManualCalification
+			}
+			TrackRegularization(previous, &records[i], now)
+		}
+		records = dropRecognizedEchoes(records, existingBySubjectID, recognized)
+	}
+	for _, r := range records {
+		newStatuses[r.SubjectID] = r.Status
+	}
+	overlayRecognized(newStatuses, recognized)
+
+	var program models.DegreeProgram
+	if err := db.Db.Select("id", "requirement_validation").Where("id = ?", programID).First(&program).Error; err != nil {
+		return nil, err
+	}
+	mode = StricterValidationMode(program.RequirementValidation, mode)
+
+	warnings := make([]RequirementViolation, 0)
+	if mode != models.ValidationOff && len(subjectIDs) > 0 {
+		groups, err := LoadRequirementGroups(subjectIDs)
+		if err != nil {
+			return nil, err
+		}
+		corequisites, err := LoadCorequisites(db.Db, subjectIDs)
+		if err != nil {
+			return nil, err
+		}
+		violations := ValidateStatuses(subjects, links, groups, newStatuses)
+		violations = append(violations, ValidateCorequisites(corequisites, newStatuses)...)
+		if len(violations) > 0 {
+			if mode == models.ValidationStrict {
+				return nil, &RequirementViolationsError{Violations: violations}
+			}
+			warnings = violations
+		}
+	}
+
+	err = db.Db.Transaction(func(tx *gorm.DB) error {
+		if replace && len(subjectIDs) > 0 {
+			prune := tx.Where("user_id = ? AND subject_id IN ?", userID, subjectIDs)
+			if len(payloadIDs) > 0 {
+				prune = prune.Where("subject_id NOT IN ?", payloadIDs)
+			}
+			if err := prune.Delete(&models.UserSubject{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "subject_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "final_calification", "regularized_at", "regularization_expired", "manual_status", "manual_calification", "updated_at"}),
+		}).Create(&records).Error; err != nil {
+			return err
+		}
+		return recordPassedExams(tx, userID, items, records)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// recordPassedExams crea el intento aprobado de cada materia guardada con ExamDate y nota, salvo
+// que ya exista uno aprobado ese día, y recalcula el estado desde los intentos. Lo recién guardado
+// queda como estado manual, así que borrar el intento vuelve a la aprobación cargada.
+func recordPassedExams(tx *gorm.DB, userID string, items []SubjectProgress, saved []models.UserSubject) error {
+	savedIDs := make(map[string]struct{}, len(saved))
+	for _, r := range saved {
+		savedIDs[r.SubjectID] = struct{}{}
+	}
+	for _, item := range items {
+		if _, ok := savedIDs[item.SubjectID]; !ok || item.ExamDate == nil || item.FinalCalification == nil {
+			continue
+		}
+		if item.Status != models.StatusPassed && item.Status != models.StatusPassedWithDist {
+			continue
+		}
+		var existing int64
+		if err := tx.Model(&models.ExamAttempt{}).
+			Where("user_id = ? AND subject_id = ? AND date = ? AND passed = ?", userID, item.SubjectID, *item.ExamDate, true).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing == 0 {
+			attempt := models.ExamAttempt{
+				ID:        uuid.NewString(),
+				UserID:    userID,
+				SubjectID: item.SubjectID,
+				Date:      *item.ExamDate,
+				Grade:     *item.FinalCalification,
+				Passed:    true,
+				Type:      models.ExamFinal,
+			}
+			if err := tx.Create(&attempt).Error; err != nil {
+				return err
+			}
+		}
+		if _, err := syncUserSubjectFromAttempts(tx, userID, item.SubjectID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
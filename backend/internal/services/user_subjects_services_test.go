@@ -0,0 +1,116 @@
+package services
+
+import (
+	"acadifyapp/internal/models"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSaveUserSubjects_StrictRejectsGroupViolation(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `user_plan_versions`").WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery("SELECT \\* FROM `subjects`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "degree_program_id"}).
+			AddRow("a", "A", "p1").AddRow("b", "B", "p1").AddRow("c", "C", "p1"))
+	mock.ExpectQuery("SELECT \\* FROM `subject_requirements`").WillReturnRows(sqlmock.NewRows([]string{"subject_id"}))
+	mock.ExpectQuery("SELECT \\* FROM `subject_recognitions`").WillReturnRows(sqlmock.NewRows([]string{"subject_id"}))
+	mock.ExpectQuery("SELECT \\* FROM `user_subjects`").WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery("SELECT `id`,`requirement_validation` FROM `degree_programs`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "requirement_validation"}).AddRow("p1", models.ValidationWarn))
+	mock.ExpectQuery("SELECT \\* FROM `requirement_groups`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subject_id", "kind", "min_status"}).AddRow("g", "c", models.GroupAny, models.ReqPassed))
+	mock.ExpectQuery("SELECT \\* FROM `requirement_group_subjects`").
+		WillReturnRows(sqlmock.NewRows([]string{"group_id", "subject_id"}).AddRow("g", "a").AddRow("g", "b"))
+	mock.ExpectQuery("SELECT \\* FROM `subject_corequisites`").WillReturnRows(sqlmock.NewRows([]string{"subject_id"}))
+
+	items := []SubjectProgress{{SubjectID: "c", Status: models.StatusInProgress}}
+	_, err := SaveUserSubjects("u1", "p1", items, models.ValidationStrict, true)
+
+	var violationsErr *RequirementViolationsError
+	if !errors.As(err, &violationsErr) {
+		t.Fatalf("SaveUserSubjects() error = %v, want *RequirementViolationsError", err)
+	}
+	if v := violationsErr.Violations; len(v) != 1 || v[0].SubjectID != "c" || v[0].Group == nil || v[0].Group.GroupID != "g" {
+		t.Fatalf("violations = %+v, want group g unmet for c", v)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveUserSubjects_ExamDateRecordsPassedAttempt(t *testing.T) {
+	examDate := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	grade := 8.0
+
+	tests := []struct {
+		name        string
+		existing    int
+		wantAttempt bool
+	}{
+		{name: "crea el intento aprobado", wantAttempt: true},
+		{name: "no duplica un intento aprobado del mismo día", existing: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := useMockDB(t)
+			mock.ExpectQuery("SELECT \\* FROM `user_plan_versions`").WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+			mock.ExpectQuery("SELECT \\* FROM `subjects`").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "degree_program_id"}).AddRow("a", "A", "p1"))
+			mock.ExpectQuery("SELECT \\* FROM `subject_requirements`").WillReturnRows(sqlmock.NewRows([]string{"subject_id"}))
+			mock.ExpectQuery("SELECT \\* FROM `subject_recognitions`").WillReturnRows(sqlmock.NewRows([]string{"subject_id"}))
+			mock.ExpectQuery("SELECT \\* FROM `user_subjects`").WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+			mock.ExpectQuery("SELECT `id`,`requirement_validation` FROM `degree_programs`").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "requirement_validation"}).AddRow("p1", models.ValidationOff))
+			mock.ExpectBegin()
+			mock.ExpectExec("INSERT INTO `user_subjects`").WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery("SELECT count\\(\\*\\) FROM `exam_attempts`").
+				WithArgs("u1", "a", examDate, true).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tc.existing))
+			if tc.wantAttempt {
+				mock.ExpectExec("INSERT INTO `exam_attempts`").
+					WithArgs(sqlmock.AnyArg(), "u1", "a", examDate, grade, true, models.ExamFinal, sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectQuery("SELECT \\* FROM `exam_attempts`").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "subject_id", "date", "grade", "passed", "type"}).
+					AddRow("e1", "u1", "a", examDate, grade, true, models.ExamFinal))
+			mock.ExpectQuery("SELECT \\* FROM `user_subjects`").
+				WillReturnRows(sqlmock.NewRows([]string{"user_id", "subject_id", "status", "final_calification"}).
+					AddRow("u1", "a", models.StatusPassed, grade))
+			mock.ExpectExec("INSERT INTO `user_subjects`").WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			items := []SubjectProgress{{SubjectID: "a", Status: models.StatusPassed, FinalCalification: &grade, ExamDate: &examDate}}
+			if _, err := SaveUserSubjects("u1", "p1", items, models.ValidationOff, false); err != nil {
+				t.Fatalf("SaveUserSubjects() error = %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestStricterValidationMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		program, requested, want models.RequirementValidationMode
+	}{
+		{program: models.ValidationStrict, requested: models.ValidationOff, want: models.ValidationStrict},
+		{program: models.ValidationStrict, requested: models.ValidationWarn, want: models.ValidationStrict},
+		{program: models.ValidationWarn, requested: models.ValidationStrict, want: models.ValidationStrict},
+		{program: models.ValidationWarn, requested: models.ValidationOff, want: models.ValidationWarn},
+		{program: models.ValidationOff, requested: models.ValidationWarn, want: models.ValidationWarn},
+		{program: models.ValidationOff, requested: "", want: models.ValidationOff},
+		{program: "", requested: models.ValidationOff, want: models.ValidationWarn},
+	}
+	for _, tt := range tests {
+		if got := StricterValidationMode(tt.program, tt.requested); got != tt.want {
+			t.Fatalf("StricterValidationMode(%q, %q) = %q, want %q", tt.program, tt.requested, got, tt.want)
+		}
+	}
+}